@@ -5,6 +5,7 @@ import (
 	"k8s.io/kubectl/pkg/util/templates"
 
 	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
 	"kusionstack.io/kusion/pkg/util/i18n"
 )
 
@@ -30,7 +31,22 @@ var (
 		kusion apply -Y settings.yaml
 
 		# Skip interactive approval of plan details before applying
-		kusion apply --yes`
+		kusion apply --yes
+
+		# Apply resources only after the resources they depend on are ready
+		kusion apply --wait --wait-timeout=10m
+
+		# Revert applied resources if the apply fails halfway
+		kusion apply --rollback-on-failure
+
+		# Apply at most 10 resources at once, and keep applying other resources if any resource fails
+		kusion apply --parallelism=10 --continue-on-error
+
+		# Retry runtime calls failed with transient errors at most 3 times, and each call times out after 1 minute
+		kusion apply --retries=3 --timeout=1m
+
+		# Apply only the specified resources, and leave other resources unchanged
+		kusion apply --target="v1:Service:default:*"`
 )
 
 func NewCmdApply() *cobra.Command {
@@ -60,6 +76,14 @@ func NewCmdApply() *cobra.Command {
 		i18n.T("dry-run to preview the execution effect (always successful) without actually applying the changes"))
 	cmd.Flags().BoolVarP(&o.Watch, "watch", "", false,
 		i18n.T("After creating/updating/deleting the requested object, watch for changes."))
+	cmd.Flags().BoolVarP(&o.Wait, "wait", "", false,
+		i18n.T("Wait for each resource to be ready before applying resources depending on it"))
+	cmd.Flags().DurationVarP(&o.WaitTimeout, "wait-timeout", "", graph.DefaultWaitTimeout,
+		i18n.T("The default timeout of waiting for a resource to be ready, overridden by the waitTimeout extension of the resource"))
+	cmd.Flags().BoolVarP(&o.RollbackOnFailure, "rollback-on-failure", "", false,
+		i18n.T("Revert resources created or updated by this apply if any resource fails to apply"))
+	cmd.Flags().BoolVarP(&o.ContinueOnError, "continue-on-error", "", false,
+		i18n.T("Keep applying resources not depending on failed resources after any resource fails to apply"))
 
 	return cmd
 }
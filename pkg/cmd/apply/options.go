@@ -1,11 +1,13 @@
 package apply
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/pterm/pterm"
@@ -18,12 +20,14 @@ import (
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/generator"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
 	"kusionstack.io/kusion/pkg/util/pretty"
+	"kusionstack.io/kusion/pkg/util/signals"
 )
 
 // ApplyOptions defines flags for the `apply` command
@@ -33,9 +37,13 @@ type ApplyOptions struct {
 }
 
 type ApplyFlag struct {
-	Yes    bool
-	DryRun bool
-	Watch  bool
+	Yes               bool
+	DryRun            bool
+	Watch             bool
+	Wait              bool
+	WaitTimeout       time.Duration
+	RollbackOnFailure bool
+	ContinueOnError   bool
 }
 
 // NewApplyOptions returns a new ApplyOptions instance
@@ -50,10 +58,28 @@ func (o *ApplyOptions) Complete(args []string) {
 }
 
 func (o *ApplyOptions) Validate() error {
-	return o.CompileOptions.Validate()
+	if err := o.CompileOptions.Validate(); err != nil {
+		return err
+	}
+	if o.ForceConflicts && !o.ServerSide {
+		return previewcmd.ErrForceConflictsWithoutServerSide
+	}
+	if o.TargetWithDeps && len(o.Targets) == 0 {
+		return previewcmd.ErrTargetWithDepsWithoutTarget
+	}
+	if o.Parallelism < 0 {
+		return previewcmd.ErrNegativeParallelism
+	}
+	if o.Timeout < 0 || o.Retries < 0 {
+		return previewcmd.ErrNegativeRetries
+	}
+	return nil
 }
 
 func (o *ApplyOptions) Run() error {
+	// stop runtime plugins launched by this command
+	defer runtimeinit.Close()
+
 	// Set no style
 	if o.NoStyle {
 		pterm.DisableStyling()
@@ -88,7 +114,7 @@ func (o *ApplyOptions) Run() error {
 	}
 
 	// Get state storage from backend config to manage state
-	stateStorage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir)
+	stateStorage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir, project.SecretStores)
 	if err != nil {
 		return err
 	}
@@ -193,16 +219,41 @@ func Apply(
 		return fmt.Errorf("no secret store is provided")
 	}
 
+	// parse cluster in flags and arguments
+	cluster, err := util.ResolveCluster(o.Cluster, o.Arguments)
+	if err != nil {
+		return err
+	}
+
 	// Construct the apply operation
+	// Cancel the apply at the first interrupt, and resources done before it are saved in the State
+	ctx, stop := signals.NotifyContext(context.Background())
+	defer stop()
+
 	ac := &operation.ApplyOperation{
 		Operation: opsmodels.Operation{
-			Stack:        changes.Stack(),
-			StateStorage: storage,
-			MsgCh:        make(chan opsmodels.Message),
-			SecretStores: project.SecretStores,
+			Ctx:               ctx,
+			Stack:             changes.Stack(),
+			StateStorage:      storage,
+			MsgCh:             make(chan opsmodels.Message),
+			SecretStores:      project.SecretStores,
+			ServerSideApply:   o.ServerSide,
+			ForceConflicts:    o.ForceConflicts,
+			Wait:              o.Wait,
+			WaitTimeout:       o.WaitTimeout,
+			RollbackOnFailure: o.RollbackOnFailure,
+			Targets:           o.Targets,
+			TargetWithDeps:    o.TargetWithDeps,
+			Parallelism:       o.Parallelism,
+			ContinueOnError:   o.ContinueOnError,
+			Timeout:           o.Timeout,
+			Retries:           o.Retries,
 		},
 	}
 
+	// Result of each resource
+	report := opsmodels.NewExecutionReport(changes.ChangeOrder)
+
 	// Line summary
 	var ls lineSummary
 
@@ -234,6 +285,7 @@ func Apply(
 					return
 				}
 				changeStep := changes.Get(msg.ResourceID)
+				report.Record(msg)
 
 				switch msg.OpResult {
 				case opsmodels.Success, opsmodels.Skip:
@@ -262,6 +314,12 @@ func Apply(
 						strings.ToLower(string(msg.OpResult)),
 					)
 					pterm.Error.WithWriter(out).Printf("%s\n", title)
+				case opsmodels.RolledBack:
+					pterm.Success.WithWriter(out).Printf("Rollback %s %s success\n",
+						changeStep.Action.String(), pterm.Bold.Sprint(changeStep.ID))
+				case opsmodels.RollbackFailed:
+					pterm.Error.WithWriter(out).Printf("Rollback %s %s failed: %v\n",
+						changeStep.Action.String(), pterm.Bold.Sprint(changeStep.ID), msg.OpErr)
 				default:
 					title := fmt.Sprintf("%s %s %s",
 						changeStep.Action.Ing(),
@@ -276,6 +334,10 @@ func Apply(
 
 	if o.DryRun {
 		for _, r := range planResources.Resources {
+			// resources out of targets are not previewed
+			if changes.Get(r.ResourceKey()) == nil {
+				continue
+			}
 			ac.MsgCh <- opsmodels.Message{
 				ResourceID: r.ResourceKey(),
 				OpResult:   opsmodels.Success,
@@ -284,9 +346,7 @@ func Apply(
 		}
 		close(ac.MsgCh)
 	} else {
-		// parse cluster in arguments
-		cluster := util.ParseClusterArgument(o.Arguments)
-		_, st := ac.Apply(&operation.ApplyRequest{
+		rsp, st := ac.Apply(&operation.ApplyRequest{
 			Request: opsmodels.Request{
 				Tenant:   changes.Project().Tenant,
 				Project:  changes.Project(),
@@ -297,6 +357,15 @@ func Apply(
 			},
 		})
 		if status.IsErr(st) {
+			wg.Wait()
+			pterm.Fprintln(out)
+			report.Print(out)
+			if st.Code() == status.Canceled {
+				pterm.Warning.WithWriter(out).Println("Apply is interrupted, skipped resources are not applied and the State of applied resources has been saved")
+			}
+			if rsp != nil && rsp.Rollback != nil {
+				pterm.Fprintln(out, rsp.Rollback.Summary())
+			}
 			return fmt.Errorf("apply failed, status:\n%v", st)
 		}
 	}
@@ -304,7 +373,15 @@ func Apply(
 	// Wait for msgCh closed
 	wg.Wait()
 	// Print summary
+	// Retries of transient failures are worth noticing even if the apply succeeds
+	if report.Retried() {
+		pterm.Fprintln(out)
+		report.Print(out)
+	}
 	pterm.Fprintln(out, fmt.Sprintf("Apply complete! Resources: %d created, %d updated, %d deleted.", ls.created, ls.updated, ls.deleted))
+	if len(o.Targets) != 0 {
+		previewcmd.WarnPartial(o.Targets)
+	}
 	return nil
 }
 
@@ -336,7 +413,8 @@ func Watch(
 	// Filter out unchanged resources
 	toBeWatched := models.Resources{}
 	for _, res := range planResources.Resources {
-		if changes.ChangeOrder.ChangeSteps[res.ResourceKey()].Action != opsmodels.UnChange {
+		step := changes.Get(res.ResourceKey())
+		if step != nil && step.Action != opsmodels.UnChange {
 			toBeWatched = append(toBeWatched, res)
 		}
 	}
@@ -26,6 +26,7 @@ import (
 	cmdinit "kusionstack.io/kusion/pkg/cmd/init"
 	"kusionstack.io/kusion/pkg/cmd/ls"
 	"kusionstack.io/kusion/pkg/cmd/preview"
+	"kusionstack.io/kusion/pkg/cmd/state"
 	"kusionstack.io/kusion/pkg/cmd/version"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/util/gitutil"
@@ -128,6 +129,7 @@ func NewKusionctlCmd(in io.Reader, out, err io.Writer) *cobra.Command {
 				preview.NewCmdPreview(),
 				apply.NewCmdApply(),
 				destroy.NewCmdDestroy(),
+				state.NewCmdState(),
 			},
 		},
 	}
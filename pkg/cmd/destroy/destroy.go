@@ -23,7 +23,16 @@ var (
 
 	destroyExample = `
 		# Delete the configuration of current stack
-		kusion destroy`
+		kusion destroy
+
+		# Delete the configuration of current stack and the State in the backend
+		kusion destroy --purge-state
+
+		# Delete the configuration of current stack deployed to the specified cluster
+		kusion destroy --cluster=cluster-a
+
+		# Delete only the specified resource and resources depending on it
+		kusion destroy --target="apps/v1:Deployment:default:nginx" --target-with-deps`
 )
 
 func NewCmdDestroy() *cobra.Command {
@@ -46,10 +55,26 @@ func NewCmdDestroy() *cobra.Command {
 	o.AddCompileFlags(cmd)
 	cmd.Flags().StringVarP(&o.Operator, "operator", "", "",
 		i18n.T("Specify the operator"))
+	cmd.Flags().StringVarP(&o.Cluster, "cluster", "", "",
+		i18n.T("Specify the cluster of the State, overrides the top-level argument `cluster`"))
 	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", false,
 		i18n.T("Automatically approve and perform the update after previewing it"))
 	cmd.Flags().BoolVarP(&o.Detail, "detail", "d", false,
 		i18n.T("Automatically show plan details after previewing it"))
+	cmd.Flags().BoolVarP(&o.PurgeState, "purge-state", "", false,
+		i18n.T("Delete the State and all its history after all resources are destroyed"))
+	cmd.Flags().StringArrayVarP(&o.Targets, "target", "", nil,
+		i18n.T("Only destroy resources with the specified ID, which can be repeated and supports \"*\" and \"?\" wildcards"))
+	cmd.Flags().BoolVarP(&o.TargetWithDeps, "target-with-deps", "", false,
+		i18n.T("Destroy resources depending on the targeted resources as well, combined use with flag `--target`"))
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", 0,
+		i18n.T("Limit the number of resources destroyed at once, 0 means no limit"))
+	cmd.Flags().BoolVarP(&o.ContinueOnError, "continue-on-error", "", false,
+		i18n.T("Keep destroying other resources after any resource fails to destroy, except resources the failed resources depend on"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The default timeout of each runtime call of a resource, overridden by the timeout extension of the resource. 0 means no timeout"))
+	cmd.Flags().IntVarP(&o.Retries, "retries", "", 0,
+		i18n.T("The default max times of retrying a runtime call failed with a transient error, overridden by the retries extension of the resource. Calls of Exec and Helm resources are never retried"))
 	o.AddBackendFlags(cmd)
 
 	return cmd
@@ -1,19 +1,25 @@
 package destroy
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/pterm/pterm"
 
 	compilecmd "kusionstack.io/kusion/pkg/cmd/compile"
+	previewcmd "kusionstack.io/kusion/pkg/cmd/preview"
+	"kusionstack.io/kusion/pkg/cmd/util"
 	"kusionstack.io/kusion/pkg/engine/backend"
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
@@ -24,9 +30,19 @@ import (
 
 type DestroyOptions struct {
 	compilecmd.CompileOptions
-	Operator string
-	Yes      bool
-	Detail   bool
+	Operator   string
+	Cluster    string
+	Yes        bool
+	Detail     bool
+	PurgeState bool
+
+	Targets        []string
+	TargetWithDeps bool
+
+	Parallelism     int
+	ContinueOnError bool
+	Timeout         time.Duration
+	Retries         int
 	backend.BackendOps
 }
 
@@ -41,12 +57,28 @@ func (o *DestroyOptions) Complete(args []string) {
 }
 
 func (o *DestroyOptions) Validate() error {
-	return o.CompileOptions.Validate()
+	if err := o.CompileOptions.Validate(); err != nil {
+		return err
+	}
+	if o.TargetWithDeps && len(o.Targets) == 0 {
+		return previewcmd.ErrTargetWithDepsWithoutTarget
+	}
+	if o.Parallelism < 0 {
+		return previewcmd.ErrNegativeParallelism
+	}
+	if o.Timeout < 0 || o.Retries < 0 {
+		return previewcmd.ErrNegativeRetries
+	}
+	if o.PurgeState && len(o.Targets) != 0 {
+		return errors.New("flag `--purge-state` can not be used with flag `--target`, since resources out of targets are still recorded in the State")
+	}
+	return nil
 }
 
 func (o *DestroyOptions) Run() error {
-	// listen for interrupts or the SIGTERM signal
-	signals.HandleInterrupt()
+	// stop runtime plugins launched by this command
+	defer runtimeinit.Close()
+
 	// Parse project and stack of work directory
 	project, stack, err := projectstack.DetectProjectAndStack(o.CompileOptions.WorkDir)
 	if err != nil {
@@ -54,20 +86,29 @@ func (o *DestroyOptions) Run() error {
 	}
 
 	// Get stateStorage from backend config to manage state
-	stateStorage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir)
+	stateStorage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir, project.SecretStores)
+	if err != nil {
+		return err
+	}
+
+	// parse cluster in flags and arguments
+	cluster, err := util.ResolveCluster(o.Cluster, o.Arguments)
 	if err != nil {
 		return err
 	}
 
 	// only destroy resources we managed
-	// todo add the `cluster` field in query
 	query := &states.StateQuery{
 		Tenant:  project.Tenant,
 		Stack:   stack.Name,
 		Project: project.Name,
+		Cluster: cluster,
 	}
 	latestState, err := stateStorage.GetLatestState(query)
-	if err != nil || latestState == nil {
+	if err != nil {
+		return fmt.Errorf("get the latest State failed as %w", err)
+	}
+	if latestState == nil {
 		log.Infof("can't find states with query: %v", jsonutil.Marshal2PrettyString(query))
 		return fmt.Errorf("can not find State in this stack")
 	}
@@ -80,13 +121,16 @@ func (o *DestroyOptions) Run() error {
 
 	// Compute changes for preview
 	spec := &models.Spec{Resources: destroyResources}
-	changes, err := o.preview(spec, project, stack, stateStorage)
+	changes, err := o.preview(spec, project, stack, cluster, stateStorage)
 	if err != nil {
 		return err
 	}
 
 	// Preview
 	changes.Summary(os.Stdout)
+	if len(o.Targets) != 0 {
+		previewcmd.WarnPartial(o.Targets)
+	}
 
 	// Detail detection
 	if o.Detail {
@@ -118,23 +162,31 @@ func (o *DestroyOptions) Run() error {
 
 	// Destroy
 	fmt.Println("Start destroying resources......")
-	if err := o.destroy(spec, changes, stateStorage); err != nil {
+	if err := o.destroy(spec, changes, cluster, stateStorage); err != nil {
 		return err
 	}
+	if o.PurgeState {
+		fmt.Println("State and its history have been deleted")
+	}
 	return nil
 }
 
 func (o *DestroyOptions) preview(planResources *models.Spec, project *projectstack.Project,
-	stack *projectstack.Stack, stateStorage states.StateStorage,
+	stack *projectstack.Stack, cluster string, stateStorage states.StateStorage,
 ) (*opsmodels.Changes, error) {
 	log.Info("Start compute preview changes ...")
 
 	pc := &operation.PreviewOperation{
 		Operation: opsmodels.Operation{
-			OperationType: opsmodels.DestroyPreview,
-			Stack:         stack,
-			StateStorage:  stateStorage,
-			ChangeOrder:   &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			OperationType:  opsmodels.DestroyPreview,
+			Stack:          stack,
+			StateStorage:   stateStorage,
+			Targets:        o.Targets,
+			TargetWithDeps: o.TargetWithDeps,
+			Parallelism:    o.Parallelism,
+			Timeout:        o.Timeout,
+			Retries:        o.Retries,
+			ChangeOrder:    &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
 		},
 	}
 
@@ -147,6 +199,7 @@ func (o *DestroyOptions) preview(planResources *models.Spec, project *projectsta
 			Operator: o.Operator,
 			Stack:    stack,
 			Spec:     planResources,
+			Cluster:  cluster,
 		},
 	})
 	if status.IsErr(s) {
@@ -156,15 +209,29 @@ func (o *DestroyOptions) preview(planResources *models.Spec, project *projectsta
 	return opsmodels.NewChanges(project, stack, rsp.Order), nil
 }
 
-func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.Changes, stateStorage states.StateStorage) error {
+func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.Changes, cluster string, stateStorage states.StateStorage) error {
+	// cancel the destroy at the first interrupt, and resources not deleted yet are kept in the State
+	ctx, stop := signals.NotifyContext(context.Background())
+	defer stop()
+
 	do := &operation.DestroyOperation{
 		Operation: opsmodels.Operation{
-			Stack:        changes.Stack(),
-			StateStorage: stateStorage,
-			MsgCh:        make(chan opsmodels.Message),
+			Ctx:             ctx,
+			Stack:           changes.Stack(),
+			StateStorage:    stateStorage,
+			MsgCh:           make(chan opsmodels.Message),
+			Targets:         o.Targets,
+			TargetWithDeps:  o.TargetWithDeps,
+			Parallelism:     o.Parallelism,
+			ContinueOnError: o.ContinueOnError,
+			Timeout:         o.Timeout,
+			Retries:         o.Retries,
 		},
 	}
 
+	// result of each resource
+	report := opsmodels.NewExecutionReport(changes.ChangeOrder)
+
 	// line summary
 	var deleted int
 
@@ -192,6 +259,7 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 					return
 				}
 				changeStep := changes.Get(msg.ResourceID)
+				report.Record(msg)
 
 				switch msg.OpResult {
 				case opsmodels.Success, opsmodels.Skip:
@@ -239,9 +307,18 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 			Operator: o.Operator,
 			Stack:    changes.Stack(),
 			Spec:     planResources,
+			Cluster:  cluster,
 		},
+		// purge the State to avoid orphaned States in the storage
+		PurgeState: o.PurgeState,
 	})
 	if status.IsErr(st) {
+		wg.Wait()
+		pterm.Println()
+		report.Print(os.Stdout)
+		if st.Code() == status.Canceled {
+			pterm.Warning.Println("Destroy is interrupted, skipped resources are not deleted and are kept in the State")
+		}
 		return fmt.Errorf("destroy failed, status: %v", st)
 	}
 
@@ -249,7 +326,13 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 	wg.Wait()
 	// Print summary
 	pterm.Println()
+	if report.Retried() {
+		report.Print(os.Stdout)
+	}
 	pterm.Printf("Destroy complete! Resources: %d deleted.\n", deleted)
+	if len(o.Targets) != 0 {
+		previewcmd.WarnPartial(o.Targets)
+	}
 	return nil
 }
 
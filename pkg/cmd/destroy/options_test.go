@@ -63,6 +63,83 @@ func TestDestroyOptions_Run(t *testing.T) {
 		err := o.Run()
 		assert.Nil(t, err)
 	})
+
+	t.Run("purge state", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockDetectProjectAndStack()
+		mockGetLatestState()
+		mockNewKubernetesRuntime()
+		mockOperationPreview()
+		var purge bool
+		monkey.Patch((*operation.DestroyOperation).Destroy,
+			func(o *operation.DestroyOperation, request *operation.DestroyRequest) status.Status {
+				purge = request.PurgeState
+				close(o.MsgCh)
+				return nil
+			})
+
+		o := NewDestroyOptions()
+		o.PurgeState = true
+		mockPromptOutput("yes")
+		err := o.Run()
+		assert.Nil(t, err)
+		assert.True(t, purge)
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockDetectProjectAndStack()
+		var cluster string
+		monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "GetLatestState", func(f *local.FileSystemState, query *states.StateQuery) (*states.State, error) {
+			cluster = query.Cluster
+			return &states.State{Resources: []models.Resource{sa1}}, nil
+		})
+		mockNewKubernetesRuntime()
+		mockOperationPreview()
+
+		o := NewDestroyOptions()
+		o.Detail = true
+		o.Cluster = "cluster-a"
+		err := o.Run()
+		assert.Nil(t, err)
+		assert.Equal(t, "cluster-a", cluster)
+	})
+
+	t.Run("get latest state failed", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockDetectProjectAndStack()
+		stateErr := errors.New("decrypt State failed")
+		monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "GetLatestState", func(f *local.FileSystemState, query *states.StateQuery) (*states.State, error) {
+			return nil, stateErr
+		})
+
+		o := NewDestroyOptions()
+		err := o.Run()
+		assert.ErrorIs(t, err, stateErr)
+	})
+
+	t.Run("no state", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockDetectProjectAndStack()
+		monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "GetLatestState", func(f *local.FileSystemState, query *states.StateQuery) (*states.State, error) {
+			return nil, nil
+		})
+
+		o := NewDestroyOptions()
+		err := o.Run()
+		assert.EqualError(t, err, "can not find State in this stack")
+	})
+
+	t.Run("conflicting clusters", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockDetectProjectAndStack()
+
+		o := NewDestroyOptions()
+		o.Cluster = "cluster-a"
+		o.Arguments = []string{"cluster=cluster-b"}
+		err := o.Run()
+		assert.NotNil(t, err)
+	})
 }
 
 var (
@@ -101,7 +178,7 @@ func Test_preview(t *testing.T) {
 
 		o := NewDestroyOptions()
 		stateStorage := &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)}
-		_, err := o.preview(&models.Spec{Resources: []models.Resource{sa1}}, project, stack, stateStorage)
+		_, err := o.preview(&models.Spec{Resources: []models.Resource{sa1}}, project, stack, "", stateStorage)
 		assert.Nil(t, err)
 	})
 }
@@ -220,7 +297,7 @@ func Test_destroy(t *testing.T) {
 
 		stateStorage := &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)}
 
-		err := o.destroy(planResources, changes, stateStorage)
+		err := o.destroy(planResources, changes, "", stateStorage)
 		assert.Nil(t, err)
 	})
 	t.Run("destroy failed", func(t *testing.T) {
@@ -243,7 +320,7 @@ func Test_destroy(t *testing.T) {
 		changes := opsmodels.NewChanges(project, stack, order)
 		stateStorage := &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)}
 
-		err := o.destroy(planResources, changes, stateStorage)
+		err := o.destroy(planResources, changes, "", stateStorage)
 		assert.NotNil(t, err)
 	})
 }
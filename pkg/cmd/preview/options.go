@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/pterm/pterm"
 
@@ -15,6 +16,7 @@ import (
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/generator"
 	"kusionstack.io/kusion/pkg/log"
@@ -25,6 +27,13 @@ import (
 
 const jsonOutput = "json"
 
+var (
+	ErrForceConflictsWithoutServerSide = errors.New("flag `--force-conflicts` only works with flag `--server-side`")
+	ErrTargetWithDepsWithoutTarget     = errors.New("flag `--target-with-deps` only works with flag `--target`")
+	ErrNegativeParallelism             = errors.New("flag `--parallelism` can not be negative")
+	ErrNegativeRetries                 = errors.New("flags `--timeout` and `--retries` can not be negative")
+)
+
 type PreviewOptions struct {
 	compilecmd.CompileOptions
 	PreviewFlags
@@ -33,11 +42,22 @@ type PreviewOptions struct {
 
 type PreviewFlags struct {
 	Operator     string
+	Cluster      string
 	Detail       bool
 	All          bool
 	NoStyle      bool
 	Output       string
 	IgnoreFields []string
+
+	ServerSide     bool
+	ForceConflicts bool
+
+	Targets        []string
+	TargetWithDeps bool
+
+	Parallelism int
+	Timeout     time.Duration
+	Retries     int
 }
 
 func NewPreviewOptions() *PreviewOptions {
@@ -57,10 +77,25 @@ func (o *PreviewOptions) Validate() error {
 	if o.Output != "" && o.Output != jsonOutput {
 		return errors.New("invalid output type, supported types: json")
 	}
+	if o.ForceConflicts && !o.ServerSide {
+		return ErrForceConflictsWithoutServerSide
+	}
+	if o.TargetWithDeps && len(o.Targets) == 0 {
+		return ErrTargetWithDepsWithoutTarget
+	}
+	if o.Parallelism < 0 {
+		return ErrNegativeParallelism
+	}
+	if o.Timeout < 0 || o.Retries < 0 {
+		return ErrNegativeRetries
+	}
 	return nil
 }
 
 func (o *PreviewOptions) Run() error {
+	// stop runtime plugins launched by this command
+	defer runtimeinit.Close()
+
 	// Set no style
 	if o.NoStyle {
 		pterm.DisableStyling()
@@ -103,7 +138,7 @@ func (o *PreviewOptions) Run() error {
 	}
 
 	// Get state storage from backend config to manage state
-	stateStorage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir)
+	stateStorage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir, project.SecretStores)
 	if err != nil {
 		return err
 	}
@@ -187,19 +222,29 @@ func Preview(
 	// Construct the preview operation
 	pc := &operation.PreviewOperation{
 		Operation: opsmodels.Operation{
-			OperationType: opsmodels.ApplyPreview,
-			Stack:         stack,
-			StateStorage:  storage,
-			IgnoreFields:  o.IgnoreFields,
-			ChangeOrder:   &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
-			SecretStores:  project.SecretStores,
+			OperationType:   opsmodels.ApplyPreview,
+			Stack:           stack,
+			StateStorage:    storage,
+			IgnoreFields:    o.IgnoreFields,
+			ServerSideApply: o.ServerSide,
+			ForceConflicts:  o.ForceConflicts,
+			Targets:         o.Targets,
+			TargetWithDeps:  o.TargetWithDeps,
+			Parallelism:     o.Parallelism,
+			Timeout:         o.Timeout,
+			Retries:         o.Retries,
+			ChangeOrder:     &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			SecretStores:    project.SecretStores,
 		},
 	}
 
 	log.Info("Start call pc.Preview() ...")
 
-	// parse cluster in arguments
-	cluster := util.ParseClusterArgument(o.Arguments)
+	// parse cluster in flags and arguments
+	cluster, err := util.ResolveCluster(o.Cluster, o.Arguments)
+	if err != nil {
+		return nil, err
+	}
 	rsp, s := pc.Preview(&operation.PreviewRequest{
 		Request: opsmodels.Request{
 			Tenant:   project.Tenant,
@@ -213,6 +258,16 @@ func Preview(
 	if status.IsErr(s) {
 		return nil, fmt.Errorf("preview failed.\n%s", s.String())
 	}
+	if len(o.Targets) != 0 && o.Output != jsonOutput {
+		WarnPartial(o.Targets)
+	}
 
 	return opsmodels.NewChanges(project, stack, rsp.Order), nil
 }
+
+// WarnPartial warns that only targeted resources are processed, and resources out of targets may be out of sync
+func WarnPartial(targets []string) {
+	pterm.Warning.Printfln("Targets %v are specified, only the targeted resources are processed. Other resources are "+
+		"left unchanged and may be out of sync with the stack, so this operation is partial and should not be routine",
+		targets)
+}
@@ -27,8 +27,14 @@ var (
 		# Preview with specifying setting file
 		kusion preview -Y settings.yaml
 
+		# Preview with the State of the specified cluster
+		kusion preview --cluster=cluster-a
+
 		# Preview with ignored fields
-		kusion preview --ignore-fields="metadata.generation,metadata.managedFields"`
+		kusion preview --ignore-fields="metadata.generation,metadata.managedFields"
+
+		# Preview only the specified resources and resources they depend on
+		kusion preview --target="apps/v1:Deployment:default:nginx" --target="v1:Service:default:*" --target-with-deps`
 )
 
 func NewCmdPreview() *cobra.Command {
@@ -58,6 +64,8 @@ func NewCmdPreview() *cobra.Command {
 func (o *PreviewOptions) AddPreviewFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.Operator, "operator", "", "",
 		i18n.T("Specify the operator"))
+	cmd.Flags().StringVarP(&o.Cluster, "cluster", "", "",
+		i18n.T("Specify the cluster of the State, overrides the top-level argument `cluster`"))
 	cmd.Flags().BoolVarP(&o.Detail, "detail", "d", false,
 		i18n.T("Automatically show plan details with interactive options"))
 	cmd.Flags().BoolVarP(&o.All, "all", "a", false,
@@ -68,4 +76,18 @@ func (o *PreviewOptions) AddPreviewFlags(cmd *cobra.Command) {
 		i18n.T("Ignore differences of target fields"))
 	cmd.Flags().StringVarP(&o.Output, "output", "o", "",
 		i18n.T("Specify the output format"))
+	cmd.Flags().BoolVarP(&o.ServerSide, "server-side", "", false,
+		i18n.T("Apply Kubernetes resources by the server-side apply, which merges resources by the server instead of the client"))
+	cmd.Flags().BoolVarP(&o.ForceConflicts, "force-conflicts", "", false,
+		i18n.T("Take the ownership of fields managed by others in the server-side apply, combined use with flag `--server-side`"))
+	cmd.Flags().StringArrayVarP(&o.Targets, "target", "", nil,
+		i18n.T("Only process resources with the specified ID, which can be repeated and supports \"*\" and \"?\" wildcards"))
+	cmd.Flags().BoolVarP(&o.TargetWithDeps, "target-with-deps", "", false,
+		i18n.T("Process resources the targeted resources depend on as well, combined use with flag `--target`"))
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", 0,
+		i18n.T("Limit the number of resources processed at once, 0 means no limit"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The default timeout of each runtime call of a resource, overridden by the timeout extension of the resource. 0 means no timeout"))
+	cmd.Flags().IntVarP(&o.Retries, "retries", "", 0,
+		i18n.T("The default max times of retrying a runtime call failed with a transient error, overridden by the retries extension of the resource. Calls of Exec and Helm resources are never retried"))
 }
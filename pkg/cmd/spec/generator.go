@@ -6,6 +6,7 @@ import (
 	"github.com/pterm/pterm"
 
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
 	"kusionstack.io/kusion/pkg/generator"
 	"kusionstack.io/kusion/pkg/generator/kcl"
 	"kusionstack.io/kusion/pkg/projectstack"
@@ -41,6 +42,10 @@ func GenerateSpecWithSpinner(o *generator.Options, project *projectstack.Project
 	}
 
 	spec, err := g.GenerateSpec(o, stack)
+	if err == nil {
+		// Scope Kubernetes resources by their clusters
+		err = kubernetes.ResolveClusters(spec, stack)
+	}
 	if err != nil {
 		if !o.NoPrompt && sp != nil {
 			sp.Fail()
@@ -0,0 +1,137 @@
+//go:build !arm64
+// +build !arm64
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
+)
+
+var resources = []models.Resource{
+	{ID: "v1:Namespace:default", Type: "Kubernetes"},
+	{ID: "apps/v1:Deployment:default:nginx", Type: "Kubernetes", DependsOn: []string{"v1:Namespace:default"}},
+}
+
+func TestListAndShowOptions_Run(t *testing.T) {
+	defer monkey.UnpatchAll()
+
+	t.Run("no state", func(t *testing.T) {
+		newTestStorage(t)
+		assert.Error(t, NewListOptions().Run())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		newTestStorage(t, resources...)
+		assert.Nil(t, NewListOptions().Run())
+	})
+
+	t.Run("show", func(t *testing.T) {
+		newTestStorage(t, resources...)
+		o := NewShowOptions()
+		o.Complete([]string{"v1:Namespace:default"})
+		assert.Nil(t, o.Validate())
+		assert.Nil(t, o.Run())
+
+		o.Complete([]string{"v1:Namespace:not-exist"})
+		assert.Error(t, o.Run())
+	})
+}
+
+func TestRmOptions_Run(t *testing.T) {
+	defer monkey.UnpatchAll()
+	storage := newTestStorage(t, resources...)
+
+	o := NewRmOptions()
+	o.Complete([]string{"v1:Namespace:not-exist"})
+	assert.Error(t, o.Run())
+
+	o.Complete([]string{"apps/v1:Deployment:default:nginx"})
+	assert.Nil(t, o.Run())
+
+	latest, err := storage.GetLatestState(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	assert.Equal(t, []string{"v1:Namespace:default"}, resourceIDs(latest.Resources))
+}
+
+func TestRmOptions_Run_Dependents(t *testing.T) {
+	defer monkey.UnpatchAll()
+	storage := newTestStorage(t, resources...)
+
+	// the deployment depends on the namespace
+	o := NewRmOptions()
+	o.Complete([]string{"v1:Namespace:default"})
+	assert.Error(t, o.Run())
+
+	o.Force = true
+	assert.Nil(t, o.Run())
+
+	latest, err := storage.GetLatestState(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	assert.Equal(t, []string{"apps/v1:Deployment:default:nginx"}, resourceIDs(latest.Resources))
+	assert.Empty(t, latest.Resources[0].DependsOn)
+}
+
+func TestMvOptions_Run(t *testing.T) {
+	defer monkey.UnpatchAll()
+	storage := newTestStorage(t, resources...)
+
+	o := NewMvOptions()
+	o.Complete([]string{"v1:Namespace:default", "apps/v1:Deployment:default:nginx"})
+	assert.Nil(t, o.Validate())
+	assert.Error(t, o.Run())
+
+	o.Complete([]string{"v1:Namespace:default", "v1:Namespace:foo"})
+	assert.Nil(t, o.Run())
+
+	latest, err := storage.GetLatestState(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	assert.Equal(t, []string{"v1:Namespace:foo", "apps/v1:Deployment:default:nginx"}, resourceIDs(latest.Resources))
+	assert.Equal(t, []string{"v1:Namespace:foo"}, latest.Resources[1].DependsOn)
+}
+
+func TestPullAndPushOptions_Run(t *testing.T) {
+	defer monkey.UnpatchAll()
+	storage := newTestStorage(t, resources...)
+	assert.Nil(t, NewPullOptions().Run())
+
+	pulled, err := storage.GetLatestState(nil)
+	assert.NoError(t, err)
+	pulled.Resources = pulled.Resources[:1]
+	file := filepath.Join(t.TempDir(), "pushed.json")
+	assert.NoError(t, os.WriteFile(file, []byte(jsonutil.Marshal2PrettyString(pulled)), 0o600))
+
+	o := NewPushOptions()
+	o.Complete([]string{file})
+	assert.Nil(t, o.Validate())
+	assert.Nil(t, o.Run())
+
+	latest, err := storage.GetLatestState(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	assert.Equal(t, []string{"v1:Namespace:default"}, resourceIDs(latest.Resources))
+
+	// the pushed serial 1 is older than the latest serial 2 now
+	assert.Error(t, o.Run())
+
+	o.Force = true
+	assert.Nil(t, o.Run())
+}
+
+func resourceIDs(resources models.Resources) []string {
+	var ids []string
+	for _, resource := range resources {
+		ids = append(ids, resource.ID)
+	}
+	return ids
+}
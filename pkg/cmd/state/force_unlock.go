@@ -0,0 +1,96 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	forceUnlockShort = "Release the lock of the State manually"
+
+	forceUnlockLong = `
+		Release the lock of the State manually by the lock ID.
+
+		The lock is acquired by operations like apply and destroy and released when they finish.
+		This command is used when the lock is left over by a process that crashed or was killed.
+		Make sure no one else is operating the stack before running it.`
+
+	forceUnlockExample = `
+		# Release the lock of the State in the current stack
+		kusion state force-unlock 6f1c7b0c2d8e4b3a9e5f0a1b2c3d4e5f
+
+		# Release the lock of the State in a specified cluster
+		kusion state force-unlock 6f1c7b0c2d8e4b3a9e5f0a1b2c3d4e5f --cluster=dev`
+)
+
+type ForceUnlockOptions struct {
+	StateOptions
+	LockID string
+}
+
+func NewForceUnlockOptions() *ForceUnlockOptions {
+	return &ForceUnlockOptions{}
+}
+
+func NewCmdForceUnlock() *cobra.Command {
+	o := NewForceUnlockOptions()
+
+	cmd := &cobra.Command{
+		Use:     "force-unlock LOCK_ID",
+		Short:   i18n.T(forceUnlockShort),
+		Long:    templates.LongDesc(i18n.T(forceUnlockLong)),
+		Example: templates.Examples(i18n.T(forceUnlockExample)),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+
+	return cmd
+}
+
+func (o *ForceUnlockOptions) Complete(args []string) {
+	if len(args) > 0 {
+		o.LockID = args[0]
+	}
+}
+
+func (o *ForceUnlockOptions) Validate() error {
+	if o.LockID == "" {
+		return errors.New("lock ID can not be empty")
+	}
+	return nil
+}
+
+func (o *ForceUnlockOptions) Run() error {
+	storage, query, err := o.StateStorage()
+	if err != nil {
+		return err
+	}
+
+	info := &states.LockInfo{
+		ID:      o.LockID,
+		Tenant:  query.Tenant,
+		Project: query.Project,
+		Stack:   query.Stack,
+		Cluster: query.Cluster,
+	}
+	if err = storage.Unlock(info); err != nil {
+		return err
+	}
+	fmt.Printf("State of stack %s has been unlocked\n", query.Stack)
+	return nil
+}
@@ -0,0 +1,76 @@
+//go:build !arm64
+// +build !arm64
+
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/backend"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/vals"
+)
+
+var (
+	project = &projectstack.Project{
+		ProjectConfiguration: projectstack.ProjectConfiguration{
+			Name:   "testdata",
+			Tenant: "admin",
+		},
+	}
+	stack = &projectstack.Stack{
+		StackConfiguration: projectstack.StackConfiguration{
+			Name: "dev",
+		},
+	}
+)
+
+func mockStateStorage(storage states.StateStorage) {
+	monkey.Patch(projectstack.DetectProjectAndStack, func(stackDir string) (*projectstack.Project, *projectstack.Stack, error) {
+		return project, stack, nil
+	})
+	monkey.Patch(backend.BackendFromConfig, func(config *backend.Storage, override backend.BackendOps, dir string, secretStores *vals.SecretStores) (states.StateStorage, error) {
+		return storage, nil
+	})
+}
+
+// newTestStorage returns a local StateStorage in a temp dir with the given resources applied in serial 1
+func newTestStorage(t *testing.T, resources ...models.Resource) *local.FileSystemState {
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	if len(resources) > 0 {
+		state := &states.State{Tenant: "admin", Project: "testdata", Stack: "dev", Serial: 1, Resources: resources}
+		assert.NoError(t, storage.Apply(state))
+	}
+	mockStateStorage(storage)
+	return storage
+}
+
+func TestForceUnlockOptions_Run(t *testing.T) {
+	defer monkey.UnpatchAll()
+	storage := newTestStorage(t)
+
+	info := states.NewLockInfo(&states.StateQuery{Tenant: "admin", Project: "testdata", Stack: "dev"}, "apply")
+	assert.NoError(t, storage.Lock(info))
+
+	t.Run("mismatched lock id", func(t *testing.T) {
+		o := NewForceUnlockOptions()
+		o.Complete([]string{"mismatched"})
+		assert.Nil(t, o.Validate())
+		assert.Error(t, o.Run())
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		o := NewForceUnlockOptions()
+		o.Complete([]string{info.ID})
+		assert.Nil(t, o.Validate())
+		assert.Nil(t, o.Run())
+		assert.NoError(t, storage.Lock(info))
+	})
+}
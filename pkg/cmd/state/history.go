@@ -0,0 +1,94 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	historyShort = "List the history of the State"
+
+	historyLong = `
+		List all States of the stack applied before, from the newest to the oldest.
+
+		The serial of a State can be used by 'kusion state rollback' to restore it.`
+
+	historyExample = `
+		# List the State history of the current stack
+		kusion state history
+
+		# List the State history of the stack in a specified work directory
+		kusion state history -w ./path/to/stack_dir`
+)
+
+type HistoryOptions struct {
+	StateOptions
+}
+
+func NewHistoryOptions() *HistoryOptions {
+	return &HistoryOptions{}
+}
+
+func NewCmdHistory() *cobra.Command {
+	o := NewHistoryOptions()
+
+	cmd := &cobra.Command{
+		Use:     "history",
+		Short:   i18n.T(historyShort),
+		Long:    templates.LongDesc(i18n.T(historyLong)),
+		Example: templates.Examples(i18n.T(historyExample)),
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+
+	return cmd
+}
+
+func (o *HistoryOptions) Run() error {
+	storage, query, err := o.StateStorage()
+	if err != nil {
+		return err
+	}
+
+	history, err := storage.GetStateHistory(query)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		fmt.Println("No State found in this stack")
+		return nil
+	}
+
+	tableData := pterm.TableData{{"Serial", "Operator", "Resources", "Kusion Version", "Modified Time"}}
+	for _, state := range history {
+		tableData = append(tableData, []string{
+			strconv.FormatUint(state.Serial, 10),
+			state.Operator,
+			strconv.Itoa(len(state.Resources)),
+			state.KusionVersion,
+			state.ModifiedTime.Format(time.RFC3339),
+		})
+	}
+	return pterm.DefaultTable.WithHasHeader().
+		WithHeaderStyle(&pterm.ThemeDefault.TableHeaderStyle).
+		WithLeftAlignment(true).
+		WithSeparator("  ").
+		WithData(tableData).
+		WithWriter(os.Stdout).
+		Render()
+}
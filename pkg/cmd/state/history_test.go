@@ -0,0 +1,66 @@
+//go:build !arm64
+// +build !arm64
+
+package state
+
+import (
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+)
+
+func TestHistoryOptions_Run(t *testing.T) {
+	defer monkey.UnpatchAll()
+	storage := newTestStorage(t)
+
+	t.Run("no state", func(t *testing.T) {
+		o := NewHistoryOptions()
+		assert.Nil(t, o.Run())
+	})
+
+	t.Run("list history", func(t *testing.T) {
+		for serial := uint64(1); serial <= 2; serial++ {
+			state := &states.State{Tenant: "admin", Project: "testdata", Stack: "dev", Serial: serial}
+			assert.NoError(t, storage.Apply(state))
+		}
+		o := NewHistoryOptions()
+		assert.Nil(t, o.Run())
+	})
+}
+
+func TestRollbackOptions_Validate(t *testing.T) {
+	o := NewRollbackOptions()
+	assert.Error(t, o.Validate())
+
+	o.Serial = 1
+	assert.Nil(t, o.Validate())
+}
+
+func TestRollbackOptions_Run(t *testing.T) {
+	defer monkey.UnpatchAll()
+
+	t.Run("no state", func(t *testing.T) {
+		newTestStorage(t)
+		o := NewRollbackOptions()
+		o.Serial = 1
+		assert.Error(t, o.Run())
+	})
+
+	t.Run("sensitive values", func(t *testing.T) {
+		secret := models.Resource{
+			ID:         "v1:Secret:default:password",
+			Type:       "Kubernetes",
+			Attributes: map[string]interface{}{"apiVersion": "v1", "kind": "Secret", "data": map[string]interface{}{"password": "MTIzNDU2"}},
+		}
+		newTestStorage(t, *secret.Redacted())
+		o := NewRollbackOptions()
+		o.Serial = 1
+		err := o.Run()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), secret.ID)
+	})
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	listShort = "List all resources in the State"
+
+	listLong = `
+		List the IDs of all resources recorded in the latest State of the stack.`
+
+	listExample = `
+		# List all resources in the State of the current stack
+		kusion state list`
+)
+
+type ListOptions struct {
+	StateOptions
+}
+
+func NewListOptions() *ListOptions {
+	return &ListOptions{}
+}
+
+func NewCmdList() *cobra.Command {
+	o := NewListOptions()
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T(listShort),
+		Long:    templates.LongDesc(i18n.T(listLong)),
+		Example: templates.Examples(i18n.T(listExample)),
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+
+	return cmd
+}
+
+func (o *ListOptions) Run() error {
+	state, err := o.latestState()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(state.Resources))
+	for _, resource := range state.Resources {
+		ids = append(ids, resource.ID)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	mvShort = "Rename a resource in the State"
+
+	mvLong = `
+		Rename the ID of a resource in the State without touching the actual infra resource.
+
+		This is useful when the ID of a resource is changed in the configuration and the resource
+		should not be deleted and created again. References in dependsOn are renamed as well.`
+
+	mvExample = `
+		# Rename a resource in the State of the current stack
+		kusion state mv apps/v1:Deployment:default:nginx apps/v1:Deployment:default:nginx-v2`
+)
+
+type MvOptions struct {
+	StateOptions
+	Source      string
+	Destination string
+}
+
+func NewMvOptions() *MvOptions {
+	return &MvOptions{}
+}
+
+func NewCmdMv() *cobra.Command {
+	o := NewMvOptions()
+
+	cmd := &cobra.Command{
+		Use:     "mv SOURCE DESTINATION",
+		Short:   i18n.T(mvShort),
+		Long:    templates.LongDesc(i18n.T(mvLong)),
+		Example: templates.Examples(i18n.T(mvExample)),
+		Args:    cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+
+	return cmd
+}
+
+func (o *MvOptions) Complete(args []string) {
+	if len(args) == 2 {
+		o.Source = args[0]
+		o.Destination = args[1]
+	}
+}
+
+func (o *MvOptions) Validate() error {
+	if o.Source == "" || o.Destination == "" {
+		return errors.New("source and destination can not be empty")
+	}
+	if o.Source == o.Destination {
+		return errors.New("source and destination can not be the same")
+	}
+	return nil
+}
+
+func (o *MvOptions) Run() error {
+	err := o.updateState("mv", func(latest *states.State) (*states.State, error) {
+		index := latest.Resources.Index()
+		if _, ok := index[o.Source]; !ok {
+			return nil, fmt.Errorf("can not find resource %s in the State", o.Source)
+		}
+		if _, ok := index[o.Destination]; ok {
+			return nil, fmt.Errorf("resource %s already exists in the State", o.Destination)
+		}
+
+		for i := range latest.Resources {
+			resource := &latest.Resources[i]
+			if resource.ID == o.Source {
+				resource.ID = o.Destination
+			}
+			for j, dependency := range resource.DependsOn {
+				if dependency == o.Source {
+					resource.DependsOn[j] = o.Destination
+				}
+			}
+		}
+		return latest, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Moved %s to %s\n", o.Source, o.Destination)
+	return nil
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"kusionstack.io/kusion/pkg/engine/backend"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+// StateOptions contains the options shared by all state subcommands
+type StateOptions struct {
+	WorkDir string
+	Cluster string
+	backend.BackendOps
+}
+
+// AddStateFlags adds the flags shared by all state subcommands
+func (o *StateOptions) AddStateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.WorkDir, "workdir", "w", "",
+		i18n.T("Specify the work directory"))
+	cmd.Flags().StringVarP(&o.Cluster, "cluster", "", "",
+		i18n.T("Specify the cluster of the State"))
+	o.AddBackendFlags(cmd)
+}
+
+// StateStorage returns the StateStorage configured for the stack in the work directory
+// and the StateQuery of the State managed by this stack
+func (o *StateOptions) StateStorage() (states.StateStorage, *states.StateQuery, error) {
+	project, stack, storage, err := o.detect()
+	if err != nil {
+		return nil, nil, err
+	}
+	return storage, o.query(project, stack), nil
+}
+
+// detect parses the project and stack of the work directory and builds the StateStorage from their backend config
+func (o *StateOptions) detect() (*projectstack.Project, *projectstack.Stack, states.StateStorage, error) {
+	project, stack, err := projectstack.DetectProjectAndStack(o.WorkDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	storage, err := backend.BackendFromConfig(project.Backend, o.BackendOps, o.WorkDir, project.SecretStores)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return project, stack, storage, nil
+}
+
+func (o *StateOptions) query(project *projectstack.Project, stack *projectstack.Stack) *states.StateQuery {
+	return &states.StateQuery{
+		Tenant:  project.Tenant,
+		Project: project.Name,
+		Stack:   stack.Name,
+		Cluster: o.Cluster,
+	}
+}
+
+// latestState returns the latest State of the stack and fails if there is no State
+func (o *StateOptions) latestState() (*states.State, error) {
+	storage, query, err := o.StateStorage()
+	if err != nil {
+		return nil, err
+	}
+	state, err := storage.GetLatestState(query)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("can not find State in this stack")
+	}
+	return state, nil
+}
+
+// updateState locks the State of the stack, passes the latest State to update and saves the updated State
+// as a new State with an increased Serial. An empty State is passed if there is no State in this stack
+func (o *StateOptions) updateState(operation string, update func(latest *states.State) (*states.State, error)) error {
+	storage, query, err := o.StateStorage()
+	if err != nil {
+		return err
+	}
+
+	info := states.NewLockInfo(query, operation)
+	if err = storage.Lock(info); err != nil {
+		return err
+	}
+	defer func() {
+		if err := storage.Unlock(info); err != nil {
+			log.Errorf("unlock State failed, run `kusion state force-unlock %s` to release the lock manually. %v", info.ID, err)
+		}
+	}()
+
+	latest, err := storage.GetLatestState(query)
+	if err != nil {
+		return err
+	}
+	if latest == nil {
+		latest = states.NewState()
+		latest.Tenant = query.Tenant
+		latest.Project = query.Project
+		latest.Stack = query.Stack
+		latest.Cluster = query.Cluster
+	}
+
+	state, err := update(latest)
+	if err != nil {
+		return err
+	}
+	// the updated State is always saved as a new one
+	state.ID = 0
+	state.Serial = latest.Serial + 1
+	return storage.Apply(state)
+}
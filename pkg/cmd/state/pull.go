@@ -0,0 +1,63 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
+)
+
+var (
+	pullShort = "Download the State"
+
+	pullLong = `
+		Download the latest State of the stack and print it to stdout in JSON format.
+
+		The output can be edited and uploaded by 'kusion state push'.`
+
+	pullExample = `
+		# Download the State of the current stack into a file
+		kusion state pull > kusion_state.json`
+)
+
+type PullOptions struct {
+	StateOptions
+}
+
+func NewPullOptions() *PullOptions {
+	return &PullOptions{}
+}
+
+func NewCmdPull() *cobra.Command {
+	o := NewPullOptions()
+
+	cmd := &cobra.Command{
+		Use:     "pull",
+		Short:   i18n.T(pullShort),
+		Long:    templates.LongDesc(i18n.T(pullLong)),
+		Example: templates.Examples(i18n.T(pullExample)),
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+
+	return cmd
+}
+
+func (o *PullOptions) Run() error {
+	state, err := o.latestState()
+	if err != nil {
+		return err
+	}
+	fmt.Println(jsonutil.Marshal2PrettyString(state))
+	return nil
+}
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	pushShort = "Upload a State"
+
+	pushLong = `
+		Upload a local State file as the latest State of the stack.
+
+		The uploaded State is saved with a Serial increased from the latest one. The upload is
+		rejected if the Serial in the file is older than the latest State, which means the State
+		has been changed since it was pulled. Use --force to skip this check.`
+
+	pushExample = `
+		# Upload a State file to the current stack
+		kusion state push kusion_state.json
+
+		# Upload a State file even if the State has been changed since it was pulled
+		kusion state push kusion_state.json --force`
+)
+
+type PushOptions struct {
+	StateOptions
+	File  string
+	Force bool
+}
+
+func NewPushOptions() *PushOptions {
+	return &PushOptions{}
+}
+
+func NewCmdPush() *cobra.Command {
+	o := NewPushOptions()
+
+	cmd := &cobra.Command{
+		Use:     "push FILE",
+		Short:   i18n.T(pushShort),
+		Long:    templates.LongDesc(i18n.T(pushLong)),
+		Example: templates.Examples(i18n.T(pushExample)),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+	cmd.Flags().BoolVarP(&o.Force, "force", "f", false,
+		i18n.T("Skip the Serial check and overwrite the latest State"))
+
+	return cmd
+}
+
+func (o *PushOptions) Complete(args []string) {
+	if len(args) > 0 {
+		o.File = args[0]
+	}
+}
+
+func (o *PushOptions) Validate() error {
+	if o.File == "" {
+		return errors.New("state file can not be empty")
+	}
+	if _, err := os.Stat(o.File); err != nil {
+		return fmt.Errorf("invalid state file: %v", err)
+	}
+	return nil
+}
+
+func (o *PushOptions) Run() error {
+	data, err := os.ReadFile(o.File)
+	if err != nil {
+		return err
+	}
+	pushed := &states.State{}
+	// JSON is a subset of YAML. Please check FileSystemState.GetLatestState for detail explanation
+	if err = yaml.Unmarshal(data, pushed); err != nil {
+		return fmt.Errorf("parse state file failed: %v", err)
+	}
+
+	err = o.updateState("push", func(latest *states.State) (*states.State, error) {
+		if pushed.Project != latest.Project || pushed.Stack != latest.Stack {
+			return nil, fmt.Errorf("state file belongs to project %s stack %s, but the current is project %s stack %s",
+				pushed.Project, pushed.Stack, latest.Project, latest.Stack)
+		}
+		if !o.Force && pushed.Serial < latest.Serial {
+			return nil, fmt.Errorf("state file serial %d is older than the latest serial %d, "+
+				"the State has been changed since it was pulled. Pull it again or push with --force", pushed.Serial, latest.Serial)
+		}
+		pushed.Tenant = latest.Tenant
+		pushed.Cluster = latest.Cluster
+		return pushed, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("State file %s has been pushed\n", o.File)
+	return nil
+}
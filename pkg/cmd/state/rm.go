@@ -0,0 +1,126 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	rmShort = "Remove resources from the State"
+
+	rmLong = `
+		Remove resources from the State without deleting the actual infra resources.
+
+		The removed resources are no longer managed by Kusion. They will be created again by the
+		next apply if they are still in the configuration.
+
+		Resources depending on the removed resources can not be removed unless --force is specified,
+		in which case the removed resources are dropped from their dependencies.`
+
+	rmExample = `
+		# Remove a resource from the State of the current stack
+		kusion state rm v1:Namespace:default
+
+		# Remove multiple resources
+		kusion state rm v1:Namespace:default apps/v1:Deployment:default:nginx
+
+		# Remove a resource other resources depend on
+		kusion state rm v1:Namespace:default --force`
+)
+
+type RmOptions struct {
+	StateOptions
+	IDs   []string
+	Force bool
+}
+
+func NewRmOptions() *RmOptions {
+	return &RmOptions{}
+}
+
+func NewCmdRm() *cobra.Command {
+	o := NewRmOptions()
+
+	cmd := &cobra.Command{
+		Use:     "rm ID...",
+		Short:   i18n.T(rmShort),
+		Long:    templates.LongDesc(i18n.T(rmLong)),
+		Example: templates.Examples(i18n.T(rmExample)),
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+	cmd.Flags().BoolVarP(&o.Force, "force", "", false,
+		i18n.T("Remove resources other resources depend on, and drop them from the dependencies"))
+
+	return cmd
+}
+
+func (o *RmOptions) Complete(args []string) {
+	o.IDs = args
+}
+
+func (o *RmOptions) Validate() error {
+	if len(o.IDs) == 0 {
+		return errors.New("resource IDs can not be empty")
+	}
+	return nil
+}
+
+func (o *RmOptions) Run() error {
+	err := o.updateState("rm", func(latest *states.State) (*states.State, error) {
+		index := latest.Resources.Index()
+		removed := make(map[string]bool, len(o.IDs))
+		for _, id := range o.IDs {
+			if _, ok := index[id]; !ok {
+				return nil, fmt.Errorf("can not find resource %s in the State", id)
+			}
+			removed[id] = true
+		}
+
+		resources := models.Resources{}
+		for _, resource := range latest.Resources {
+			if removed[resource.ID] {
+				continue
+			}
+			// dependencies on removed resources can't be resolved by later operations
+			dependsOn := make([]string, 0, len(resource.DependsOn))
+			for _, dependency := range resource.DependsOn {
+				if !removed[dependency] {
+					dependsOn = append(dependsOn, dependency)
+				} else if !o.Force {
+					return nil, fmt.Errorf("resource %s depends on %s, specify --force to remove it anyway", resource.ID, dependency)
+				}
+			}
+			if len(dependsOn) != len(resource.DependsOn) {
+				resource.DependsOn = dependsOn
+			}
+			resources = append(resources, resource)
+		}
+		latest.Resources = resources
+		return latest, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range o.IDs {
+		fmt.Printf("Removed %s\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	applycmd "kusionstack.io/kusion/pkg/cmd/apply"
+	previewcmd "kusionstack.io/kusion/pkg/cmd/preview"
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	rollbackShort = "Rollback resources to a State applied before"
+
+	rollbackLong = `
+		Rollback resources to the State with the specified serial.
+
+		The resources recorded in that State are previewed and applied just like 'kusion apply',
+		and the result is saved as a new State. Run 'kusion state history' to list the serials.
+
+		States with sensitive values can not be rolled back to, since these values are only recorded as hashes.`
+
+	rollbackExample = `
+		# Rollback the current stack to the State with serial 3
+		kusion state rollback --serial 3
+
+		# Rollback without prompting
+		kusion state rollback --serial 3 --yes`
+)
+
+type RollbackOptions struct {
+	StateOptions
+	Serial   uint64
+	Operator string
+	Yes      bool
+	Detail   bool
+}
+
+func NewRollbackOptions() *RollbackOptions {
+	return &RollbackOptions{}
+}
+
+func NewCmdRollback() *cobra.Command {
+	o := NewRollbackOptions()
+
+	cmd := &cobra.Command{
+		Use:     "rollback",
+		Short:   i18n.T(rollbackShort),
+		Long:    templates.LongDesc(i18n.T(rollbackLong)),
+		Example: templates.Examples(i18n.T(rollbackExample)),
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+	cmd.Flags().Uint64VarP(&o.Serial, "serial", "", 0,
+		i18n.T("Specify the serial of the State to rollback to"))
+	cmd.Flags().StringVarP(&o.Operator, "operator", "", "",
+		i18n.T("Specify the operator"))
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", false,
+		i18n.T("Automatically approve and perform the rollback after previewing it"))
+	cmd.Flags().BoolVarP(&o.Detail, "detail", "d", false,
+		i18n.T("Automatically show plan details after previewing it"))
+
+	return cmd
+}
+
+func (o *RollbackOptions) Validate() error {
+	if o.Serial == 0 {
+		return errors.New("serial must be specified")
+	}
+	return nil
+}
+
+func (o *RollbackOptions) Run() error {
+	project, stack, storage, err := o.detect()
+	if err != nil {
+		return err
+	}
+
+	query := o.query(project, stack)
+	target, err := storage.GetStateBySerial(query, o.Serial)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("can not find State with serial %d in this stack", o.Serial)
+	}
+	// sensitive values are stored as hashes, which must not be applied to resources
+	var sensitive []string
+	for i := range target.Resources {
+		if len(target.Resources[i].AllSensitivePaths()) > 0 {
+			sensitive = append(sensitive, target.Resources[i].ID)
+		}
+	}
+	if len(sensitive) > 0 {
+		return fmt.Errorf("can not rollback to the State with serial %d since sensitive values of resources %v are not "+
+			"recorded in it, apply the spec of that version instead", o.Serial, sensitive)
+	}
+
+	// reuse the apply flow to reconcile resources to the target State
+	ao := applycmd.NewApplyOptions()
+	ao.WorkDir = o.WorkDir
+	ao.BackendOps = o.BackendOps
+	ao.Operator = o.Operator
+	ao.Yes = o.Yes
+	ao.Cluster = o.Cluster
+
+	sp := &models.Spec{Resources: target.Resources}
+	changes, err := previewcmd.Preview(&ao.PreviewOptions, storage, sp, project, stack)
+	if err != nil {
+		return err
+	}
+	if changes.AllUnChange() {
+		fmt.Println("All resources are reconciled. No diff found")
+		return nil
+	}
+
+	// Summary preview table
+	changes.Summary(os.Stdout)
+
+	// Detail detection
+	if o.Detail {
+		changes.OutputDiff("all")
+		return nil
+	}
+
+	// Prompt
+	if !o.Yes {
+		for {
+			input, err := prompt()
+			if err != nil {
+				return err
+			}
+			if input == "yes" {
+				break
+			} else if input == "details" {
+				detail, err := changes.PromptDetails()
+				if err != nil {
+					return err
+				}
+				changes.OutputDiff(detail)
+			} else {
+				fmt.Println("Operation rollback canceled")
+				return nil
+			}
+		}
+	}
+
+	fmt.Printf("Start rolling back to the State with serial %d ...\n", o.Serial)
+	return applycmd.Apply(ao, storage, sp, changes, os.Stdout)
+}
+
+func prompt() (string, error) {
+	prompt := &survey.Select{
+		Message: `Do you want to rollback to this State?`,
+		Options: []string{"yes", "details", "no"},
+		Default: "details",
+	}
+
+	var input string
+	err := survey.AskOne(prompt, &input)
+	if err != nil {
+		fmt.Printf("Prompt failed %v\n", err)
+		return "", err
+	}
+	return input, nil
+}
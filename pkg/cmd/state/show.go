@@ -0,0 +1,83 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
+)
+
+var (
+	showShort = "Show a resource in the State"
+
+	showLong = `
+		Show the attributes of a resource recorded in the latest State of the stack.`
+
+	showExample = `
+		# Show a resource in the State of the current stack
+		kusion state show v1:Namespace:default`
+)
+
+type ShowOptions struct {
+	StateOptions
+	ID string
+}
+
+func NewShowOptions() *ShowOptions {
+	return &ShowOptions{}
+}
+
+func NewCmdShow() *cobra.Command {
+	o := NewShowOptions()
+
+	cmd := &cobra.Command{
+		Use:     "show ID",
+		Short:   i18n.T(showShort),
+		Long:    templates.LongDesc(i18n.T(showLong)),
+		Example: templates.Examples(i18n.T(showExample)),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	o.AddStateFlags(cmd)
+
+	return cmd
+}
+
+func (o *ShowOptions) Complete(args []string) {
+	if len(args) > 0 {
+		o.ID = args[0]
+	}
+}
+
+func (o *ShowOptions) Validate() error {
+	if o.ID == "" {
+		return errors.New("resource ID can not be empty")
+	}
+	return nil
+}
+
+func (o *ShowOptions) Run() error {
+	state, err := o.latestState()
+	if err != nil {
+		return err
+	}
+
+	resource, ok := state.Resources.Index()[o.ID]
+	if !ok {
+		return fmt.Errorf("can not find resource %s in the State", o.ID)
+	}
+	fmt.Println(jsonutil.Marshal2PrettyString(resource))
+	return nil
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	stateShort = "Manage the State of a stack"
+
+	stateLong = `
+		Manage the State of the stack in the current directory or the specified work directory.
+
+		The State is read from and written to the backend configured in project.yaml, which
+		can be overridden by the backend flags.`
+
+	stateExample = `
+		# List all resources in the State of the current stack
+		kusion state list
+
+		# Show a resource in the State of the current stack
+		kusion state show v1:Namespace:default
+
+		# List the State history of the current stack
+		kusion state history
+
+		# Rollback the current stack to the State with serial 3
+		kusion state rollback --serial 3
+
+		# Release the lock held by a crashed apply
+		kusion state force-unlock 6f1c7b0c2d8e4b3a9e5f0a1b2c3d4e5f`
+)
+
+func NewCmdState() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "state",
+		Short:   i18n.T(stateShort),
+		Long:    templates.LongDesc(i18n.T(stateLong)),
+		Example: templates.Examples(i18n.T(stateExample)),
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(NewCmdList())
+	cmd.AddCommand(NewCmdShow())
+	cmd.AddCommand(NewCmdRm())
+	cmd.AddCommand(NewCmdMv())
+	cmd.AddCommand(NewCmdPull())
+	cmd.AddCommand(NewCmdPush())
+	cmd.AddCommand(NewCmdHistory())
+	cmd.AddCommand(NewCmdRollback())
+	cmd.AddCommand(NewCmdForceUnlock())
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -35,3 +36,16 @@ func ParseClusterArgument(args []string) string {
 	}
 	return cluster
 }
+
+// ResolveCluster returns the cluster specified by the flag `--cluster`, or the one in the top-level arguments
+// like `-D cluster=xxx` if the flag is not set. It is an error to specify different clusters in both ways.
+func ResolveCluster(cluster string, args []string) (string, error) {
+	argCluster := ParseClusterArgument(args)
+	if cluster == "" {
+		return argCluster, nil
+	}
+	if argCluster != "" && argCluster != cluster {
+		return "", fmt.Errorf("conflicting clusters: --cluster=%s and -D cluster=%s", cluster, argCluster)
+	}
+	return cluster, nil
+}
@@ -57,3 +57,34 @@ func TestParseClusterArgument(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveCluster(t *testing.T) {
+	type args struct {
+		cluster string
+		args    []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{name: "flag", args: args{cluster: "fake"}, want: "fake"},
+		{name: "argument", args: args{args: []string{"cluster=fake"}}, want: "fake"},
+		{name: "flag_and_argument", args: args{cluster: "fake", args: []string{"cluster=fake"}}, want: "fake"},
+		{name: "conflict", args: args{cluster: "fake", args: []string{"cluster=other"}}, wantErr: true},
+		{name: "empty", args: args{}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveCluster(tt.args.cluster, tt.args.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ResolveCluster() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ResolveCluster() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
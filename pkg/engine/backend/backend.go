@@ -11,14 +11,19 @@ import (
 
 	backendInit "kusionstack.io/kusion/pkg/engine/backend/init"
 	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/encryption"
 	"kusionstack.io/kusion/pkg/engine/states/local"
 	"kusionstack.io/kusion/pkg/util/i18n"
+	"kusionstack.io/kusion/pkg/vals"
 )
 
 // backend config state storage type
 type Storage struct {
 	Type   string                 `json:"storageType,omitempty" yaml:"storageType,omitempty"`
 	Config map[string]interface{} `json:"config,omitempty" yaml:"config,omitempty"`
+
+	// Encryption encrypts States at rest if it is configured
+	Encryption *encryption.Config `json:"encryption,omitempty" yaml:"encryption,omitempty"`
 }
 
 // BackendOps kusion cli backend override config
@@ -67,8 +72,10 @@ func NewDefaultBackend(dir string, fileName string) *Storage {
 
 // BackendFromConfig return stateStorage, this func handler
 // backend config merge and configure backend.
-// return a StateStorage to manage State
-func BackendFromConfig(config *Storage, override BackendOps, dir string) (states.StateStorage, error) {
+// return a StateStorage to manage State, which encrypts States if
+// encryption is configured. secretStores are the secret stores of
+// the project, which may be used by the encryption.
+func BackendFromConfig(config *Storage, override BackendOps, dir string, secretStores *vals.SecretStores) (states.StateStorage, error) {
 	var backendConfig Storage
 	if config == nil {
 		config = NewDefaultBackend(dir, local.KusionState)
@@ -114,7 +121,11 @@ func BackendFromConfig(config *Storage, override BackendOps, dir string) (states
 		return nil, err
 	}
 
-	return bf.StateStorage(), nil
+	encrypter, err := encryption.NewEncrypter(config.Encryption, dir, secretStores)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.NewEncryptedState(bf.StateStorage(), encrypter), nil
 }
 
 // validBackendConfig check backend config.
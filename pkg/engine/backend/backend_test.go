@@ -8,6 +8,7 @@ import (
 
 	_ "kusionstack.io/kusion/pkg/engine/backend/init"
 	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/encryption"
 	"kusionstack.io/kusion/pkg/engine/states/local"
 )
 
@@ -79,14 +80,14 @@ func TestBackendFromConfig(t *testing.T) {
 				},
 			},
 			want: want{
-				storage: &local.FileSystemState{Path: "kusion_local.json"},
+				storage: encryption.NewEncryptedState(&local.FileSystemState{Path: "kusion_local.json"}, nil),
 				err:     nil,
 			},
 		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			storage, _ := BackendFromConfig(tt.config, tt.override, "./")
+			storage, _ := BackendFromConfig(tt.config, tt.override, "./", nil)
 			if diff := cmp.Diff(tt.want.storage, storage); diff != "" {
 				t.Errorf("\nWrapBackendFromConfigFailed(...): -want message, +got message:\n%s", diff)
 			}
@@ -0,0 +1,235 @@
+package mapper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect is the kind of database that tables state and state_lock are stored in.
+// SQL built by gendry uses "?" as placeholders and doesn't quote identifiers, so the only differences between
+// dialects we care about are placeholders, errors of unique keys and DDL of tables.
+type Dialect string
+
+const (
+	// MySQL is the default Dialect, the zero value of Dialect is treated as MySQL
+	MySQL      Dialect = "mysql"
+	PostgreSQL Dialect = "postgres"
+	SQLite     Dialect = "sqlite"
+)
+
+const (
+	// mysqlErrDupEntry is the MySQL error number of duplicate entry for a unique key
+	mysqlErrDupEntry = 1062
+	// mysqlErrDupKeyName is the MySQL error number of adding an index whose name already exists
+	mysqlErrDupKeyName = 1061
+	// pqErrUniqueViolation is the PostgreSQL error code of unique violation
+	pqErrUniqueViolation = "23505"
+)
+
+// Dialects are all supported Dialects
+var Dialects = []Dialect{MySQL, PostgreSQL, SQLite}
+
+// IsValid returns true if d is a supported Dialect
+func (d Dialect) IsValid() bool {
+	for _, dialect := range Dialects {
+		if d == dialect {
+			return true
+		}
+	}
+	return d == ""
+}
+
+// Rebind converts "?" placeholders in the query to placeholders of this Dialect
+func (d Dialect) Rebind(query string) string {
+	if d != PostgreSQL {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// IsDuplicateKey returns true if the err is caused by inserting a row whose unique key already exists
+func (d Dialect) IsDuplicateKey(err error) bool {
+	switch d {
+	case PostgreSQL:
+		var pqErr *pq.Error
+		return errors.As(err, &pqErr) && pqErr.Code == pqErrUniqueViolation
+	case SQLite:
+		var sqliteErr *sqlite.Error
+		return errors.As(err, &sqliteErr) &&
+			(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+	default:
+		return isMySQLError(err, mysqlErrDupEntry)
+	}
+}
+
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
+
+// execer executes statements of migrations, which is implemented by *sql.DB
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Migrate creates tables state and state_lock with their indexes if they don't exist
+func (d Dialect) Migrate(db *sql.DB) error {
+	if nil == db {
+		return errors.New("sql.DB is nil")
+	}
+	return d.migrate(db)
+}
+
+func (d Dialect) migrate(db execer) error {
+	var ddl []string
+	switch d {
+	case PostgreSQL:
+		ddl = postgresDDL
+	case SQLite:
+		ddl = sqliteDDL
+	default:
+		ddl = mysqlDDL
+	}
+	for _, stmt := range ddl {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate %s schema failed: %w", d, err)
+		}
+	}
+	if d != MySQL && d != "" {
+		return nil
+	}
+	for _, stmt := range mysqlIndexes {
+		_, err := db.Exec(stmt)
+		if err != nil && !isMySQLError(err, mysqlErrDupKeyName) {
+			return fmt.Errorf("migrate %s schema failed, rows with the same serial in a stack should be removed before adding the unique key: %w", d, err)
+		}
+	}
+	return nil
+}
+
+// insertReturningID executes the insertion and returns the auto-increment ID of the last inserted row
+func (d Dialect) insertReturningID(db *sql.DB, cond string, values []interface{}) (int64, error) {
+	if d == PostgreSQL {
+		// lib/pq doesn't support LastInsertId
+		var id int64
+		err := db.QueryRow(cond+" RETURNING id", values...).Scan(&id)
+		return id, err
+	}
+
+	result, err := db.Exec(cond, values...)
+	if nil != err || nil == result {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+var mysqlDDL = []string{
+	"CREATE TABLE IF NOT EXISTS `state` (" +
+		"`id` bigint unsigned NOT NULL AUTO_INCREMENT," +
+		"`tenant` varchar(100) NOT NULL DEFAULT ''," +
+		"`project` varchar(100) NOT NULL," +
+		"`stack` varchar(100) NOT NULL," +
+		"`cluster` varchar(100) NOT NULL DEFAULT ''," +
+		"`version` int NOT NULL DEFAULT 0," +
+		"`kusion_version` varchar(32) NOT NULL DEFAULT ''," +
+		"`serial` bigint unsigned NOT NULL," +
+		"`operator` varchar(255) NOT NULL DEFAULT ''," +
+		"`resources` longtext NOT NULL," +
+		"`create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+		"`modified_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP," +
+		"PRIMARY KEY (`id`)," +
+		"UNIQUE KEY `uk_state` (`tenant`, `project`, `stack`, `cluster`, `serial`))",
+	"CREATE TABLE IF NOT EXISTS `state_lock` (" +
+		"`id` varchar(64) NOT NULL," +
+		"`tenant` varchar(100) NOT NULL DEFAULT ''," +
+		"`project` varchar(100) NOT NULL," +
+		"`stack` varchar(100) NOT NULL," +
+		"`cluster` varchar(100) NOT NULL DEFAULT ''," +
+		"`operation` varchar(32) NOT NULL DEFAULT ''," +
+		"`who` varchar(255) NOT NULL DEFAULT ''," +
+		"`kusion_version` varchar(32) NOT NULL DEFAULT ''," +
+		"`create_time` datetime NOT NULL," +
+		"PRIMARY KEY (`id`)," +
+		"UNIQUE KEY `uk_state_lock` (`tenant`, `project`, `stack`, `cluster`))",
+}
+
+// mysqlIndexes add unique keys to tables created before the keys are declared in mysqlDDL. MySQL doesn't support
+// CREATE INDEX IF NOT EXISTS, so errors of existing keys are ignored
+var mysqlIndexes = []string{
+	"ALTER TABLE `state` ADD UNIQUE KEY `uk_state` (`tenant`, `project`, `stack`, `cluster`, `serial`)",
+}
+
+var postgresDDL = []string{
+	"CREATE TABLE IF NOT EXISTS state (" +
+		"id BIGSERIAL PRIMARY KEY," +
+		"tenant VARCHAR(100) NOT NULL DEFAULT ''," +
+		"project VARCHAR(100) NOT NULL," +
+		"stack VARCHAR(100) NOT NULL," +
+		"cluster VARCHAR(100) NOT NULL DEFAULT ''," +
+		"version INTEGER NOT NULL DEFAULT 0," +
+		"kusion_version VARCHAR(32) NOT NULL DEFAULT ''," +
+		"serial BIGINT NOT NULL," +
+		"operator VARCHAR(255) NOT NULL DEFAULT ''," +
+		"resources TEXT NOT NULL," +
+		"create_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+		"modified_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS uk_state ON state (tenant, project, stack, cluster, serial)",
+	"CREATE TABLE IF NOT EXISTS state_lock (" +
+		"id VARCHAR(64) PRIMARY KEY," +
+		"tenant VARCHAR(100) NOT NULL DEFAULT ''," +
+		"project VARCHAR(100) NOT NULL," +
+		"stack VARCHAR(100) NOT NULL," +
+		"cluster VARCHAR(100) NOT NULL DEFAULT ''," +
+		"operation VARCHAR(32) NOT NULL DEFAULT ''," +
+		"who VARCHAR(255) NOT NULL DEFAULT ''," +
+		"kusion_version VARCHAR(32) NOT NULL DEFAULT ''," +
+		"create_time TIMESTAMP WITH TIME ZONE NOT NULL)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS uk_state_lock ON state_lock (tenant, project, stack, cluster)",
+}
+
+var sqliteDDL = []string{
+	"CREATE TABLE IF NOT EXISTS state (" +
+		"id INTEGER PRIMARY KEY AUTOINCREMENT," +
+		"tenant TEXT NOT NULL DEFAULT ''," +
+		"project TEXT NOT NULL," +
+		"stack TEXT NOT NULL," +
+		"cluster TEXT NOT NULL DEFAULT ''," +
+		"version INTEGER NOT NULL DEFAULT 0," +
+		"kusion_version TEXT NOT NULL DEFAULT ''," +
+		"serial INTEGER NOT NULL," +
+		"operator TEXT NOT NULL DEFAULT ''," +
+		"resources TEXT NOT NULL," +
+		"create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+		"modified_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS uk_state ON state (tenant, project, stack, cluster, serial)",
+	"CREATE TABLE IF NOT EXISTS state_lock (" +
+		"id TEXT PRIMARY KEY," +
+		"tenant TEXT NOT NULL DEFAULT ''," +
+		"project TEXT NOT NULL," +
+		"stack TEXT NOT NULL," +
+		"cluster TEXT NOT NULL DEFAULT ''," +
+		"operation TEXT NOT NULL DEFAULT ''," +
+		"who TEXT NOT NULL DEFAULT ''," +
+		"kusion_version TEXT NOT NULL DEFAULT ''," +
+		"create_time DATETIME NOT NULL)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS uk_state_lock ON state_lock (tenant, project, stack, cluster)",
+}
@@ -0,0 +1,103 @@
+package mapper
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_Rebind(t *testing.T) {
+	query := "SELECT * FROM state WHERE (tenant=? AND project=?)"
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{name: "mysql", dialect: MySQL, want: query},
+		{name: "sqlite", dialect: SQLite, want: query},
+		{name: "postgres", dialect: PostgreSQL, want: "SELECT * FROM state WHERE (tenant=$1 AND project=$2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.dialect.Rebind(query))
+		})
+	}
+}
+
+func TestDialect_IsDuplicateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		err     error
+		want    bool
+	}{
+		{name: "mysql", dialect: MySQL, err: &mysql.MySQLError{Number: mysqlErrDupEntry}, want: true},
+		{name: "default_mysql", dialect: "", err: &mysql.MySQLError{Number: mysqlErrDupEntry}, want: true},
+		{name: "postgres", dialect: PostgreSQL, err: &pq.Error{Code: pqErrUniqueViolation}, want: true},
+		{name: "other_error", dialect: PostgreSQL, err: &mysql.MySQLError{Number: mysqlErrDupEntry}, want: false},
+		{name: "nil", dialect: SQLite, err: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.dialect.IsDuplicateKey(tt.err))
+		})
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "kusion.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// migration is idempotent
+	assert.NoError(t, SQLite.Migrate(db))
+	assert.NoError(t, SQLite.Migrate(db))
+
+	row := map[string]interface{}{"tenant": "t", "project": "p", "stack": "s", "serial": 1, "resources": "[]"}
+	id, err := Insert(db, SQLite, []map[string]interface{}{row})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	// the unique key rejects the same serial
+	_, err = Insert(db, SQLite, []map[string]interface{}{row})
+	assert.True(t, SQLite.IsDuplicateKey(err))
+
+	stateDO, err := GetOne(db, SQLite, map[string]interface{}{"project": "p", "serial": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "s", stateDO.Stack)
+}
+
+// fakeExecer records executed statements, and fails statements adding existing keys
+type fakeExecer struct {
+	stmts      []string
+	existedKey bool
+}
+
+func (f *fakeExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	f.stmts = append(f.stmts, query)
+	if f.existedKey && strings.HasPrefix(query, "ALTER TABLE") {
+		return nil, &mysql.MySQLError{Number: mysqlErrDupKeyName, Message: "Duplicate key name 'uk_state'"}
+	}
+	return nil, nil
+}
+
+func TestMySQL_Migrate(t *testing.T) {
+	// the unique key is added to the existing table
+	db := &fakeExecer{}
+	assert.NoError(t, MySQL.migrate(db))
+	assert.Equal(t, append(append([]string{}, mysqlDDL...), mysqlIndexes...), db.stmts)
+
+	// the key has been added
+	db = &fakeExecer{existedKey: true}
+	assert.NoError(t, MySQL.migrate(db))
+
+	// other dialects create indexes with DDL
+	db = &fakeExecer{}
+	assert.NoError(t, PostgreSQL.migrate(db))
+	assert.Equal(t, postgresDDL, db.stmts)
+}
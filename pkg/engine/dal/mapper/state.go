@@ -25,7 +25,7 @@ type StateDO struct {
 }
 
 // GetOne gets one record from table build_task by condition "where"
-func GetOne(db *sql.DB, where map[string]interface{}) (*StateDO, error) {
+func GetOne(db *sql.DB, dialect Dialect, where map[string]interface{}) (*StateDO, error) {
 	if nil == db {
 		return nil, errors.New("sql.DB is nil")
 	}
@@ -33,7 +33,7 @@ func GetOne(db *sql.DB, where map[string]interface{}) (*StateDO, error) {
 	if nil != err {
 		return nil, err
 	}
-	row, err := db.Query(cond, values...)
+	row, err := db.Query(dialect.Rebind(cond), values...)
 	if nil != err || nil == row {
 		return nil, err
 	}
@@ -44,8 +44,28 @@ func GetOne(db *sql.DB, where map[string]interface{}) (*StateDO, error) {
 	return dbRes, err
 }
 
-// Insert inserts an array of data into table StateDO
-func Insert(db *sql.DB, data []map[string]interface{}) (int64, error) {
+// GetList gets a list of records from table state by condition "where"
+func GetList(db *sql.DB, dialect Dialect, where map[string]interface{}) ([]*StateDO, error) {
+	if nil == db {
+		return nil, errors.New("sql.DB is nil")
+	}
+	cond, values, err := builder.BuildSelect("state", where, nil)
+	if nil != err {
+		return nil, err
+	}
+	row, err := db.Query(dialect.Rebind(cond), values...)
+	if nil != err || nil == row {
+		return nil, err
+	}
+	defer row.Close()
+	var dbRes []*StateDO
+	scanner.SetTagName("json")
+	err = scanner.Scan(row, &dbRes)
+	return dbRes, err
+}
+
+// Insert inserts an array of data into table state and returns the ID of the last inserted row
+func Insert(db *sql.DB, dialect Dialect, data []map[string]interface{}) (int64, error) {
 	if nil == db {
 		return 0, errors.New("sql.DB is nil")
 	}
@@ -55,10 +75,24 @@ func Insert(db *sql.DB, data []map[string]interface{}) (int64, error) {
 		return 0, err
 	}
 
-	result, err := db.Exec(cond, values...)
+	return dialect.insertReturningID(db, dialect.Rebind(cond), values)
+}
+
+// Delete deletes records from table state by condition "where"
+func Delete(db *sql.DB, dialect Dialect, where map[string]interface{}) (int64, error) {
+	if nil == db {
+		return 0, errors.New("sql.DB is nil")
+	}
+
+	cond, values, err := builder.BuildDelete("state", where)
+	if nil != err {
+		return 0, err
+	}
+
+	result, err := db.Exec(dialect.Rebind(cond), values...)
 	if nil != err || nil == result {
 		return 0, err
 	}
 
-	return result.LastInsertId()
+	return result.RowsAffected()
 }
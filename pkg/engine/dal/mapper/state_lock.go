@@ -0,0 +1,78 @@
+package mapper
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/didi/gendry/builder"
+	"github.com/didi/gendry/scanner"
+	"github.com/pkg/errors"
+)
+
+// StateLockDO is a row of table state_lock. The table MUST have a unique key on (tenant, project, stack, cluster)
+// so that only one lock can be inserted for a State at the same time.
+type StateLockDO struct {
+	ID            string    `json:"id"`
+	Tenant        string    `json:"tenant"`
+	Project       string    `json:"project"`
+	Stack         string    `json:"stack"`
+	Cluster       string    `json:"cluster"`
+	Operation     string    `json:"operation"`
+	Who           string    `json:"who"`
+	KusionVersion string    `json:"kusion_version"`
+	CreateTime    time.Time `json:"create_time"`
+}
+
+// GetLock gets one record from table state_lock by condition "where"
+func GetLock(db *sql.DB, dialect Dialect, where map[string]interface{}) (*StateLockDO, error) {
+	if nil == db {
+		return nil, errors.New("sql.DB is nil")
+	}
+	cond, values, err := builder.BuildSelect("state_lock", where, nil)
+	if nil != err {
+		return nil, err
+	}
+	row, err := db.Query(dialect.Rebind(cond), values...)
+	if nil != err || nil == row {
+		return nil, err
+	}
+	defer row.Close()
+	var dbRes *StateLockDO
+	scanner.SetTagName("json")
+	err = scanner.Scan(row, &dbRes)
+	return dbRes, err
+}
+
+// InsertLock inserts an array of data into table state_lock
+func InsertLock(db *sql.DB, dialect Dialect, data []map[string]interface{}) error {
+	if nil == db {
+		return errors.New("sql.DB is nil")
+	}
+
+	cond, values, err := builder.BuildInsert("state_lock", data)
+	if nil != err {
+		return err
+	}
+
+	_, err = db.Exec(dialect.Rebind(cond), values...)
+	return err
+}
+
+// DeleteLock deletes records from table state_lock by condition "where"
+func DeleteLock(db *sql.DB, dialect Dialect, where map[string]interface{}) (int64, error) {
+	if nil == db {
+		return 0, errors.New("sql.DB is nil")
+	}
+
+	cond, values, err := builder.BuildDelete("state_lock", where)
+	if nil != err {
+		return 0, err
+	}
+
+	result, err := db.Exec(dialect.Rebind(cond), values...)
+	if nil != err || nil == result {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -22,6 +22,10 @@ type Resource struct {
 
 	// Extensions specifies arbitrary metadata of this resource
 	Extensions map[string]interface{} `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+
+	// SensitivePaths contains JSON pointers of attributes whose values are resolved from secret references.
+	// Values of these attributes are hashed in States and masked in outputs
+	SensitivePaths []string `json:"sensitivePaths,omitempty" yaml:"sensitivePaths,omitempty"`
 }
 
 func (r *Resource) ResourceKey() string {
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SensitiveValuePrefix is the prefix of hashes which replace sensitive attribute values
+const SensitiveValuePrefix = "kusion+sha256:"
+
+// JSONPointer returns the JSON pointer (RFC 6901) of the attribute located by tokens
+func JSONPointer(tokens ...string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// AllSensitivePaths returns JSON pointers of all sensitive attributes of this resource, including SensitivePaths and
+// all keys in data and stringData of the Kubernetes Secret
+func (r *Resource) AllSensitivePaths() []string {
+	paths := append([]string{}, r.SensitivePaths...)
+	if r.Type == "Kubernetes" && r.Attributes["apiVersion"] == "v1" && r.Attributes["kind"] == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			data, _ := r.Attributes[field].(map[string]interface{})
+			for key := range data {
+				paths = append(paths, JSONPointer(field, key))
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Redacted returns a copy of this resource whose sensitive attribute values are replaced by their hashes, so that
+// they can be stored and printed without leaking secrets. The hash of a value is salted with the resource ID and the
+// attribute path, and is stable across operations, so that the rotation of a secret can still be detected by
+// comparing redacted resources. Hashes are kept as they are, which makes Redacted idempotent.
+func (r *Resource) Redacted() *Resource {
+	if r == nil {
+		return nil
+	}
+	out := r.DeepCopy()
+	for _, path := range r.AllSensitivePaths() {
+		redactAttribute(out.Attributes, path, func(v interface{}) interface{} {
+			return hashSensitiveValue(r.ID, path, v)
+		})
+	}
+	return out
+}
+
+// redactAttribute replaces the attribute located by the JSON pointer with the result of redact, missing attributes
+// are ignored
+func redactAttribute(obj interface{}, pointer string, redact func(interface{}) interface{}) {
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		last := i == len(tokens)-1
+		switch o := obj.(type) {
+		case map[string]interface{}:
+			v, ok := o[token]
+			if !ok {
+				return
+			}
+			if last {
+				o[token] = redact(v)
+				return
+			}
+			obj = v
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(o) {
+				return
+			}
+			if last {
+				o[index] = redact(o[index])
+				return
+			}
+			obj = o[index]
+		default:
+			return
+		}
+	}
+}
+
+func hashSensitiveValue(id, path string, v interface{}) string {
+	var data []byte
+	switch value := v.(type) {
+	case string:
+		if strings.HasPrefix(value, SensitiveValuePrefix) {
+			return value
+		}
+		data = []byte(value)
+	default:
+		data, _ = json.Marshal(value)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(id))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(data)
+	return SensitiveValuePrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// WithSensitiveValuesFrom returns a copy of this resource whose sensitive attributes take the values of the same
+// attributes of the plain resource, which restores plaintext values of attributes hashed in States. Attributes missing
+// in the plain resource are kept as they are
+func (r *Resource) WithSensitiveValuesFrom(plain *Resource) *Resource {
+	if r == nil {
+		return nil
+	}
+	out := r.DeepCopy()
+	if plain == nil {
+		return out
+	}
+	for _, path := range r.AllSensitivePaths() {
+		if v, ok := lookupAttribute(plain.Attributes, path); ok {
+			redactAttribute(out.Attributes, path, func(interface{}) interface{} {
+				return v
+			})
+		}
+	}
+	return out
+}
+
+// HasSensitiveHash returns whether the value is or contains a hash of a sensitive value
+func HasSensitiveHash(v interface{}) bool {
+	switch value := v.(type) {
+	case string:
+		return strings.HasPrefix(value, SensitiveValuePrefix)
+	case []interface{}:
+		for _, item := range value {
+			if HasSensitiveHash(item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range value {
+			if HasSensitiveHash(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupAttribute returns the attribute located by the JSON pointer
+func lookupAttribute(obj interface{}, pointer string) (interface{}, bool) {
+	found := false
+	var value interface{}
+	redactAttribute(obj, pointer, func(v interface{}) interface{} {
+		found, value = true, v
+		return v
+	})
+	return value, found
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResource_Redacted(t *testing.T) {
+	secret := &Resource{
+		ID:   "v1:Secret:default:password",
+		Type: "Kubernetes",
+		Attributes: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"data":       map[string]interface{}{"tls.crt": "Y2VydA=="},
+			"stringData": map[string]interface{}{"password": "123456"},
+		},
+	}
+	app := &Resource{
+		ID:   "app",
+		Type: "Terraform",
+		Attributes: map[string]interface{}{
+			"user":     "root",
+			"password": "123456",
+			"env":      []interface{}{map[string]interface{}{"value": "token"}},
+		},
+		SensitivePaths: []string{"/password", "/env/0/value", "/missing/0"},
+	}
+
+	tests := []struct {
+		name     string
+		resource *Resource
+		redacted []string
+	}{
+		{
+			name:     "kubernetes_secret",
+			resource: secret,
+			redacted: []string{"/data/tls.crt", "/stringData/password"},
+		},
+		{
+			name:     "sensitive_paths",
+			resource: app,
+			redacted: []string{"/env/0/value", "/password"},
+		},
+		{
+			name:     "nil",
+			resource: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted := tt.resource.Redacted()
+			if tt.resource == nil {
+				assert.Nil(t, redacted)
+				return
+			}
+			assert.NotContains(t, string(mustMarshal(t, redacted)), "123456")
+			for _, path := range tt.redacted {
+				v, _ := lookupAttribute(redacted.Attributes, path)
+				assert.True(t, strings.HasPrefix(v.(string), SensitiveValuePrefix), path)
+			}
+			// the original resource is not modified
+			assert.NotEqual(t, tt.resource.Attributes, redacted.Attributes)
+			// redacting twice makes no difference
+			assert.Equal(t, redacted, redacted.Redacted())
+		})
+	}
+
+	// the hash is stable and changes with the value
+	assert.Equal(t, app.Redacted(), app.Redacted())
+	rotated := app.DeepCopy()
+	rotated.Attributes["password"] = "654321"
+	assert.NotEqual(t, app.Redacted().Attributes["password"], rotated.Redacted().Attributes["password"])
+	assert.Equal(t, app.Redacted().Attributes["env"], rotated.Redacted().Attributes["env"])
+}
+
+func TestResource_WithSensitiveValuesFrom(t *testing.T) {
+	plain := &Resource{
+		ID:   "app",
+		Type: "Terraform",
+		Attributes: map[string]interface{}{
+			"password": "123456",
+			"env":      []interface{}{map[string]interface{}{"value": "token"}},
+		},
+		SensitivePaths: []string{"/password", "/env/0/value"},
+	}
+	prior := plain.Redacted()
+	prior.Attributes["id"] = "generated"
+	assert.True(t, HasSensitiveHash(prior.Attributes))
+
+	restored := prior.WithSensitiveValuesFrom(plain)
+	assert.False(t, HasSensitiveHash(restored.Attributes))
+	assert.Equal(t, "123456", restored.Attributes["password"])
+	assert.Equal(t, "generated", restored.Attributes["id"])
+	// the prior resource is not modified
+	assert.True(t, HasSensitiveHash(prior.Attributes["password"]))
+
+	// hashes are kept without plaintext values
+	assert.True(t, HasSensitiveHash(prior.WithSensitiveValuesFrom(nil).Attributes))
+}
+
+func mustMarshal(t *testing.T, r *Resource) []byte {
+	data, err := json.Marshal(r)
+	assert.NoError(t, err)
+	return data
+}
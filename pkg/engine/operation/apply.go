@@ -27,6 +27,9 @@ type ApplyRequest struct {
 
 type ApplyResponse struct {
 	State *states.State
+
+	// Rollback is the outcome of reverting applied resources when the apply fails with RollbackOnFailure
+	Rollback *RollbackReport
 }
 
 func NewApplyGraph(m *models.Spec, priorState *states.State) (*dag.AcyclicGraph, status.Status) {
@@ -77,6 +80,13 @@ func (ao *ApplyOperation) Apply(request *ApplyRequest) (rsp *ApplyResponse, st s
 		return nil, st
 	}
 
+	// 0. lock the State during the whole operation
+	lockInfo, err := o.LockState(&request.Request, "apply")
+	if err != nil {
+		return nil, status.NewErrorStatus(err)
+	}
+	defer o.UnlockState(lockInfo)
+
 	// 1. init & build Indexes
 	priorState, resultState := o.InitStates(&request.Request)
 	priorStateResourceIndex := priorState.Resources.Index()
@@ -99,15 +109,29 @@ func (ao *ApplyOperation) Apply(request *ApplyRequest) (rsp *ApplyResponse, st s
 	if status.IsErr(s) {
 		return nil, s
 	}
+	ctxResourceIndex := map[string]*models.Resource{}
+	if s = targetGraph(applyGraph, &o, request.Spec, priorStateResourceIndex, ctxResourceIndex); status.IsErr(s) {
+		return nil, s
+	}
 	log.Infof("Apply Graph:\n%s", applyGraph.String())
 
 	applyOperation := &ApplyOperation{
 		Operation: opsmodels.Operation{
+			Ctx:                     o.Ctx,
 			OperationType:           opsmodels.Apply,
 			StateStorage:            o.StateStorage,
-			CtxResourceIndex:        map[string]*models.Resource{},
+			CtxResourceIndex:        ctxResourceIndex,
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      stateResourceIndex,
+			ServerSideApply:         o.ServerSideApply,
+			ForceConflicts:          o.ForceConflicts,
+			Wait:                    o.Wait,
+			WaitTimeout:             o.WaitTimeout,
+			RollbackOnFailure:       o.RollbackOnFailure,
+			Parallelism:             o.Parallelism,
+			ContinueOnError:         o.ContinueOnError,
+			Timeout:                 o.Timeout,
+			Retries:                 o.Retries,
 			RuntimeMap:              o.RuntimeMap,
 			Stack:                   o.Stack,
 			MsgCh:                   o.MsgCh,
@@ -117,12 +141,22 @@ func (ao *ApplyOperation) Apply(request *ApplyRequest) (rsp *ApplyResponse, st s
 		},
 	}
 
-	w := &dag.Walker{Callback: applyOperation.applyWalkFun}
+	w := &dag.Walker{Callback: newWalkLimiter(&applyOperation.Operation).wrap(applyOperation.applyWalkFun)}
 	w.Update(applyGraph)
 	// Wait
-	if diags := w.Wait(); diags.HasErrors() {
+	diags := w.Wait()
+	if applyOperation.Context().Err() != nil {
+		st = cancelledStatus("apply", &applyOperation.Operation, diags)
+	} else if diags.HasErrors() {
 		st = status.NewErrorStatus(diags.Err())
-		return nil, st
+	}
+	if status.IsErr(st) {
+		if !o.RollbackOnFailure {
+			return nil, st
+		}
+		// revert applied resources, the response contains the State after rollback and the rollback report
+		report := applyOperation.rollback()
+		return &ApplyResponse{State: resultState, Rollback: report}, st
 	}
 
 	return &ApplyResponse{State: resultState}, nil
@@ -160,10 +194,10 @@ func (ao *ApplyOperation) applyWalkFun(v dag.Vertex) (diags tfdiags.Diagnostics)
 			if status.IsErr(s) {
 				o.MsgCh <- opsmodels.Message{
 					ResourceID: rn.Hashcode().(string), OpResult: opsmodels.Failed,
-					OpErr: fmt.Errorf("node execte failed, status:\n%v", s),
+					OpErr: fmt.Errorf("node execte failed, status:\n%v", s), Retries: rn.Retries(),
 				}
 			} else {
-				o.MsgCh <- opsmodels.Message{ResourceID: rn.Hashcode().(string), OpResult: opsmodels.Success}
+				o.MsgCh <- opsmodels.Message{ResourceID: rn.Hashcode().(string), OpResult: opsmodels.Success, Retries: rn.Retries()}
 			}
 		} else {
 			s = node.Execute(o)
@@ -4,6 +4,7 @@
 package operation
 
 import (
+	"context"
 	"path/filepath"
 	"reflect"
 	"sync"
@@ -148,7 +149,7 @@ func TestOperation_Apply(t *testing.T) {
 				Operator: "faker",
 				Spec:     mf,
 			}}},
-			wantRsp: &ApplyResponse{rs},
+			wantRsp: &ApplyResponse{State: rs},
 			wantSt:  nil,
 		},
 	}
@@ -172,6 +173,7 @@ func TestOperation_Apply(t *testing.T) {
 				Operation: *o,
 			}
 
+			defer monkey.UnpatchAll()
 			monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, operation *opsmodels.Operation) status.Status {
 				o.ResultState = rs
 				return nil
@@ -186,3 +188,122 @@ func TestOperation_Apply(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyOperation_Targets(t *testing.T) {
+	priorA, priorB, priorD := newRollbackResource("a", 1), newRollbackResource("b", 1, "a"), newRollbackResource("d", 1)
+	rt := &fakeRollbackRuntime{live: map[string]*models.Resource{
+		"a": priorA.DeepCopy(),
+		"b": priorB.DeepCopy(),
+		"d": priorD.DeepCopy(),
+	}}
+	defer monkey.UnpatchAll()
+	monkey.Patch(runtimeinit.Runtimes, func(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
+		return map[models.Type]runtime.Runtime{runtime.Kubernetes: rt}, nil
+	})
+
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev"}}
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{Name: "demo"}}
+	assert.NoError(t, storage.Apply(&states.State{
+		Project: "demo", Stack: "dev", Serial: 1, Resources: models.Resources{priorA, priorB, priorD},
+	}))
+
+	msgCh := make(chan opsmodels.Message)
+	go func() {
+		for range msgCh {
+		}
+	}()
+	ao := &ApplyOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		MsgCh:        msgCh,
+		Stack:        stack,
+		Targets:      []string{"b"},
+	}}
+	// b refers to a, which is out of targets and resolved from the prior state
+	b := newRollbackResource("b", 2, "a")
+	b.Attributes["ref"] = "$kusion_path.a.v"
+	rsp, st := ao.Apply(&ApplyRequest{Request: opsmodels.Request{
+		Project: project,
+		Stack:   stack,
+		Spec: &models.Spec{Resources: models.Resources{
+			newRollbackResource("a", 2),
+			b,
+			newRollbackResource("c", 1),
+		}},
+	}})
+	assert.Nil(t, st)
+
+	// only b is updated, a isn't updated, c isn't created and d isn't deleted
+	assert.Len(t, rt.live, 3)
+	assert.EqualValues(t, 1, rt.live["a"].Attributes["v"])
+	assert.EqualValues(t, 2, rt.live["b"].Attributes["v"])
+	assert.EqualValues(t, 1, rt.live["b"].Attributes["ref"])
+	assert.Contains(t, rt.live, "d")
+
+	index := rsp.State.Resources.Index()
+	assert.Len(t, index, 3)
+	assert.EqualValues(t, 1, index["a"].Attributes["v"])
+	assert.EqualValues(t, 2, index["b"].Attributes["v"])
+	assert.EqualValues(t, 1, index["d"].Attributes["v"])
+}
+
+// cancelRuntime cancels the operation after the resource named by cancelAt is applied
+type cancelRuntime struct {
+	*fakeRollbackRuntime
+	cancelAt string
+	cancel   context.CancelFunc
+}
+
+func (c *cancelRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	response := c.fakeRollbackRuntime.Apply(ctx, request)
+	if !request.DryRun && request.PlanResource.ID == c.cancelAt {
+		c.cancel()
+	}
+	return response
+}
+
+func TestApplyOperation_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rt := &cancelRuntime{fakeRollbackRuntime: &fakeRollbackRuntime{live: map[string]*models.Resource{}}, cancelAt: "a", cancel: cancel}
+	defer monkey.UnpatchAll()
+	monkey.Patch(runtimeinit.Runtimes, func(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
+		return map[models.Type]runtime.Runtime{runtime.Kubernetes: rt}, nil
+	})
+
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev"}}
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{Name: "demo"}}
+
+	msgCh := make(chan opsmodels.Message)
+	go func() {
+		for range msgCh {
+		}
+	}()
+	ao := &ApplyOperation{Operation: opsmodels.Operation{
+		Ctx:          ctx,
+		StateStorage: storage,
+		MsgCh:        msgCh,
+		Stack:        stack,
+	}}
+	_, st := ao.Apply(&ApplyRequest{Request: opsmodels.Request{
+		Project: project,
+		Stack:   stack,
+		Spec: &models.Spec{Resources: models.Resources{
+			newRollbackResource("a", 1),
+			newRollbackResource("b", 1, "a"),
+		}},
+	}})
+	if assert.True(t, status.IsErr(st)) {
+		assert.Equal(t, status.Canceled, st.Code())
+	}
+
+	// a is applied before the cancellation and saved in the State, and b is skipped
+	assert.Len(t, rt.live, 1)
+	assert.Contains(t, rt.live, "a")
+	latest, err := storage.GetLatestState(&states.StateQuery{Project: "demo", Stack: "dev"})
+	assert.NoError(t, err)
+	if assert.Len(t, latest.Resources, 1) {
+		assert.Equal(t, "a", latest.Resources[0].ID)
+	}
+}
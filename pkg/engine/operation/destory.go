@@ -22,6 +22,8 @@ type DestroyOperation struct {
 
 type DestroyRequest struct {
 	opsmodels.Request `json:",inline" yaml:",inline"`
+	// PurgeState deletes the State and its history after all resources are destroyed
+	PurgeState bool `json:"purgeState,omitempty" yaml:"purgeState,omitempty"`
 }
 
 func NewDestroyGraph(resource models.Resources) (*dag.AcyclicGraph, status.Status) {
@@ -61,6 +63,13 @@ func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status)
 		return st
 	}
 
+	// 0. lock the State during the whole operation
+	lockInfo, err := o.LockState(&request.Request, "destroy")
+	if err != nil {
+		return status.NewErrorStatus(err)
+	}
+	defer o.UnlockState(lockInfo)
+
 	// 1. init & build Indexes
 	priorState, resultState := o.InitStates(&request.Request)
 	priorStateResourceIndex := priorState.Resources.Index()
@@ -83,14 +92,23 @@ func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status)
 	if status.IsErr(s) {
 		return s
 	}
+	ctxResourceIndex := map[string]*models.Resource{}
+	if s = targetGraph(destroyGraph, &o, nil, priorStateResourceIndex, ctxResourceIndex); status.IsErr(s) {
+		return s
+	}
 
 	newDo := &DestroyOperation{
 		Operation: opsmodels.Operation{
+			Ctx:                     o.Ctx,
 			OperationType:           opsmodels.Destroy,
 			StateStorage:            o.StateStorage,
-			CtxResourceIndex:        map[string]*models.Resource{},
+			CtxResourceIndex:        ctxResourceIndex,
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      stateResourceIndex,
+			Parallelism:             o.Parallelism,
+			ContinueOnError:         o.ContinueOnError,
+			Timeout:                 o.Timeout,
+			Retries:                 o.Retries,
 			RuntimeMap:              o.RuntimeMap,
 			Stack:                   o.Stack,
 			MsgCh:                   o.MsgCh,
@@ -99,16 +117,44 @@ func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status)
 		},
 	}
 
-	w := &dag.Walker{Callback: newDo.destroyWalkFun}
+	w := &dag.Walker{Callback: newWalkLimiter(&newDo.Operation).wrap(newDo.destroyWalkFun)}
 	w.Update(destroyGraph)
 	// Wait
-	if diags := w.Wait(); diags.HasErrors() {
+	diags := w.Wait()
+	if newDo.Context().Err() != nil {
+		return cancelledStatus("destroy", &newDo.Operation, diags)
+	}
+	if diags.HasErrors() {
 		st = status.NewErrorStatus(diags.Err())
 		return st
 	}
+
+	// 3. purge the State with the lock still held, so that States applied by others are never deleted
+	if request.PurgeState {
+		if err = purgeState(&newDo.Operation, &request.Request); err != nil {
+			return status.NewErrorStatus(err)
+		}
+	}
 	return nil
 }
 
+// purgeState deletes the State and all its history. The latest State is read again and must be the one written by this
+// destroy without any resources
+func purgeState(o *opsmodels.Operation, request *opsmodels.Request) error {
+	query := opsmodels.NewStateQuery(request)
+	latest, err := o.StateStorage.GetLatestState(query)
+	if err != nil {
+		return err
+	}
+	if latest != nil && latest.Serial != o.ResultState.Serial {
+		return fmt.Errorf("refuse to purge the State, the latest serial %d is not %d written by this destroy", latest.Serial, o.ResultState.Serial)
+	}
+	if latest != nil && len(latest.Resources) != 0 {
+		return fmt.Errorf("refuse to purge the State, %d resources are still recorded in it", len(latest.Resources))
+	}
+	return o.StateStorage.Delete(query, 0)
+}
+
 func (do *DestroyOperation) destroyWalkFun(v dag.Vertex) (diags tfdiags.Diagnostics) {
 	ao := &ApplyOperation{
 		Operation: do.Operation,
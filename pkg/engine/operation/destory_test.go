@@ -62,7 +62,7 @@ func TestOperation_Destroy(t *testing.T) {
 		},
 	}
 	r := &DestroyRequest{
-		opsmodels.Request{
+		Request: opsmodels.Request{
 			Tenant:   tenant,
 			Stack:    stack,
 			Project:  project,
@@ -106,6 +106,66 @@ func TestOperation_Destroy(t *testing.T) {
 		st := o.Destroy(r)
 		assert.True(t, status.IsErr(st))
 	})
+
+	t.Run("purge state", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, operation *opsmodels.Operation) status.Status {
+			return nil
+		})
+		// the State read by purge has no resources
+		read := 0
+		monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "GetLatestState", func(f *local.FileSystemState, query *states.StateQuery) (*states.State, error) {
+			read++
+			if read == 1 {
+				return &states.State{Serial: 1, Resources: []models.Resource{resourceState}}, nil
+			}
+			return &states.State{Serial: 1}, nil
+		})
+		deleted := false
+		monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "Delete", func(f *local.FileSystemState, query *states.StateQuery, serial uint64) error {
+			deleted = serial == 0
+			return nil
+		})
+		monkey.Patch(kubernetes.NewKubernetesRuntime, func() (runtime.Runtime, error) {
+			return &fakerRuntime{}, nil
+		})
+
+		o.MsgCh = make(chan opsmodels.Message, 1)
+		go readMsgCh(o.MsgCh)
+		st := o.Destroy(&DestroyRequest{Request: r.Request, PurgeState: true})
+		assert.Nil(t, st)
+		assert.True(t, deleted)
+	})
+
+	t.Run("refuse to purge state applied by others", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, operation *opsmodels.Operation) status.Status {
+			return nil
+		})
+		// another State is applied after this destroy
+		read := 0
+		monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "GetLatestState", func(f *local.FileSystemState, query *states.StateQuery) (*states.State, error) {
+			read++
+			if read == 1 {
+				return &states.State{Serial: 1, Resources: []models.Resource{resourceState}}, nil
+			}
+			return &states.State{Serial: 2, Resources: []models.Resource{resourceState}}, nil
+		})
+		deleted := false
+		monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "Delete", func(f *local.FileSystemState, query *states.StateQuery, serial uint64) error {
+			deleted = true
+			return nil
+		})
+		monkey.Patch(kubernetes.NewKubernetesRuntime, func() (runtime.Runtime, error) {
+			return &fakerRuntime{}, nil
+		})
+
+		o.MsgCh = make(chan opsmodels.Message, 1)
+		go readMsgCh(o.MsgCh)
+		st := o.Destroy(&DestroyRequest{Request: r.Request, PurgeState: true})
+		assert.True(t, status.IsErr(st))
+		assert.False(t, deleted)
+	})
 }
 
 func readMsgCh(ch chan opsmodels.Message) {
@@ -37,13 +37,7 @@ func (d *Diff) Diff(request *DiffRequest) (string, error) {
 	plan := request.Spec
 
 	// Get the latest state resources
-	latestState, err := d.StateStorage.GetLatestState(
-		&states.StateQuery{
-			Tenant:  request.Tenant,
-			Stack:   request.Stack.Name,
-			Project: request.Project.Name,
-		},
-	)
+	latestState, err := d.StateStorage.GetLatestState(opsmodels.NewStateQuery(&request.Request))
 	if err != nil {
 		return "", errors.Wrap(err, "GetLatestState failed")
 	}
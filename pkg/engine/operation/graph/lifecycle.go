@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"fmt"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+const (
+	// PreventDestroyExtension is the extension of resources to fail operations which delete the resource, e.g. true
+	PreventDestroyExtension = "preventDestroy"
+
+	// IgnoreChangesExtension is the extension of resources to specify fields ignored when computing the diff of the
+	// resource, which works like the IgnoreFields of operations, e.g. ["spec.replicas"]
+	IgnoreChangesExtension = "ignoreChanges"
+
+	// CreateBeforeDestroyExtension is the extension of resources to delete the resource after resources replacing it
+	// are created, e.g. true
+	CreateBeforeDestroyExtension = "createBeforeDestroy"
+
+	// ReplacesExtension is the extension of resources to specify IDs of resources replaced by the resource, which are
+	// deleted after the resource is created if they are marked by CreateBeforeDestroyExtension,
+	// e.g. ["apps/v1:Deployment:default:nginx-v1"]
+	ReplacesExtension = "replaces"
+)
+
+// PreventDestroy returns whether the resource must not be deleted
+func PreventDestroy(resource *models.Resource) (bool, error) {
+	return boolExtension(resource, PreventDestroyExtension)
+}
+
+// CreateBeforeDestroy returns whether the resource is deleted after resources replacing it are created
+func CreateBeforeDestroy(resource *models.Resource) (bool, error) {
+	return boolExtension(resource, CreateBeforeDestroyExtension)
+}
+
+// Replaces returns IDs of resources replaced by the resource
+func Replaces(resource *models.Resource) ([]string, error) {
+	return stringsExtension(resource, ReplacesExtension, "resource IDs")
+}
+
+// ignoreChanges returns fields of the resource ignored when computing the diff
+func ignoreChanges(resource *models.Resource) ([]string, error) {
+	return stringsExtension(resource, IgnoreChangesExtension, "field paths")
+}
+
+func stringsExtension(resource *models.Resource, key, items string) ([]string, error) {
+	if resource == nil {
+		return nil, nil
+	}
+	v, ok := resource.Extensions[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	switch values := v.(type) {
+	case []string:
+		return values, nil
+	case []interface{}:
+		result := make([]string, 0, len(values))
+		for _, value := range values {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("the %s extension of resource %s should be a list of %s", key, resource.ResourceKey(), items)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("the %s extension of resource %s should be a list of %s", key, resource.ResourceKey(), items)
+	}
+}
+
+func boolExtension(resource *models.Resource, key string) (bool, error) {
+	if resource == nil {
+		return false, nil
+	}
+	v, ok := resource.Extensions[key]
+	if !ok || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("the %s extension of resource %s should be a boolean", key, resource.ResourceKey())
+	}
+	return b, nil
+}
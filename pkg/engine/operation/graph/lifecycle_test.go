@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// fakeDryRunRuntime returns the plan resource with the replicas changed by the server in dry runs
+type fakeDryRunRuntime struct {
+	runtime.Runtime
+}
+
+func (f *fakeDryRunRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	resource := *request.PlanResource
+	resource.Attributes = map[string]interface{}{"spec": map[string]interface{}{"replicas": 3, "image": "nginx"}}
+	return &runtime.ApplyResponse{Resource: &resource}
+}
+
+func TestIgnoreChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions map[string]interface{}
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:       "fields",
+			extensions: map[string]interface{}{IgnoreChangesExtension: []interface{}{"spec.replicas", "metadata.labels"}},
+			want:       []string{"spec.replicas", "metadata.labels"},
+		},
+		{
+			name:       "illegal field",
+			extensions: map[string]interface{}{IgnoreChangesExtension: []interface{}{"spec.replicas", 1}},
+			wantErr:    true,
+		},
+		{
+			name:       "not a list",
+			extensions: map[string]interface{}{IgnoreChangesExtension: "spec.replicas"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ignoreChanges(&models.Resource{ID: "id", Extensions: tt.extensions})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, fields)
+		})
+	}
+}
+
+func TestResourceNode_computeActionType(t *testing.T) {
+	live := func(extensions map[string]interface{}) *models.Resource {
+		return &models.Resource{
+			ID:         "id",
+			Type:       runtime.Kubernetes,
+			Attributes: map[string]interface{}{"spec": map[string]interface{}{"replicas": 1, "image": "nginx"}},
+			Extensions: extensions,
+		}
+	}
+	tests := []struct {
+		name          string
+		operationType opsmodels.OperationType
+		action        opsmodels.ActionType
+		extensions    map[string]interface{}
+		want          opsmodels.ActionType
+		wantCode      status.Code
+	}{
+		{
+			name:          "update",
+			operationType: opsmodels.ApplyPreview,
+			action:        opsmodels.Update,
+			want:          opsmodels.Update,
+		},
+		{
+			name:          "ignore changes",
+			operationType: opsmodels.ApplyPreview,
+			action:        opsmodels.Update,
+			extensions:    map[string]interface{}{IgnoreChangesExtension: []interface{}{"spec.replicas"}},
+			want:          opsmodels.UnChange,
+		},
+		{
+			name:          "illegal ignore changes",
+			operationType: opsmodels.ApplyPreview,
+			action:        opsmodels.Update,
+			extensions:    map[string]interface{}{IgnoreChangesExtension: "spec.replicas"},
+			wantCode:      status.IllegalManifest,
+		},
+		{
+			name:          "delete",
+			operationType: opsmodels.Destroy,
+			action:        opsmodels.Delete,
+			want:          opsmodels.Delete,
+		},
+		{
+			name:          "prevent destroy",
+			operationType: opsmodels.Destroy,
+			action:        opsmodels.Delete,
+			extensions:    map[string]interface{}{PreventDestroyExtension: true},
+			wantCode:      status.InvalidArgument,
+		},
+		{
+			name:          "prevent deleting removed resource",
+			operationType: opsmodels.Apply,
+			action:        opsmodels.Delete,
+			extensions:    map[string]interface{}{PreventDestroyExtension: true},
+			wantCode:      status.InvalidArgument,
+		},
+		{
+			name:          "illegal prevent destroy",
+			operationType: opsmodels.DestroyPreview,
+			action:        opsmodels.Delete,
+			extensions:    map[string]interface{}{PreventDestroyExtension: "true"},
+			wantCode:      status.IllegalManifest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := live(tt.extensions)
+			rn, _ := NewResourceNode("id", resource, tt.action)
+			operation := &opsmodels.Operation{
+				OperationType: tt.operationType,
+				RuntimeMap:    map[models.Type]runtime.Runtime{runtime.Kubernetes: &fakeDryRunRuntime{}},
+			}
+
+			planned := resource
+			if tt.action == opsmodels.Delete {
+				planned = nil
+			}
+			_, _, s := rn.computeActionType(operation, planned, live(tt.extensions), live(tt.extensions))
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, tt.want, rn.Action)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/printers"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const (
+	// WaitTimeoutExtension is the key of the extension which overrides the timeout of waiting for the resource to be
+	// ready, e.g. "10m"
+	WaitTimeoutExtension = "waitTimeout"
+
+	// DefaultWaitTimeout is the default timeout of waiting for a resource to be ready
+	DefaultWaitTimeout = 5 * time.Minute
+)
+
+// waitForReady blocks until the runtime reports the resource and all resources it generates are ready, with the same
+// readiness logic as the watch of apply. Resources which don't support watching are regarded as ready
+func (rn *ResourceNode) waitForReady(operation *opsmodels.Operation) status.Status {
+	timeout, err := waitTimeout(rn.resource, operation.WaitTimeout)
+	if err != nil {
+		return status.NewErrorStatusWithCode(status.InvalidArgument, err)
+	}
+	ctx, cancel := context.WithTimeout(operation.Context(), timeout)
+	defer cancel()
+
+	response := operation.RuntimeMap[rn.resource.Type].Watch(ctx, &runtime.WatchRequest{
+		Resource: rn.resource,
+		Stack:    operation.Stack,
+	})
+	if response == nil {
+		log.Infof("resource %s doesn't support watching, skip waiting", rn.resource.ResourceKey())
+		return nil
+	}
+	if status.IsErr(response.Status) {
+		return response.Status
+	}
+	if response.Watchers == nil || len(response.Watchers.Watchers) == 0 {
+		return nil
+	}
+
+	table := printers.NewTable(response.Watchers.IDs)
+	cases := make([]reflect.SelectCase, 0, len(response.Watchers.Watchers)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, ch := range response.Watchers.Watchers {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	for !table.AllCompleted() {
+		chosen, recv, recvOK := reflect.Select(cases)
+		if chosen == 0 {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return status.NewErrorStatusWithCode(status.Canceled, fmt.Errorf(
+					"waiting for resource %s to be ready is cancelled", rn.resource.ResourceKey()))
+			}
+			return status.NewErrorStatusWithCode(status.DeadlineExceeded, fmt.Errorf(
+				"resource %s is not ready in %s, last observed status: %s", rn.resource.ResourceKey(), timeout, lastObserved(table)))
+		}
+		if !recvOK {
+			// the watcher is closed, stop receiving from it
+			cases[chosen].Chan = reflect.ValueOf((<-chan k8swatch.Event)(nil))
+			continue
+		}
+
+		e := recv.Interface().(k8swatch.Event)
+		o, ok := e.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		var detail string
+		var ready bool
+		if e.Type == k8swatch.Deleted {
+			detail = fmt.Sprintf("%s has been deleted", o.GetName())
+			ready = true
+		} else {
+			detail, ready = printers.Generate(printers.Convert(o))
+		}
+		if ready {
+			e.Type = printers.READY
+		}
+		table.Update(engine.BuildIDForKubernetes(o), printers.NewRow(e.Type, o.GetKind(), o.GetName(), detail))
+	}
+	log.Infof("resource %s is ready", rn.resource.ResourceKey())
+	return nil
+}
+
+// waitTimeout returns the timeout of waiting for the resource to be ready
+func waitTimeout(resource *models.Resource, defaultTimeout time.Duration) (time.Duration, error) {
+	if v, ok := resource.Extensions[WaitTimeoutExtension]; ok && v != nil {
+		s, ok := v.(string)
+		if !ok {
+			return 0, fmt.Errorf("the %s extension of resource %s should be a duration string", WaitTimeoutExtension, resource.ResourceKey())
+		}
+		timeout, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("illegal %s extension of resource %s: %v", WaitTimeoutExtension, resource.ResourceKey(), err)
+		}
+		return timeout, nil
+	}
+	if defaultTimeout <= 0 {
+		return DefaultWaitTimeout, nil
+	}
+	return defaultTimeout, nil
+}
+
+// lastObserved returns the last observed status of all watched resources in the table
+func lastObserved(table *printers.Table) string {
+	if len(table.Rows) == 0 {
+		return "nothing observed"
+	}
+	ids := make([]string, 0, len(table.Rows))
+	for id := range table.Rows {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	observed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		row := table.Rows[id]
+		observed = append(observed, fmt.Sprintf("%s %s: %s", row.Kind, row.Name, row.Detail))
+	}
+	return strings.Join(observed, "; ")
+}
@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/printers/convertor"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// fakeWatchRuntime sends the events in watch and keeps the watcher open
+type fakeWatchRuntime struct {
+	runtime.Runtime
+	unsupported bool
+	events      []k8swatch.Event
+}
+
+func (f *fakeWatchRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	if f.unsupported {
+		return nil
+	}
+	ch := make(chan k8swatch.Event, len(f.events))
+	for _, e := range f.events {
+		ch <- e
+	}
+	watchers := runtime.NewWatchers()
+	watchers.Insert(engine.BuildIDForKubernetes(f.events[0].Object.(*unstructured.Unstructured)), ch)
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+func newTerraformEvent(t k8swatch.EventType, detail string, ready bool) k8swatch.Event {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(convertor.TerraformGroupVersion.String())
+	obj.SetKind("random_password")
+	obj.SetName("password")
+	_ = unstructured.SetNestedField(obj.Object, detail, "status", "detail")
+	_ = unstructured.SetNestedField(obj.Object, ready, "status", "ready")
+	return k8swatch.Event{Type: t, Object: obj}
+}
+
+func TestResourceNode_WaitForReady(t *testing.T) {
+	tests := []struct {
+		name         string
+		runtime      *fakeWatchRuntime
+		extensions   map[string]interface{}
+		wantCode     status.Code
+		wantContains string
+	}{
+		{
+			name: "ready",
+			runtime: &fakeWatchRuntime{events: []k8swatch.Event{
+				newTerraformEvent(k8swatch.Added, "status: Pending", false),
+				newTerraformEvent(k8swatch.Modified, "status: Running", true),
+			}},
+		},
+		{
+			name: "timeout",
+			runtime: &fakeWatchRuntime{events: []k8swatch.Event{
+				newTerraformEvent(k8swatch.Added, "status: Pending", false),
+			}},
+			extensions:   map[string]interface{}{WaitTimeoutExtension: "100ms"},
+			wantCode:     status.DeadlineExceeded,
+			wantContains: "random_password password: status: Pending",
+		},
+		{
+			name:    "unsupported",
+			runtime: &fakeWatchRuntime{unsupported: true},
+		},
+		{
+			name:       "illegal_timeout",
+			runtime:    &fakeWatchRuntime{unsupported: true},
+			extensions: map[string]interface{}{WaitTimeoutExtension: 100},
+			wantCode:   status.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rn, _ := NewResourceNode("password", &models.Resource{
+				ID:         "password",
+				Type:       runtime.Terraform,
+				Extensions: tt.extensions,
+			}, opsmodels.Create)
+			s := rn.waitForReady(&opsmodels.Operation{
+				RuntimeMap:  map[models.Type]runtime.Runtime{runtime.Terraform: tt.runtime},
+				WaitTimeout: time.Second,
+			})
+			if tt.wantCode == "" {
+				assert.Nil(t, s)
+				return
+			}
+			assert.Equal(t, tt.wantCode, s.Code())
+			assert.Contains(t, s.Message(), tt.wantContains)
+		})
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	resource := &models.Resource{ID: "id"}
+	timeout, err := waitTimeout(resource, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultWaitTimeout, timeout)
+
+	timeout, err = waitTimeout(resource, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, timeout)
+
+	resource.Extensions = map[string]interface{}{WaitTimeoutExtension: "10m"}
+	timeout, err = waitTimeout(resource, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, timeout)
+
+	resource.Extensions = map[string]interface{}{WaitTimeoutExtension: "ten minutes"}
+	_, err = waitTimeout(resource, time.Minute)
+	assert.Error(t, err)
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"kusionstack.io/kusion/pkg/engine/models"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
@@ -21,6 +24,7 @@ type ResourceNode struct {
 	*baseNode
 	Action   opsmodels.ActionType
 	resource *models.Resource
+	retries  int
 }
 
 var _ ExecutableNode = (*ResourceNode)(nil)
@@ -34,16 +38,23 @@ func (rn *ResourceNode) PreExecute(o *opsmodels.Operation) status.Status {
 	var replaced reflect.Value
 	var s status.Status
 
+	// record attributes which will be resolved from secret references before they are replaced
+	if o.OperationType == opsmodels.ApplyPreview || o.OperationType == opsmodels.Apply {
+		rn.resource.SensitivePaths = mergePaths(rn.resource.SensitivePaths, secretRefPaths(rn.resource.Attributes, ""))
+	}
+
 	switch o.OperationType {
 	case opsmodels.ApplyPreview:
 		// first time apply. Do not replace implicit dependency ref
 		if len(o.PriorStateResourceIndex) == 0 {
 			_, replaced, s = ReplaceSecretRef(value, o.SecretStores)
 		} else {
+			rn.markImplicitRefSensitivePaths(o)
 			_, replaced, s = ReplaceRef(value, o.CtxResourceIndex, ImplicitReplaceFun, o.SecretStores, vals.ParseSecretRef)
 		}
 	case opsmodels.Apply:
 		// replace secret ref and implicit ref
+		rn.markImplicitRefSensitivePaths(o)
 		_, replaced, s = ReplaceRef(value, o.CtxResourceIndex, ImplicitReplaceFun, o.SecretStores, vals.ParseSecretRef)
 	default:
 		return nil
@@ -72,7 +83,7 @@ func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
 	}
 
 	// compute action type
-	dryRunResource, s := rn.computeActionType(operation, planedResource, priorResource, liveResource)
+	dryRunResource, diffLiveResource, s := rn.computeActionType(operation, planedResource, priorResource, liveResource)
 	if status.IsErr(s) {
 		return s
 	}
@@ -85,11 +96,17 @@ func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
 		if e := operation.RefreshResourceIndex(key, dryRunResource, rn.Action); e != nil {
 			return status.NewErrorStatus(e)
 		}
-		updateChangeOrder(operation, rn, liveResource, dryRunResource)
+		updateChangeOrder(operation, rn, diffLiveResource.Redacted(), dryRunResource.Redacted())
 	case opsmodels.Apply, opsmodels.Destroy:
 		if s = rn.applyResource(operation, priorResource, planedResource, liveResource); status.IsErr(s) {
 			return s
 		}
+		// dependents start after this resource is ready
+		if operation.OperationType == opsmodels.Apply && operation.Wait && rn.Action != opsmodels.Delete {
+			if s = rn.waitForReady(operation); status.IsErr(s) {
+				return s
+			}
+		}
 	default:
 		return status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
 	}
@@ -98,14 +115,16 @@ func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
 }
 
 // computeActionType compute ActionType of current resource node according to  planResource, priorResource and liveResource.
-// dryRunResource is a middle result during the process of computing ActionType. We will use it to perform live diff latter
+// dryRunResource is a middle result during the process of computing ActionType. We will use it to perform live diff latter.
+// Ignored fields are removed from dryRunResource and a copy of liveResource, which is returned as diffLiveResource, so that
+// liveResource itself is unchanged
 func (rn *ResourceNode) computeActionType(
 	operation *opsmodels.Operation,
 	planedResource *models.Resource,
 	priorResource *models.Resource,
 	liveResource *models.Resource,
-) (*models.Resource, status.Status) {
-	dryRunResource := planedResource
+) (dryRunResource *models.Resource, diffLiveResource *models.Resource, s status.Status) {
+	dryRunResource, diffLiveResource = planedResource, liveResource
 	switch operation.OperationType {
 	case opsmodels.Destroy, opsmodels.DestroyPreview:
 		rn.Action = opsmodels.Delete
@@ -116,25 +135,43 @@ func (rn *ResourceNode) computeActionType(
 			rn.Action = opsmodels.Create
 		} else {
 			// Dry run to fetch predictable resource
-			dryRunResp := operation.RuntimeMap[rn.resource.Type].Apply(context.Background(), &runtime.ApplyRequest{
-				PriorResource: priorResource,
-				PlanResource:  planedResource,
-				Stack:         operation.Stack,
-				DryRun:        true,
+			var dryRunResp *runtime.ApplyResponse
+			s := rn.callRuntime(operation, "dry run", false, func(ctx context.Context) status.Status {
+				dryRunResp = operation.RuntimeMap[rn.resource.Type].Apply(ctx, &runtime.ApplyRequest{
+					PriorResource:   priorResource,
+					PlanResource:    planedResource,
+					Stack:           operation.Stack,
+					DryRun:          true,
+					ServerSideApply: operation.ServerSideApply,
+					ForceConflicts:  operation.ForceConflicts,
+				})
+				return dryRunResp.Status
 			})
-			if status.IsErr(dryRunResp.Status) {
-				return nil, dryRunResp.Status
+			if status.IsErr(s) {
+				return nil, nil, s
 			}
 			dryRunResource = dryRunResp.Resource
-			// Ignore differences of target fields
-			for _, field := range operation.IgnoreFields {
+			markSensitive(planedResource, liveResource, dryRunResource)
+			// Ignore differences of target fields, including fields ignored by the resource itself
+			fields, err := ignoreChanges(rn.resource)
+			if err != nil {
+				return nil, nil, status.NewErrorStatusWithCode(status.IllegalManifest, err)
+			}
+			fields = append(append([]string{}, operation.IgnoreFields...), fields...)
+			if len(fields) > 0 && liveResource != nil {
+				diffLiveResource = liveResource.DeepCopy()
+			}
+			for _, field := range fields {
 				splits := strings.Split(field, ".")
-				removeNestedField(liveResource.Attributes, splits...)
+				if diffLiveResource != nil {
+					removeNestedField(diffLiveResource.Attributes, splits...)
+				}
 				removeNestedField(dryRunResource.Attributes, splits...)
 			}
-			report, err := diff.ToReport(liveResource, dryRunResource)
+			// compare redacted resources, so that sensitive values hashed in the prior State are equal to the plaintext
+			report, err := diff.ToReport(diffLiveResource.Redacted(), dryRunResource.Redacted())
 			if err != nil {
-				return nil, status.NewErrorStatus(err)
+				return nil, nil, status.NewErrorStatus(err)
 			}
 			if len(report.Diffs) == 0 {
 				rn.Action = opsmodels.UnChange
@@ -143,9 +180,19 @@ func (rn *ResourceNode) computeActionType(
 			}
 		}
 	default:
-		return nil, status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
+		return nil, nil, status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
+	}
+	if rn.Action == opsmodels.Delete {
+		prevent, err := PreventDestroy(rn.resource)
+		if err != nil {
+			return nil, nil, status.NewErrorStatusWithCode(status.IllegalManifest, err)
+		}
+		if prevent {
+			return nil, nil, status.NewErrorStatusWithCode(status.InvalidArgument, fmt.Errorf(
+				"resource %s can not be deleted since its %s extension is true", rn.resource.ResourceKey(), PreventDestroyExtension))
+		}
 	}
-	return dryRunResource, nil
+	return dryRunResource, diffLiveResource, nil
 }
 
 func (rn *ResourceNode) initThreeWayDiffData(operation *opsmodels.Operation) (*models.Resource, *models.Resource, *models.Resource, status.Status) {
@@ -168,13 +215,15 @@ func (rn *ResourceNode) initThreeWayDiffData(operation *opsmodels.Operation) (*m
 		Stack:         operation.Stack,
 	}
 	resourceType := rn.resource.Type
-	response := operation.RuntimeMap[resourceType].Read(context.Background(), readRequest)
-	liveResource := response.Resource
-	s := response.Status
+	var response *runtime.ReadResponse
+	s := rn.callRuntime(operation, "read", false, func(ctx context.Context) status.Status {
+		response = operation.RuntimeMap[resourceType].Read(ctx, readRequest)
+		return response.Status
+	})
 	if status.IsErr(s) {
 		return nil, nil, nil, s
 	}
-	return planedResource, priorResource, liveResource, nil
+	return planedResource, priorResource, response.Resource, nil
 }
 
 func removeNestedField(obj interface{}, fields ...string) {
@@ -197,8 +246,8 @@ func removeNestedField(obj interface{}, fields ...string) {
 }
 
 func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, prior, planed, live *models.Resource) status.Status {
-	log.Infof("operation:%v, prior:%v, plan:%v, live:%v", rn.Action, jsonutil.Marshal2String(prior),
-		jsonutil.Marshal2String(planed), jsonutil.Marshal2String(live))
+	log.Infof("operation:%v, prior:%v, plan:%v, live:%v", rn.Action, jsonutil.Marshal2String(prior.Redacted()),
+		jsonutil.Marshal2String(planed.Redacted()), jsonutil.Marshal2String(live.Redacted()))
 
 	var res *models.Resource
 	var s status.Status
@@ -207,13 +256,27 @@ func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, prior, pla
 	rt := operation.RuntimeMap[resourceType]
 	switch rn.Action {
 	case opsmodels.Create, opsmodels.Update:
-		response := rt.Apply(context.Background(), &runtime.ApplyRequest{PriorResource: prior, PlanResource: planed, Stack: operation.Stack})
+		var response *runtime.ApplyResponse
+		s = rn.callRuntime(operation, "apply", true, func(ctx context.Context) status.Status {
+			response = rt.Apply(ctx, &runtime.ApplyRequest{
+				PriorResource:   prior,
+				PlanResource:    planed,
+				Stack:           operation.Stack,
+				ServerSideApply: operation.ServerSideApply,
+				ForceConflicts:  operation.ForceConflicts,
+			})
+			return response.Status
+		})
+		if status.IsErr(s) {
+			return s
+		}
 		res = response.Resource
-		s = response.Status
-		log.Debugf("apply resource:%s, response: %v", planed.ID, jsonutil.Marshal2String(response))
+		markSensitive(planed, res)
+		log.Debugf("apply resource:%s, response: %v", planed.ID, jsonutil.Marshal2String(res.Redacted()))
 	case opsmodels.Delete:
-		response := rt.Delete(context.Background(), &runtime.DeleteRequest{Resource: prior, Stack: operation.Stack})
-		s = response.Status
+		s = rn.callRuntime(operation, "delete", true, func(ctx context.Context) status.Status {
+			return rt.Delete(ctx, &runtime.DeleteRequest{Resource: prior, Stack: operation.Stack}).Status
+		})
 		if s != nil {
 			log.Debugf("delete resource:%s, resource: %v", planed.ID, s.String())
 		}
@@ -221,10 +284,17 @@ func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, prior, pla
 		log.Infof("planed resource and live resource are equal")
 		// auto import resources exist in spec and live cluster but no recorded in kusion_state.json
 		if prior == nil {
-			response := rt.Import(context.Background(), &runtime.ImportRequest{PlanResource: planed})
-			s = response.Status
+			var response *runtime.ImportResponse
+			s = rn.callRuntime(operation, "import", true, func(ctx context.Context) status.Status {
+				response = rt.Import(ctx, &runtime.ImportRequest{PlanResource: planed, Stack: operation.Stack})
+				return response.Status
+			})
 			log.Debugf("import resource:%s, resource:%v", planed.ID, jsonutil.Marshal2String(s))
+			if status.IsErr(s) {
+				return s
+			}
 			res = response.Resource
+			markSensitive(planed, res)
 		} else {
 			res = prior
 		}
@@ -237,6 +307,13 @@ func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, prior, pla
 	if e := operation.RefreshResourceIndex(key, res, rn.Action); e != nil {
 		return status.NewErrorStatus(e)
 	}
+	// sensitive values of the prior State are hashed, so dependents resolve references to the plaintext live resource
+	if rn.Action == opsmodels.UnChange && prior != nil && live != nil {
+		operation.RefreshCtxResourceIndex(key, live)
+	}
+	if rn.Action == opsmodels.Create || rn.Action == opsmodels.Update {
+		operation.RecordApplied(key, rn.Action)
+	}
 	if e := operation.UpdateState(operation.StateResourceIndex); e != nil {
 		return status.NewErrorStatus(e)
 	}
@@ -258,6 +335,90 @@ func NewResourceNode(key string, state *models.Resource, action opsmodels.Action
 	return &ResourceNode{baseNode: node, Action: action, resource: state}, nil
 }
 
+// markSensitive marks the same attributes of resources returned by runtimes as sensitive as the planed resource
+func markSensitive(planed *models.Resource, resources ...*models.Resource) {
+	if planed == nil {
+		return
+	}
+	for _, r := range resources {
+		if r != nil {
+			r.SensitivePaths = mergePaths(r.SensitivePaths, planed.SensitivePaths)
+		}
+	}
+}
+
+// secretRefPaths returns JSON pointers of attributes whose values are secret references
+func secretRefPaths(v interface{}, path string) []string {
+	var paths []string
+	switch value := v.(type) {
+	case string:
+		if _, ok := vals.IsSecured(value); ok {
+			paths = append(paths, path)
+		}
+	case []interface{}:
+		for i, item := range value {
+			paths = append(paths, secretRefPaths(item, path+models.JSONPointer(strconv.Itoa(i)))...)
+		}
+	case map[string]interface{}:
+		for key, item := range value {
+			paths = append(paths, secretRefPaths(item, path+models.JSONPointer(key))...)
+		}
+	}
+	return paths
+}
+
+// markImplicitRefSensitivePaths records attributes which will be resolved from sensitive attributes of other resources by
+// implicit references before they are replaced, since the plaintext values are copied into this resource
+func (rn *ResourceNode) markImplicitRefSensitivePaths(o *opsmodels.Operation) {
+	o.Lock.Lock()
+	defer o.Lock.Unlock()
+	paths := implicitRefSensitivePaths(rn.resource.Attributes, "", o.CtxResourceIndex)
+	rn.resource.SensitivePaths = mergePaths(rn.resource.SensitivePaths, paths)
+}
+
+// implicitRefSensitivePaths returns JSON pointers of attributes whose implicit references refer to sensitive attributes,
+// or to attributes containing sensitive attributes, of resources in the resourceIndex
+func implicitRefSensitivePaths(v interface{}, path string, resourceIndex map[string]*models.Resource) []string {
+	var paths []string
+	switch value := v.(type) {
+	case string:
+		if !strings.HasPrefix(value, ImplicitRefPrefix) {
+			return nil
+		}
+		split := strings.Split(strings.TrimPrefix(value, ImplicitRefPrefix), ".")
+		referred := resourceIndex[split[0]]
+		if referred == nil {
+			return nil
+		}
+		source := models.JSONPointer(split[1:]...)
+		for _, sensitive := range referred.AllSensitivePaths() {
+			if source == sensitive || strings.HasPrefix(source, sensitive+"/") {
+				// the referred attribute is sensitive or in a sensitive attribute
+				paths = append(paths, path)
+			} else if strings.HasPrefix(sensitive, source+"/") {
+				// the referred attribute contains sensitive attributes
+				paths = append(paths, path+strings.TrimPrefix(sensitive, source))
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			paths = append(paths, implicitRefSensitivePaths(item, path+models.JSONPointer(strconv.Itoa(i)), resourceIndex)...)
+		}
+	case map[string]interface{}:
+		for key, item := range value {
+			paths = append(paths, implicitRefSensitivePaths(item, path+models.JSONPointer(key), resourceIndex)...)
+		}
+	}
+	return paths
+}
+
+func mergePaths(paths []string, others []string) []string {
+	if len(others) == 0 {
+		return paths
+	}
+	return sets.NewString(paths...).Insert(others...).List()
+}
+
 // save change steps in DAG walking order so that we can preview a full applying list
 func updateChangeOrder(ops *opsmodels.Operation, rn *ResourceNode, plan, live interface{}) {
 	defer ops.Lock.Unlock()
@@ -307,6 +468,11 @@ var ImplicitReplaceFun = func(resourceIndex map[string]*models.Resource, refPath
 			valueMap = valueMap.(map[string]interface{})[k]
 		}
 	}
+	// hashes in States must never be applied as values of other resources
+	if models.HasSensitiveHash(valueMap) {
+		msg := fmt.Sprintf("can't resolve the sensitive value in resource:%s by ref:%s since its plaintext is unknown", key, refPath)
+		return reflect.Value{}, status.NewErrorStatusWithMsg(status.IllegalManifest, msg)
+	}
 	return reflect.ValueOf(valueMap), nil
 }
 
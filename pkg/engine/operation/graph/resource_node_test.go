@@ -16,6 +16,7 @@ import (
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/engine/states/local"
 	"kusionstack.io/kusion/pkg/status"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
 	"kusionstack.io/kusion/third_party/terraform/dag"
 )
 
@@ -260,3 +261,143 @@ func Test_removeNestedField(t *testing.T) {
 		assert.Len(t, ports[0], 2)
 	})
 }
+
+func Test_secretRefPaths(t *testing.T) {
+	attributes := map[string]interface{}{
+		"password": "ref+vault://secret/db#/password",
+		"user":     "root",
+		"env": []interface{}{
+			map[string]interface{}{"name": "TOKEN", "value": "ref+vault://secret/token"},
+		},
+		"annotations": map[string]interface{}{"kusion.io/key": "ref+vault://secret/key"},
+	}
+	paths := secretRefPaths(attributes, "")
+	assert.ElementsMatch(t, []string{"/password", "/env/0/value", "/annotations/kusion.io~1key"}, paths)
+	assert.Equal(t, []string{"/a", "/b", "/c"}, mergePaths([]string{"/b", "/a"}, []string{"/c", "/a"}))
+}
+
+func TestImplicitReplaceFun(t *testing.T) {
+	plain := &models.Resource{
+		ID:             "secret",
+		Attributes:     map[string]interface{}{"data": map[string]interface{}{"password": "123456"}},
+		SensitivePaths: []string{"/data/password"},
+	}
+	tests := []struct {
+		name     string
+		resource *models.Resource
+		want     interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "plaintext",
+			resource: plain,
+			want:     "123456",
+		},
+		{
+			name:     "hash",
+			resource: plain.Redacted(),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, s := ImplicitReplaceFun(map[string]*models.Resource{"secret": tt.resource}, "secret.data.password")
+			if tt.wantErr {
+				assert.Equal(t, status.IllegalManifest, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, tt.want, v.Interface())
+		})
+	}
+}
+
+func TestResourceNode_Execute_SensitiveImplicitRef(t *testing.T) {
+	const password = "czNjcmV0"
+	secret := &models.Resource{
+		ID:   "v1:Secret:ns:db",
+		Type: runtime.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"data":       map[string]interface{}{"password": password},
+		},
+	}
+	newApp := func() *models.Resource {
+		return &models.Resource{
+			ID:   "apps/v1:Deployment:ns:app",
+			Type: runtime.Kubernetes,
+			Attributes: map[string]interface{}{
+				"env": []interface{}{
+					map[string]interface{}{"name": "PASSWORD", "value": ImplicitRefPrefix + "v1:Secret:ns:db.data.password"},
+				},
+				"data": ImplicitRefPrefix + "v1:Secret:ns:db.data",
+			},
+			DependsOn: []string{secret.ID},
+		}
+	}
+	newOperation := func(operationType opsmodels.OperationType) *opsmodels.Operation {
+		return &opsmodels.Operation{
+			OperationType:           operationType,
+			StateStorage:            local.NewFileSystemState(),
+			CtxResourceIndex:        map[string]*models.Resource{secret.ID: secret},
+			PriorStateResourceIndex: map[string]*models.Resource{secret.ID: secret},
+			StateResourceIndex:      map[string]*models.Resource{secret.ID: secret},
+			ChangeOrder:             &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			ResultState:             states.NewState(),
+			Lock:                    &sync.Mutex{},
+			RuntimeMap:              map[models.Type]runtime.Runtime{runtime.Kubernetes: &kubernetes.KubernetesRuntime{}},
+		}
+	}
+
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(&kubernetes.KubernetesRuntime{}), "Apply",
+		func(k *kubernetes.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+			return &runtime.ApplyResponse{Resource: request.PlanResource.DeepCopy()}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&kubernetes.KubernetesRuntime{}), "Read",
+		func(k *kubernetes.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			return &runtime.ReadResponse{}
+		})
+	var saved *states.State
+	monkey.PatchInstanceMethod(reflect.TypeOf(local.NewFileSystemState()), "Apply",
+		func(f *local.FileSystemState, state *states.State) error {
+			saved = state
+			return nil
+		})
+
+	// the ChangeStep of preview only contains hashes of values referred to the Secret
+	preview := newOperation(opsmodels.ApplyPreview)
+	app := newApp()
+	rn := &ResourceNode{baseNode: &baseNode{ID: app.ID}, resource: app}
+	assert.Nil(t, rn.Execute(preview))
+	assert.ElementsMatch(t, []string{"/env/0/value", "/data/password"}, app.SensitivePaths)
+	step := jsonutil.Marshal2String(preview.ChangeOrder.ChangeSteps[app.ID])
+	assert.NotContains(t, step, password)
+	assert.Contains(t, step, models.SensitiveValuePrefix)
+
+	// the saved State only contains hashes of values referred to the Secret
+	apply := newOperation(opsmodels.Apply)
+	app = newApp()
+	rn = &ResourceNode{baseNode: &baseNode{ID: app.ID}, resource: app}
+	assert.Nil(t, rn.Execute(apply))
+	state := jsonutil.Marshal2String(saved)
+	assert.NotContains(t, state, password)
+	assert.Contains(t, state, models.SensitiveValuePrefix)
+	// dependents still resolve plaintext values
+	assert.Equal(t, password, apply.CtxResourceIndex[app.ID].Attributes["data"].(map[string]interface{})["password"])
+}
+
+func Test_implicitRefSensitivePaths(t *testing.T) {
+	index := map[string]*models.Resource{
+		"a": {ID: "a", Attributes: map[string]interface{}{}, SensitivePaths: []string{"/spec/token"}},
+	}
+	attributes := map[string]interface{}{
+		"token": ImplicitRefPrefix + "a.spec.token",
+		"inner": ImplicitRefPrefix + "a.spec.token.value",
+		"spec":  ImplicitRefPrefix + "a.spec",
+		"name":  ImplicitRefPrefix + "a.metadata.name",
+		"other": ImplicitRefPrefix + "b.spec.token",
+	}
+	assert.ElementsMatch(t, []string{"/token", "/inner", "/spec/token"}, implicitRefSensitivePaths(attributes, "", index))
+}
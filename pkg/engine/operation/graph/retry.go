@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const (
+	// TimeoutExtension is the extension of resources to specify the timeout of each runtime call, e.g. "30s"
+	TimeoutExtension = "timeout"
+
+	// RetriesExtension is the extension of resources to specify the max times of retrying a runtime call which fails
+	// with a transient error
+	RetriesExtension = "retries"
+
+	// maxRetryDelay is the max delay between two attempts of a runtime call
+	maxRetryDelay = 30 * time.Second
+)
+
+// retryDelay is the delay before the first retry, which is doubled before each later retry
+var retryDelay = time.Second
+
+// retryable returns whether the runtime call failed with the status may succeed if retried. Only calls of runtimes
+// implementing runtime.Retrier are retried
+func retryable(rt runtime.Runtime, s status.Status, mutation bool) bool {
+	retrier, ok := rt.(runtime.Retrier)
+	return ok && retrier.Retryable(s, mutation)
+}
+
+// retryPolicy returns the timeout of each runtime call and the max retries of the resource, which are specified by
+// extensions of the resource or default to the settings of the operation. A zero timeout means no timeout
+func retryPolicy(resource *models.Resource, operation *opsmodels.Operation) (time.Duration, int, error) {
+	timeout, retries := operation.Timeout, operation.Retries
+	if v, ok := resource.Extensions[TimeoutExtension]; ok && v != nil {
+		s, ok := v.(string)
+		if !ok {
+			return 0, 0, fmt.Errorf("the %s extension of resource %s should be a duration string", TimeoutExtension, resource.ResourceKey())
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, 0, fmt.Errorf("illegal %s extension of resource %s: %v", TimeoutExtension, resource.ResourceKey(), err)
+		}
+		timeout = d
+	}
+	if v, ok := resource.Extensions[RetriesExtension]; ok && v != nil {
+		switch n := v.(type) {
+		case int:
+			retries = n
+		case int64:
+			retries = int(n)
+		case float64:
+			if n != float64(int(n)) {
+				return 0, 0, fmt.Errorf("the %s extension of resource %s should be an integer", RetriesExtension, resource.ResourceKey())
+			}
+			retries = int(n)
+		default:
+			return 0, 0, fmt.Errorf("the %s extension of resource %s should be an integer", RetriesExtension, resource.ResourceKey())
+		}
+	}
+	if timeout < 0 || retries < 0 {
+		return 0, 0, fmt.Errorf("the timeout and retries of resource %s can not be negative", resource.ResourceKey())
+	}
+	return timeout, retries, nil
+}
+
+// callRuntime calls the runtime with the timeout of each attempt, and retries the call with exponential backoff if it
+// fails with a transient error and the runtime of the resource is a runtime.Retrier, which decides by mutation whether
+// the call may have changed resources. Calls are passed the runtime context of the operation, so cancelling the
+// operation only stops retries and leaves the call in progress to finish. The name of the call is only used in logs
+func (rn *ResourceNode) callRuntime(
+	operation *opsmodels.Operation,
+	name string,
+	mutation bool,
+	call func(ctx context.Context) status.Status,
+) status.Status {
+	timeout, retries, err := retryPolicy(rn.resource, operation)
+	if err != nil {
+		return status.NewErrorStatusWithCode(status.InvalidArgument, err)
+	}
+
+	parent, opCtx := operation.RuntimeContext(), operation.Context()
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := parent, context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(parent, timeout)
+		}
+		s := call(ctx)
+		if status.IsErr(s) && timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s = status.NewErrorStatusWithCode(status.DeadlineExceeded, fmt.Errorf("%s %s timed out after %s: %s",
+				name, rn.resource.ResourceKey(), timeout, s.Message()))
+		}
+		cancel()
+
+		if !status.IsErr(s) || attempt >= retries || opCtx.Err() != nil || !retryable(operation.RuntimeMap[rn.resource.Type], s, mutation) {
+			return s
+		}
+		log.Warnf("%s %s failed with a transient error, retry %d/%d in %s: %s", name, rn.resource.ResourceKey(),
+			attempt+1, retries, delay, s.Message())
+		select {
+		case <-opCtx.Done():
+			return s
+		case <-time.After(delay):
+		}
+		rn.retries++
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// Retries returns the times runtime calls of the resource are retried
+func (rn *ResourceNode) Retries() int {
+	return rn.retries
+}
@@ -0,0 +1,212 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// fakeRetrierRuntime retries failures of API servers like the Kubernetes runtime
+type fakeRetrierRuntime struct {
+	runtime.Runtime
+}
+
+func (f *fakeRetrierRuntime) Retryable(s status.Status, _ bool) bool {
+	return runtime.IsTransient(s, runtime.TransientPatterns)
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		rt   runtime.Runtime
+		s    status.Status
+		want bool
+	}{
+		{
+			name: "unavailable",
+			rt:   &fakeRetrierRuntime{},
+			s:    status.NewErrorStatusWithCode(status.Unavailable, errors.New("plugin exited")),
+			want: true,
+		},
+		{
+			name: "throttled",
+			rt:   &fakeRetrierRuntime{},
+			s:    status.NewErrorStatus(errors.New("the server has received too many requests and has asked us to try again later")),
+			want: true,
+		},
+		{
+			name: "webhook timeout",
+			rt:   &fakeRetrierRuntime{},
+			s:    status.NewErrorStatus(errors.New(`failed calling webhook "validate.example.com": context deadline exceeded`)),
+			want: true,
+		},
+		{
+			name: "illegal manifest",
+			rt:   &fakeRetrierRuntime{},
+			s:    status.NewErrorStatusWithCode(status.IllegalManifest, errors.New("invalid timeout")),
+			want: false,
+		},
+		{
+			name: "invalid",
+			rt:   &fakeRetrierRuntime{},
+			s:    status.NewErrorStatus(errors.New(`Deployment.apps "nginx" is invalid: spec.replicas: Invalid value: -1`)),
+			want: false,
+		},
+		{
+			name: "user command",
+			rt:   &fakeDryRunRuntime{},
+			s:    status.NewErrorStatus(errors.New("apply command of exec:migrate failed: exit status 1: connection timeout")),
+			want: false,
+		},
+		{
+			name: "no runtime",
+			s:    status.NewErrorStatusWithCode(status.Unavailable, errors.New("plugin exited")),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryable(tt.rt, tt.s, false))
+		})
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	operation := &opsmodels.Operation{Timeout: time.Minute, Retries: 2}
+	tests := []struct {
+		name        string
+		extensions  map[string]interface{}
+		wantTimeout time.Duration
+		wantRetries int
+		wantErr     bool
+	}{
+		{
+			name:        "default",
+			wantTimeout: time.Minute,
+			wantRetries: 2,
+		},
+		{
+			name:        "extensions",
+			extensions:  map[string]interface{}{TimeoutExtension: "30s", RetriesExtension: float64(5)},
+			wantTimeout: 30 * time.Second,
+			wantRetries: 5,
+		},
+		{
+			name:       "illegal retries",
+			extensions: map[string]interface{}{RetriesExtension: "5"},
+			wantErr:    true,
+		},
+		{
+			name:       "negative timeout",
+			extensions: map[string]interface{}{TimeoutExtension: "-1s"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &models.Resource{ID: "id", Extensions: tt.extensions}
+			timeout, retries, err := retryPolicy(resource, operation)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTimeout, timeout)
+			assert.Equal(t, tt.wantRetries, retries)
+		})
+	}
+}
+
+func TestResourceNode_callRuntime(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+	throttled := status.NewErrorStatus(errors.New("Too many requests"))
+	runtimes := map[models.Type]runtime.Runtime{"": &fakeRetrierRuntime{}}
+
+	t.Run("retry until success", func(t *testing.T) {
+		rn, _ := NewResourceNode("id", &models.Resource{ID: "id"}, opsmodels.Update)
+		calls := 0
+		s := rn.callRuntime(&opsmodels.Operation{Retries: 3, RuntimeMap: runtimes}, "apply", true, func(ctx context.Context) status.Status {
+			if calls++; calls < 3 {
+				return throttled
+			}
+			return nil
+		})
+		assert.Nil(t, s)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 2, rn.Retries())
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		rn, _ := NewResourceNode("id", &models.Resource{ID: "id"}, opsmodels.Update)
+		calls := 0
+		s := rn.callRuntime(&opsmodels.Operation{Retries: 2, RuntimeMap: runtimes}, "apply", true, func(ctx context.Context) status.Status {
+			calls++
+			return throttled
+		})
+		assert.Equal(t, throttled, s)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 2, rn.Retries())
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		rn, _ := NewResourceNode("id", &models.Resource{ID: "id"}, opsmodels.Update)
+		calls := 0
+		s := rn.callRuntime(&opsmodels.Operation{Retries: 2, RuntimeMap: runtimes}, "apply", true, func(ctx context.Context) status.Status {
+			calls++
+			return status.NewErrorStatusWithCode(status.IllegalManifest, errors.New("illegal"))
+		})
+		assert.Equal(t, status.IllegalManifest, s.Code())
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, 0, rn.Retries())
+	})
+
+	t.Run("runtime not retrier", func(t *testing.T) {
+		rn, _ := NewResourceNode("id", &models.Resource{ID: "id"}, opsmodels.Update)
+		calls := 0
+		s := rn.callRuntime(&opsmodels.Operation{Retries: 2}, "apply", true, func(ctx context.Context) status.Status {
+			calls++
+			return throttled
+		})
+		assert.Equal(t, throttled, s)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("cancelled operation", func(t *testing.T) {
+		rn, _ := NewResourceNode("id", &models.Resource{ID: "id"}, opsmodels.Update)
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		s := rn.callRuntime(&opsmodels.Operation{Ctx: ctx, Retries: 2, RuntimeMap: runtimes}, "apply", true, func(ctx context.Context) status.Status {
+			calls++
+			// the call in progress is not cancelled with the operation
+			cancel()
+			assert.NoError(t, ctx.Err())
+			return throttled
+		})
+		assert.Equal(t, throttled, s)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		rn, _ := NewResourceNode("id", &models.Resource{
+			ID:         "id",
+			Extensions: map[string]interface{}{TimeoutExtension: "10ms", RetriesExtension: 1},
+		}, opsmodels.Update)
+		calls := 0
+		s := rn.callRuntime(&opsmodels.Operation{RuntimeMap: runtimes}, "apply", true, func(ctx context.Context) status.Status {
+			calls++
+			<-ctx.Done()
+			return status.NewErrorStatus(ctx.Err())
+		})
+		assert.Equal(t, status.DeadlineExceeded, s.Code())
+		assert.Equal(t, 2, calls)
+	})
+}
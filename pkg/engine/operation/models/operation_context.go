@@ -1,8 +1,10 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/jinzhu/copier"
 
@@ -18,6 +20,10 @@ import (
 
 // Operation is the base model for all operations
 type Operation struct {
+	// Ctx is the context of this operation. Resources not started are skipped after it is cancelled, while runtime calls
+	// in progress run to completion, since they are passed RuntimeContext() which is not cancelled with Ctx
+	Ctx context.Context
+
 	// OperationType represents the OperationType of this operation
 	OperationType OperationType
 
@@ -36,6 +42,47 @@ type Operation struct {
 	// IgnoreFields will be ignored in preview stage
 	IgnoreFields []string
 
+	// ServerSideApply means resources are applied by the server-side apply of runtimes
+	ServerSideApply bool
+
+	// ForceConflicts means to take the ownership of fields managed by others in the server-side apply
+	ForceConflicts bool
+
+	// Wait means each resource blocks its dependents until it is ready after applied
+	Wait bool
+
+	// WaitTimeout is the default timeout of waiting for a resource to be ready, which can be overridden by the
+	// waitTimeout extension of the resource
+	WaitTimeout time.Duration
+
+	// RollbackOnFailure means resources created or updated by this operation are reverted if the operation fails
+	RollbackOnFailure bool
+
+	// Targets are IDs of resources to process, and "*" and "?" in them match any characters. Other resources in the
+	// operation are unchanged
+	Targets []string
+
+	// TargetWithDeps means resources that targeted resources wait for are processed too
+	TargetWithDeps bool
+
+	// Parallelism is the max number of resources processed at once, and 0 means no limit
+	Parallelism int
+
+	// ContinueOnError means resources not depending on failed resources are still processed after any resource fails,
+	// otherwise no more resources are processed after the first failure
+	ContinueOnError bool
+
+	// Timeout is the default timeout of each runtime call of a resource, which can be overridden by the timeout extension
+	// of the resource. 0 means no timeout
+	Timeout time.Duration
+
+	// Retries is the default max times of retrying a runtime call which fails with a transient error, which can be
+	// overridden by the retries extension of the resource. Only calls of runtimes implementing runtime.Retrier are retried
+	Retries int
+
+	// Applied contains resources created or updated by this operation in the order of completion
+	Applied []AppliedResource
+
 	// ChangeOrder is resources' change order during this operation
 	ChangeOrder *ChangeOrder
 
@@ -59,10 +106,17 @@ type Operation struct {
 	SecretStores *vals.SecretStores
 }
 
+// AppliedResource is a resource created or updated by an operation
+type AppliedResource struct {
+	ID     string
+	Action ActionType
+}
+
 type Message struct {
 	ResourceID string   // ResourceNode.ID()
 	OpResult   OpResult // Success/Failed/Skip
 	OpErr      error    // Operate error detail
+	Retries    int      // Times runtime calls of the resource are retried
 }
 
 type Request struct {
@@ -81,8 +135,40 @@ const (
 	Success OpResult = "Success"
 	Failed  OpResult = "Failed"
 	Skip    OpResult = "Skip"
+
+	// RolledBack and RollbackFailed are results of reverting resources applied by a failed operation
+	RolledBack     OpResult = "RolledBack"
+	RollbackFailed OpResult = "RollbackFailed"
 )
 
+// Context returns the context of this operation, which defaults to context.Background()
+func (o *Operation) Context() context.Context {
+	if o.Ctx == nil {
+		return context.Background()
+	}
+	return o.Ctx
+}
+
+// RuntimeContext returns the context passed to runtime calls of this operation. It carries values of Context() but is
+// not cancelled with it, so that an interrupted operation doesn't kill runtime calls in progress, e.g. a terraform apply
+// which may leave resources not recorded in any State
+func (o *Operation) RuntimeContext() context.Context {
+	return detachedContext{parent: o.Context()}
+}
+
+// detachedContext carries values of its parent but is never cancelled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
 // RefreshResourceIndex refresh resources in CtxResourceIndex & StateResourceIndex
 func (o *Operation) RefreshResourceIndex(resourceKey string, resource *models.Resource, actionType ActionType) error {
 	o.Lock.Lock()
@@ -101,13 +187,23 @@ func (o *Operation) RefreshResourceIndex(resourceKey string, resource *models.Re
 	return nil
 }
 
+// RefreshCtxResourceIndex refresh the resource in CtxResourceIndex only, which must keep plaintext values of sensitive
+// attributes for resolving references of other resources
+func (o *Operation) RefreshCtxResourceIndex(resourceKey string, resource *models.Resource) {
+	o.Lock.Lock()
+	defer o.Lock.Unlock()
+	o.CtxResourceIndex[resourceKey] = resource
+}
+
+// RecordApplied records the resource created or updated by this operation
+func (o *Operation) RecordApplied(resourceKey string, actionType ActionType) {
+	o.Lock.Lock()
+	defer o.Lock.Unlock()
+	o.Applied = append(o.Applied, AppliedResource{ID: resourceKey, Action: actionType})
+}
+
 func (o *Operation) InitStates(request *Request) (*states.State, *states.State) {
-	query := &states.StateQuery{
-		Tenant:  request.Tenant,
-		Stack:   request.Stack.Name,
-		Project: request.Project.Name,
-		Cluster: request.Cluster,
-	}
+	query := NewStateQuery(request)
 	latestState, err := o.StateStorage.GetLatestState(query)
 	util.CheckNotError(err, fmt.Sprintf("get the latest State failed with query: %v", jsonutil.Marshal2PrettyString(query)))
 	if latestState == nil {
@@ -126,11 +222,43 @@ func (o *Operation) InitStates(request *Request) (*states.State, *states.State)
 	return latestState, resultState
 }
 
+// NewStateQuery returns the StateQuery of the State operated by this request
+func NewStateQuery(request *Request) *states.StateQuery {
+	return &states.StateQuery{
+		Tenant:  request.Tenant,
+		Stack:   request.Stack.Name,
+		Project: request.Project.Name,
+		Cluster: request.Cluster,
+	}
+}
+
+// LockState acquires the lock of the State operated by this request on behalf of the named operation.
+// The returned LockInfo is needed to release this lock
+func (o *Operation) LockState(request *Request, operation string) (*states.LockInfo, error) {
+	info := states.NewLockInfo(NewStateQuery(request), operation)
+	if err := o.StateStorage.Lock(info); err != nil {
+		return nil, fmt.Errorf("lock State failed. %w", err)
+	}
+	return info, nil
+}
+
+// UnlockState releases the lock acquired by LockState. Failures are only logged since the operation has already finished
+func (o *Operation) UnlockState(info *states.LockInfo) {
+	if info == nil {
+		return
+	}
+	if err := o.StateStorage.Unlock(info); err != nil {
+		log.Errorf("unlock State failed, run `kusion state force-unlock %s` to release the lock manually. %v", info.ID, err)
+	}
+}
+
+// UpdateState saves resources in resourceIndex as the next State. The next State is built on a copy of ResultState,
+// which is only replaced after the State is saved, so that a failed write doesn't skip serials of later writes
 func (o *Operation) UpdateState(resourceIndex map[string]*models.Resource) error {
 	o.Lock.Lock()
 	defer o.Lock.Unlock()
 
-	state := o.ResultState
+	state := *o.ResultState
 	state.Serial += 1
 	state.Resources = nil
 
@@ -140,14 +268,16 @@ func (o *Operation) UpdateState(resourceIndex map[string]*models.Resource) error
 		if resourceIndex[key] == nil {
 			continue
 		}
-		res = append(res, *resourceIndex[key])
+		// sensitive values are stored as hashes
+		res = append(res, *resourceIndex[key].Redacted())
 	}
 
 	state.Resources = res
-	err := o.StateStorage.Apply(state)
+	err := o.StateStorage.Apply(&state)
 	if err != nil {
 		return fmt.Errorf("apply State failed. %w", err)
 	}
+	*o.ResultState = state
 	log.Infof("update State:%v success", state.ID)
 	return nil
 }
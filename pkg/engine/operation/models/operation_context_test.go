@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+)
+
+// fakeStateStorage records serials of applied States, and fails writes until failures are used up
+type fakeStateStorage struct {
+	states.StateStorage
+	failures int
+	serials  []uint64
+}
+
+func (f *fakeStateStorage) Apply(state *states.State) error {
+	f.serials = append(f.serials, state.Serial)
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("serial conflict")
+	}
+	return nil
+}
+
+func TestOperation_UpdateState(t *testing.T) {
+	storage := &fakeStateStorage{failures: 1}
+	o := &Operation{
+		StateStorage: storage,
+		Lock:         &sync.Mutex{},
+		ResultState:  &states.State{Serial: 3},
+	}
+	index := map[string]*models.Resource{"a": {ID: "a"}}
+
+	assert.Error(t, o.UpdateState(index))
+	assert.Equal(t, uint64(3), o.ResultState.Serial)
+	assert.Nil(t, o.ResultState.Resources)
+
+	// the failed write doesn't change the serial of the next write
+	assert.NoError(t, o.UpdateState(index))
+	assert.NoError(t, o.UpdateState(index))
+	assert.Equal(t, []uint64{4, 4, 5}, storage.serials)
+	assert.Equal(t, uint64(5), o.ResultState.Serial)
+	assert.Len(t, o.ResultState.Resources, 1)
+}
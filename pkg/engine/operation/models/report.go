@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/pterm/pterm"
+)
+
+// ExecutionReport records results, retries and errors of resources from messages of an operation. Resources in the
+// ChangeOrder without results are skipped, since resources they depend on failed or the operation stopped
+type ExecutionReport struct {
+	order   *ChangeOrder
+	lock    sync.Mutex
+	results map[string]OpResult
+	retries map[string]int
+	errs    map[string]error
+}
+
+func NewExecutionReport(order *ChangeOrder) *ExecutionReport {
+	return &ExecutionReport{order: order, results: map[string]OpResult{}, retries: map[string]int{}, errs: map[string]error{}}
+}
+
+// Record the result in the message, and messages without Success or Failed results are ignored
+func (r *ExecutionReport) Record(msg Message) {
+	if msg.OpResult != Success && msg.OpResult != Failed {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.results[msg.ResourceID] = msg.OpResult
+	if msg.Retries > 0 {
+		r.retries[msg.ResourceID] = msg.Retries
+	}
+	if msg.OpErr != nil {
+		r.errs[msg.ResourceID] = msg.OpErr
+	}
+}
+
+// Retried returns whether runtime calls of any resource are retried
+func (r *ExecutionReport) Retried() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.retries) != 0
+}
+
+// Result returns the result of the resource, which is Skip if no result is recorded
+func (r *ExecutionReport) Result(id string) OpResult {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if result, ok := r.results[id]; ok {
+		return result
+	}
+	return Skip
+}
+
+// Summary returns the summary line of this report
+func (r *ExecutionReport) Summary() string {
+	counts := map[OpResult]int{}
+	for _, key := range r.order.StepKeys {
+		counts[r.Result(key)]++
+	}
+	return fmt.Sprintf("Resources: %d succeeded, %d failed, %d skipped.", counts[Success], counts[Failed], counts[Skip])
+}
+
+// Print the result and retries of each resource, final errors of failed resources and the summary line
+func (r *ExecutionReport) Print(writer io.Writer) {
+	tableData := pterm.TableData{{"ID", "Action", "Result", "Retries"}}
+	for _, step := range r.order.Values() {
+		result := r.Result(step.ID)
+		var styled string
+		switch result {
+		case Success:
+			styled = pterm.Green(result)
+		case Failed:
+			styled = pterm.Red(result)
+		default:
+			styled = pterm.Gray(result)
+		}
+		r.lock.Lock()
+		retries := r.retries[step.ID]
+		r.lock.Unlock()
+		tableData = append(tableData, []string{step.ID, step.Action.String(), styled, fmt.Sprint(retries)})
+	}
+
+	pterm.DefaultTable.WithHasHeader().
+		WithHeaderStyle(&pterm.ThemeDefault.TableHeaderStyle).
+		WithLeftAlignment(true).
+		WithSeparator("  ").
+		WithData(tableData).
+		WithWriter(writer).
+		Render()
+	for _, key := range r.order.StepKeys {
+		r.lock.Lock()
+		err := r.errs[key]
+		r.lock.Unlock()
+		if err != nil {
+			pterm.Error.WithWriter(writer).Printfln("%s: %v", key, err)
+		}
+	}
+	pterm.Fprintln(writer, r.Summary())
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutionReport(t *testing.T) {
+	order := &ChangeOrder{
+		StepKeys: []string{"a", "b", "c"},
+		ChangeSteps: map[string]*ChangeStep{
+			"a": NewChangeStep("a", Create, nil, nil),
+			"b": NewChangeStep("b", Update, nil, nil),
+			"c": NewChangeStep("c", Create, nil, nil),
+		},
+	}
+	report := NewExecutionReport(order)
+	report.Record(Message{ResourceID: "a"})
+	report.Record(Message{ResourceID: "a", OpResult: Success, Retries: 2})
+	report.Record(Message{ResourceID: "b"})
+	report.Record(Message{ResourceID: "b", OpResult: Failed, OpErr: errors.New("apply failed")})
+
+	assert.Equal(t, Success, report.Result("a"))
+	assert.Equal(t, Failed, report.Result("b"))
+	assert.Equal(t, Skip, report.Result("c"))
+	assert.Equal(t, "Resources: 1 succeeded, 1 failed, 1 skipped.", report.Summary())
+	assert.True(t, report.Retried())
+
+	var buf bytes.Buffer
+	report.Print(&buf)
+	assert.Contains(t, buf.String(), "Retries")
+	assert.Contains(t, buf.String(), "b: apply failed")
+	assert.Contains(t, buf.String(), report.Summary())
+}
@@ -29,10 +29,20 @@ func (d *DeleteResourceParser) Parse(g *dag.AcyclicGraph) (s status.Status) {
 	}
 
 	manifestGraphMap := make(map[string]interface{})
+	// resources in the spec replacing resources with specified IDs
+	replacements := make(map[string][]*graph.ResourceNode)
 	for _, v := range g.Vertices() {
 		if rn, ok := v.(*graph.ResourceNode); ok {
 			id := rn.Hashcode().(string)
 			manifestGraphMap[id] = v
+
+			replaced, err := graph.Replaces(rn.State())
+			if err != nil {
+				return status.NewErrorStatusWithCode(status.IllegalManifest, err)
+			}
+			for _, replacedID := range replaced {
+				replacements[replacedID] = append(replacements[replacedID], rn)
+			}
 		}
 	}
 
@@ -66,6 +76,17 @@ func (d *DeleteResourceParser) Parse(g *dag.AcyclicGraph) (s status.Status) {
 			}
 			g.Add(rn)
 			g.Connect(dag.BasicEdge(root, rn))
+
+			// delete this node after nodes replacing it are created
+			createBeforeDestroy, err := graph.CreateBeforeDestroy(resource)
+			if err != nil {
+				return status.NewErrorStatusWithCode(status.IllegalManifest, err)
+			}
+			if createBeforeDestroy {
+				for _, replacement := range replacements[key] {
+					g.Connect(dag.BasicEdge(replacement, rn))
+				}
+			}
 		}
 
 		// compute implicit and explicate dependencies
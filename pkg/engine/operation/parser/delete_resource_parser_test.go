@@ -4,8 +4,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
 	"kusionstack.io/kusion/third_party/terraform/dag"
 )
 
@@ -76,3 +81,65 @@ vsecurity
 vswitch
   vpc
 `
+
+func TestDeleteResourceParser_CreateBeforeDestroy(t *testing.T) {
+	const oldID = "apps/v1:Deployment:default:nginx-v1"
+	const newID = "apps/v1:Deployment:default:nginx-v2"
+	replaces := map[string]interface{}{graph.ReplacesExtension: []interface{}{oldID}}
+	tests := []struct {
+		name                  string
+		extensions            map[string]interface{}
+		replacementExtensions map[string]interface{}
+		want                  bool
+		wantErr               bool
+	}{
+		{
+			name:                  "create before destroy",
+			extensions:            map[string]interface{}{graph.CreateBeforeDestroyExtension: true},
+			replacementExtensions: replaces,
+			want:                  true,
+		},
+		{
+			name:                  "destroy without waiting",
+			replacementExtensions: replaces,
+			want:                  false,
+		},
+		{
+			name:       "not replaced",
+			extensions: map[string]interface{}{graph.CreateBeforeDestroyExtension: true},
+			want:       false,
+		},
+		{
+			name:                  "illegal replaces",
+			extensions:            map[string]interface{}{graph.CreateBeforeDestroyExtension: true},
+			replacementExtensions: map[string]interface{}{graph.ReplacesExtension: oldID},
+			wantErr:               true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ag := &dag.AcyclicGraph{}
+			ag.Add(&graph.RootNode{})
+			replacement, _ := graph.NewResourceNode(newID, &models.Resource{
+				ID:         newID,
+				Type:       runtime.Kubernetes,
+				Extensions: tt.replacementExtensions,
+			}, opsmodels.Update)
+			ag.Add(replacement)
+			ag.Connect(dag.BasicEdge(&graph.RootNode{}, replacement))
+
+			deleteResourceParser := NewDeleteResourceParser(models.Resources{
+				{ID: oldID, Type: runtime.Kubernetes, Extensions: tt.extensions},
+			})
+			s := deleteResourceParser.Parse(ag)
+			if tt.wantErr {
+				assert.Equal(t, status.IllegalManifest, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+
+			deleted, _ := graph.NewResourceNode(oldID, &models.Resource{ID: oldID}, opsmodels.Delete)
+			assert.Equal(t, tt.want, ag.HasEdge(dag.BasicEdge(GetVertex(ag, replacement), GetVertex(ag, deleted))))
+		})
+	}
+}
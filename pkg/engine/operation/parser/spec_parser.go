@@ -6,6 +6,7 @@ import (
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation/graph"
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
 	"kusionstack.io/kusion/pkg/status"
 	"kusionstack.io/kusion/pkg/util"
 	"kusionstack.io/kusion/pkg/util/json"
@@ -35,6 +36,8 @@ func (m *SpecParser) Parse(g *dag.AcyclicGraph) (s status.Status) {
 	util.CheckNotError(err, "get dag root error")
 	util.CheckNotNil(root, fmt.Sprintf("No root in this DAG:%s", json.Marshal2String(g)))
 	resourceIndex := sp.Resources.Index()
+	// dependencies between Kubernetes resources which are inferred from their manifests
+	kubernetesDependencies := kubernetes.ImplicitDependencies(sp.Resources)
 	for key, resource := range resourceIndex {
 		rn, s := graph.NewResourceNode(key, resourceIndex[key], opsmodels.Update)
 		if status.IsErr(s) {
@@ -56,6 +59,10 @@ func (m *SpecParser) Parse(g *dag.AcyclicGraph) (s status.Status) {
 		if status.IsErr(s) {
 			return s
 		}
+		if len(kubernetesDependencies[key]) > 0 {
+			refNodeKeys = Deduplicate(append(refNodeKeys, kubernetesDependencies[key]...))
+			resource.DependsOn = refNodeKeys
+		}
 
 		// linkRefNodes
 		s = LinkRefNodes(g, refNodeKeys, resourceIndex, rn, opsmodels.Update, nil)
@@ -4,8 +4,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/third_party/terraform/dag"
 )
 
@@ -65,3 +68,56 @@ pony
 root
   jack
 `
+
+func TestSpecParser_ParseKubernetesDependencies(t *testing.T) {
+	mf := &models.Spec{Resources: []models.Resource{
+		{
+			ID:   "apps/v1:Deployment:default:nginx",
+			Type: runtime.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "nginx", "namespace": "default"},
+				"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+					"volumes": []interface{}{map[string]interface{}{"name": "c", "configMap": map[string]interface{}{"name": "nginx"}}},
+				}}},
+			},
+		},
+		{
+			ID:   "v1:ConfigMap:default:nginx",
+			Type: runtime.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "nginx", "namespace": "default"},
+			},
+		},
+		{
+			ID:   "v1:Namespace:default",
+			Type: runtime.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]interface{}{"name": "default"},
+			},
+		},
+	}}
+
+	ag := &dag.AcyclicGraph{}
+	ag.Add(&graph.RootNode{})
+	_ = NewSpecParser(mf).Parse(ag)
+
+	expected := `
+apps/v1:Deployment:default:nginx
+root
+  v1:Namespace:default
+v1:ConfigMap:default:nginx
+  apps/v1:Deployment:default:nginx
+v1:Namespace:default
+  v1:ConfigMap:default:nginx
+`
+	if actual := strings.TrimSpace(ag.String()); actual != strings.TrimSpace(expected) {
+		t.Errorf("wrong result\ngot:\n%s\n\nwant:\n%s", actual, strings.TrimSpace(expected))
+	}
+	assert.Equal(t, []string{"v1:ConfigMap:default:nginx", "v1:Namespace:default"}, mf.Resources[0].DependsOn)
+}
@@ -82,6 +82,10 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 	if status.IsErr(s) {
 		return nil, s
 	}
+	ctxResourceIndex := map[string]*models.Resource{}
+	if s = targetGraph(ag, &o, request.Spec, priorStateResourceIndex, ctxResourceIndex); status.IsErr(s) {
+		return nil, s
+	}
 	// copy priorStateResourceIndex into a new map
 	stateResourceIndex := map[string]*models.Resource{}
 	for k, v := range priorStateResourceIndex {
@@ -93,13 +97,19 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 
 	previewOperation := &PreviewOperation{
 		Operation: opsmodels.Operation{
+			Ctx:                     o.Ctx,
 			OperationType:           o.OperationType,
 			StateStorage:            o.StateStorage,
-			CtxResourceIndex:        map[string]*models.Resource{},
+			CtxResourceIndex:        ctxResourceIndex,
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      stateResourceIndex,
 			IgnoreFields:            o.IgnoreFields,
+			ServerSideApply:         o.ServerSideApply,
+			ForceConflicts:          o.ForceConflicts,
 			ChangeOrder:             o.ChangeOrder,
+			Parallelism:             o.Parallelism,
+			Timeout:                 o.Timeout,
+			Retries:                 o.Retries,
 			RuntimeMap:              o.RuntimeMap,
 			Stack:                   o.Stack,
 			ResultState:             resultState,
@@ -108,10 +118,14 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 		},
 	}
 
-	w := &dag.Walker{Callback: previewOperation.previewWalkFun}
+	w := &dag.Walker{Callback: newWalkLimiter(&previewOperation.Operation).wrap(previewOperation.previewWalkFun)}
 	w.Update(ag)
 	// Wait
-	if diags := w.Wait(); diags.HasErrors() {
+	diags := w.Wait()
+	if err := previewOperation.Context().Err(); err != nil {
+		return nil, status.NewErrorStatusWithCode(status.Canceled, fmt.Errorf("preview is cancelled: %v", err))
+	}
+	if diags.HasErrors() {
 		return nil, status.NewErrorStatus(diags.Err())
 	}
 
@@ -0,0 +1,99 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// RollbackReport is the outcome of reverting resources applied by a failed apply
+type RollbackReport struct {
+	// Reverted contains IDs of resources reverted successfully
+	Reverted []string
+
+	// Failed contains errors of resources failed to revert, keyed by resource IDs
+	Failed map[string]error
+}
+
+// Summary returns the summary line of this report
+func (r *RollbackReport) Summary() string {
+	return fmt.Sprintf("Rollback complete! Resources: %d reverted, %d failed to revert.", len(r.Reverted), len(r.Failed))
+}
+
+// rollback reverts resources created or updated by this operation in the reverse order of completion, which is also a
+// reverse order of the DAG. Created resources are deleted and updated resources are applied with their prior States.
+// The State is updated after each resource is reverted. Runtime calls of the rollback are not bound to the context of
+// the operation, so that resources are still reverted after the operation is cancelled
+func (ao *ApplyOperation) rollback() *RollbackReport {
+	o := &ao.Operation
+	report := &RollbackReport{Failed: map[string]error{}}
+	for i := len(o.Applied) - 1; i >= 0; i-- {
+		applied := o.Applied[i]
+		err := ao.revert(applied)
+		if err == nil {
+			err = o.UpdateState(o.StateResourceIndex)
+		}
+		if err != nil {
+			log.Errorf("rollback %s %s failed: %v", applied.Action, applied.ID, err)
+			report.Failed[applied.ID] = err
+			o.MsgCh <- opsmodels.Message{ResourceID: applied.ID, OpResult: opsmodels.RollbackFailed, OpErr: err}
+			continue
+		}
+		log.Infof("rollback %s %s success", applied.Action, applied.ID)
+		report.Reverted = append(report.Reverted, applied.ID)
+		o.MsgCh <- opsmodels.Message{ResourceID: applied.ID, OpResult: opsmodels.RolledBack}
+	}
+	return report
+}
+
+// revert the resource to its prior State
+func (ao *ApplyOperation) revert(applied opsmodels.AppliedResource) error {
+	o := &ao.Operation
+	current := o.StateResourceIndex[applied.ID]
+	if current == nil {
+		return errors.New("resource is not found in the State")
+	}
+	rt := o.RuntimeMap[current.Type]
+
+	switch applied.Action {
+	case opsmodels.Create:
+		response := rt.Delete(context.Background(), &runtime.DeleteRequest{Resource: current, Stack: o.Stack})
+		if status.IsErr(response.Status) {
+			return errors.New(response.Status.String())
+		}
+		return o.RefreshResourceIndex(applied.ID, nil, opsmodels.Delete)
+	case opsmodels.Update:
+		prior := o.PriorStateResourceIndex[applied.ID]
+		if prior == nil {
+			return errors.New("resource is not recorded in the prior State")
+		}
+		if hasSensitiveValues(prior) {
+			return errors.New("sensitive values of the resource are not recorded in the prior State")
+		}
+		response := rt.Apply(context.Background(), &runtime.ApplyRequest{
+			PriorResource:   current,
+			PlanResource:    prior,
+			Stack:           o.Stack,
+			ServerSideApply: o.ServerSideApply,
+			ForceConflicts:  o.ForceConflicts,
+		})
+		if status.IsErr(response.Status) {
+			return errors.New(response.Status.String())
+		}
+		return o.RefreshResourceIndex(applied.ID, response.Resource, opsmodels.Update)
+	default:
+		return fmt.Errorf("unsupported action: %s", applied.Action)
+	}
+}
+
+// hasSensitiveValues returns whether the resource has sensitive values, which are stored as hashes in the State and
+// can't be applied
+func hasSensitiveValues(resource *models.Resource) bool {
+	return len(resource.AllSensitivePaths()) > 0
+}
@@ -0,0 +1,129 @@
+//go:build !arm64
+// +build !arm64
+
+package operation
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	runtimeinit "kusionstack.io/kusion/pkg/engine/runtime/init"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// fakeRollbackRuntime keeps live resources in memory, and fails to apply the resource named by fail
+type fakeRollbackRuntime struct {
+	lock sync.Mutex
+	live map[string]*models.Resource
+	fail string
+}
+
+func (f *fakeRollbackRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if request.PlanResource.ID == f.fail {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(errors.New("apply failed"))}
+	}
+	if !request.DryRun {
+		f.live[request.PlanResource.ID] = request.PlanResource.DeepCopy()
+	}
+	return &runtime.ApplyResponse{Resource: request.PlanResource}
+}
+
+func (f *fakeRollbackRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	resource := request.PlanResource
+	if resource == nil {
+		resource = request.PriorResource
+	}
+	return &runtime.ReadResponse{Resource: f.live[resource.ID]}
+}
+
+func (f *fakeRollbackRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	return &runtime.ImportResponse{Resource: request.PlanResource}
+}
+
+func (f *fakeRollbackRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.live, request.Resource.ID)
+	return &runtime.DeleteResponse{}
+}
+
+func (f *fakeRollbackRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	return nil
+}
+
+func newRollbackResource(id string, v float64, dependsOn ...string) models.Resource {
+	return models.Resource{ID: id, Type: runtime.Kubernetes, Attributes: map[string]interface{}{"v": v}, DependsOn: dependsOn}
+}
+
+func TestApplyOperation_RollbackOnFailure(t *testing.T) {
+	prior := newRollbackResource("c", 1)
+	rt := &fakeRollbackRuntime{live: map[string]*models.Resource{"c": prior.DeepCopy()}, fail: "b"}
+	defer monkey.UnpatchAll()
+	monkey.Patch(runtimeinit.Runtimes, func(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
+		return map[models.Type]runtime.Runtime{runtime.Kubernetes: rt}, nil
+	})
+
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev"}}
+	project := &projectstack.Project{ProjectConfiguration: projectstack.ProjectConfiguration{Name: "demo"}}
+	query := &states.StateQuery{Project: "demo", Stack: "dev"}
+	assert.NoError(t, storage.Apply(&states.State{Project: "demo", Stack: "dev", Serial: 1, Resources: models.Resources{prior}}))
+
+	msgs := map[string]opsmodels.OpResult{}
+	msgCh := make(chan opsmodels.Message)
+	done := make(chan struct{})
+	go func() {
+		for msg := range msgCh {
+			if msg.OpResult == opsmodels.RolledBack || msg.OpResult == opsmodels.RollbackFailed {
+				msgs[msg.ResourceID] = msg.OpResult
+			}
+		}
+		close(done)
+	}()
+
+	ao := &ApplyOperation{Operation: opsmodels.Operation{
+		StateStorage:      storage,
+		MsgCh:             msgCh,
+		Stack:             stack,
+		RollbackOnFailure: true,
+	}}
+	rsp, st := ao.Apply(&ApplyRequest{Request: opsmodels.Request{
+		Project: project,
+		Stack:   stack,
+		Spec: &models.Spec{Resources: models.Resources{
+			newRollbackResource("a", 1),
+			newRollbackResource("b", 1, "a", "c"),
+			newRollbackResource("c", 2),
+		}},
+	}})
+	<-done
+
+	assert.True(t, status.IsErr(st))
+	assert.ElementsMatch(t, []string{"a", "c"}, rsp.Rollback.Reverted)
+	assert.Empty(t, rsp.Rollback.Failed)
+	assert.Equal(t, map[string]opsmodels.OpResult{"a": opsmodels.RolledBack, "c": opsmodels.RolledBack}, msgs)
+
+	// created resources are deleted and updated resources are reverted
+	assert.Equal(t, map[string]*models.Resource{"c": &prior}, rt.live)
+	latest, err := storage.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.Len(t, latest.Resources, 1)
+	assert.Equal(t, "c", latest.Resources[0].ID)
+	assert.EqualValues(t, 1, latest.Resources[0].Attributes["v"])
+}
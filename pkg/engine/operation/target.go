@@ -0,0 +1,121 @@
+package operation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/third_party/terraform/dag"
+)
+
+// compileTarget turns a target into a regexp matching the whole resource ID. "*" matches any sequence of characters
+// including separators, and "?" matches any single character
+func compileTarget(target string) (*regexp.Regexp, error) {
+	expr := regexp.QuoteMeta(target)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.Compile("^" + expr + "$")
+}
+
+// pruneGraph removes all resource nodes not selected by targets from the graph, and returns IDs of remaining resources.
+// With withDeps, nodes that selected nodes wait for are kept too, which are dependencies of resources to be applied and
+// dependents of resources to be deleted. Edges through removed nodes are kept, so the order of remaining nodes is unchanged
+func pruneGraph(g *dag.AcyclicGraph, targets []string, withDeps bool) ([]string, status.Status) {
+	selected := make(dag.Set)
+	for _, target := range targets {
+		re, err := compileTarget(target)
+		if err != nil {
+			return nil, status.NewErrorStatusWithCode(status.InvalidArgument, fmt.Errorf("illegal target %s: %v", target, err))
+		}
+		matched := false
+		for _, v := range g.Vertices() {
+			if rn, ok := v.(*graph.ResourceNode); ok && re.MatchString(rn.Hashcode().(string)) {
+				selected.Add(v)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, status.NewErrorStatusWithMsg(status.InvalidArgument, fmt.Sprintf("target %s matches no resource", target))
+		}
+	}
+
+	if withDeps {
+		for _, v := range selected.List() {
+			deps, err := g.Descendents(v)
+			if err != nil {
+				return nil, status.NewErrorStatus(err)
+			}
+			for _, dep := range deps {
+				selected.Add(dep)
+			}
+		}
+	}
+
+	var ids []string
+	for _, v := range g.Vertices() {
+		rn, ok := v.(*graph.ResourceNode)
+		if !ok {
+			continue
+		}
+		if selected.Include(v) {
+			ids = append(ids, rn.Hashcode().(string))
+			continue
+		}
+		for _, source := range g.UpEdges(v) {
+			for _, target := range g.DownEdges(v) {
+				g.Connect(dag.BasicEdge(source, target))
+			}
+		}
+		g.Remove(v)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// targetGraph prunes the graph when targets are specified in the operation, and warns that only a part of resources
+// are processed. Resources out of targets are unchanged, and their prior states are added into ctxResourceIndex so that
+// implicit references to them can still be resolved. Sensitive values hashed in prior states are restored from
+// resources in the spec, whose secret references are resolved again
+func targetGraph(g *dag.AcyclicGraph, o *opsmodels.Operation, spec *models.Spec, priorResourceIndex,
+	ctxResourceIndex map[string]*models.Resource,
+) status.Status {
+	if len(o.Targets) == 0 {
+		return nil
+	}
+	ids, s := pruneGraph(g, o.Targets, o.TargetWithDeps)
+	if status.IsErr(s) {
+		return s
+	}
+	log.Warnf("targets %v are specified, only resources %v are processed and other resources are unchanged", o.Targets, ids)
+
+	var specResourceIndex map[string]*models.Resource
+	if spec != nil {
+		specResourceIndex = spec.Resources.Index()
+	}
+	for k, v := range priorResourceIndex {
+		if v == nil || len(v.AllSensitivePaths()) == 0 || specResourceIndex[k] == nil {
+			ctxResourceIndex[k] = v
+			continue
+		}
+		plain := *specResourceIndex[k]
+		_, replaced, s := graph.ReplaceSecretRef(reflect.ValueOf(plain.Attributes), o.SecretStores)
+		if status.IsErr(s) {
+			// references to sensitive values of this resource fail later instead of resolving to hashes
+			log.Warnf("resolve secret references of resource %s failed: %s", k, s.Message())
+			ctxResourceIndex[k] = v
+			continue
+		}
+		if !replaced.IsZero() {
+			plain.Attributes = replaced.Interface().(map[string]interface{})
+		}
+		ctxResourceIndex[k] = v.WithSensitiveValuesFrom(&plain)
+	}
+	return nil
+}
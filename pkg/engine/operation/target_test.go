@@ -0,0 +1,116 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/third_party/terraform/dag"
+)
+
+const (
+	deployment = "apps/v1:Deployment:default:nginx"
+	configMap  = "v1:ConfigMap:default:nginx"
+	serviceA   = "v1:Service:default:nginx-a"
+	serviceB   = "v1:Service:default:nginx-b"
+)
+
+// newTargetGraph returns the graph where the deployment depends on the config map, and services depend on nothing
+func newTargetGraph(t *testing.T) (*dag.AcyclicGraph, map[string]dag.Vertex) {
+	g := &dag.AcyclicGraph{}
+	root := g.Add(&graph.RootNode{})
+	nodes := map[string]dag.Vertex{}
+	for _, id := range []string{deployment, configMap, serviceA, serviceB} {
+		rn, s := graph.NewResourceNode(id, nil, opsmodels.Update)
+		assert.Nil(t, s)
+		nodes[id] = g.Add(rn)
+	}
+	g.Connect(dag.BasicEdge(root, nodes[configMap]))
+	g.Connect(dag.BasicEdge(nodes[configMap], nodes[deployment]))
+	g.Connect(dag.BasicEdge(root, nodes[serviceA]))
+	g.Connect(dag.BasicEdge(root, nodes[serviceB]))
+	return g, nodes
+}
+
+func TestPruneGraph(t *testing.T) {
+	tests := []struct {
+		name     string
+		targets  []string
+		withDeps bool
+		want     []string
+		wantCode status.Code
+	}{
+		{
+			name:    "exact",
+			targets: []string{deployment},
+			want:    []string{deployment},
+		},
+		{
+			name:    "glob",
+			targets: []string{"v1:Service:*", "*:ConfigMap:default:ngin?"},
+			want:    []string{configMap, serviceA, serviceB},
+		},
+		{
+			name:     "with deps",
+			targets:  []string{deployment},
+			withDeps: true,
+			want:     []string{deployment, configMap},
+		},
+		{
+			name:     "unmatched",
+			targets:  []string{deployment, "v1:Secret:*"},
+			wantCode: status.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := newTargetGraph(t)
+			got, s := pruneGraph(g, tt.targets, tt.withDeps)
+			if tt.wantCode != "" {
+				if assert.True(t, status.IsErr(s)) {
+					assert.Equal(t, tt.wantCode, s.Code())
+				}
+				return
+			}
+			assert.Nil(t, s)
+			assert.ElementsMatch(t, tt.want, got)
+			// the root node is always kept
+			assert.Len(t, g.Vertices(), len(tt.want)+1)
+			assert.NoError(t, g.Validate())
+		})
+	}
+}
+
+func TestPruneGraph_KeepOrder(t *testing.T) {
+	g, nodes := newTargetGraph(t)
+	_, s := pruneGraph(g, []string{deployment}, false)
+	assert.Nil(t, s)
+
+	// the deployment waits for the root node through the pruned config map
+	assert.True(t, g.HasEdge(dag.BasicEdge(&graph.RootNode{}, nodes[deployment])))
+}
+
+func TestTargetGraph_SensitiveValues(t *testing.T) {
+	plain := &models.Resource{
+		ID:             configMap,
+		Type:           runtime.Kubernetes,
+		Attributes:     map[string]interface{}{"data": map[string]interface{}{"password": "123456"}},
+		SensitivePaths: []string{"/data/password"},
+	}
+	prior := plain.Redacted()
+	priorResourceIndex := map[string]*models.Resource{configMap: prior}
+	ctxResourceIndex := map[string]*models.Resource{}
+
+	g, _ := newTargetGraph(t)
+	o := &opsmodels.Operation{Targets: []string{serviceA}}
+	s := targetGraph(g, o, &models.Spec{Resources: models.Resources{*plain}}, priorResourceIndex, ctxResourceIndex)
+	assert.Nil(t, s)
+	// references to the untargeted config map resolve to the plaintext instead of the hash in the prior State
+	assert.Equal(t, plain.Attributes, ctxResourceIndex[configMap].Attributes)
+	assert.True(t, models.HasSensitiveHash(prior.Attributes))
+}
@@ -0,0 +1,76 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/third_party/terraform/dag"
+	"kusionstack.io/kusion/third_party/terraform/tfdiags"
+)
+
+// walkLimiter controls how resource nodes are executed during the walk of a DAG. dag.Walker executes every vertex as
+// soon as its dependencies are done, and walkLimiter bounds how many resource nodes are executed at once by the
+// parallelism. Without continueOnError, resource nodes are skipped after any resource node fails, which makes the walk
+// stop at the first failure. Resource nodes are skipped too after the context of the operation is cancelled
+type walkLimiter struct {
+	ctx             context.Context
+	sem             chan struct{}
+	continueOnError bool
+	failed          int32
+}
+
+func newWalkLimiter(o *opsmodels.Operation) *walkLimiter {
+	l := &walkLimiter{ctx: o.Context(), continueOnError: o.ContinueOnError}
+	if o.Parallelism > 0 {
+		l.sem = make(chan struct{}, o.Parallelism)
+	}
+	return l
+}
+
+// wrap the walk function of an operation. Skipped resource nodes return no error, so that their dependents are
+// skipped by the walk function too
+func (l *walkLimiter) wrap(fn dag.WalkFunc) dag.WalkFunc {
+	return func(v dag.Vertex) tfdiags.Diagnostics {
+		rn, ok := v.(*graph.ResourceNode)
+		if !ok {
+			return fn(v)
+		}
+
+		if l.sem != nil {
+			l.sem <- struct{}{}
+			defer func() { <-l.sem }()
+		}
+		// check after acquired, since other resource nodes may fail or the operation may be cancelled during the waiting
+		if err := l.ctx.Err(); err != nil {
+			log.Infof("skip %s since the operation is cancelled: %v", rn.Hashcode(), err)
+			return nil
+		}
+		if !l.continueOnError && atomic.LoadInt32(&l.failed) == 1 {
+			log.Infof("skip %s since the operation stops at the first failure", rn.Hashcode())
+			return nil
+		}
+
+		diags := fn(v)
+		if diags.HasErrors() {
+			atomic.StoreInt32(&l.failed, 1)
+		}
+		return diags
+	}
+}
+
+// cancelledStatus saves the State with resources done before the operation is cancelled, and returns the Canceled
+// status with errors of the walk
+func cancelledStatus(name string, o *opsmodels.Operation, diags tfdiags.Diagnostics) status.Status {
+	if o.StateStorage != nil && o.ResultState != nil {
+		if err := o.UpdateState(o.StateResourceIndex); err != nil {
+			diags = diags.Append(err)
+		}
+	}
+	diags = diags.Append(fmt.Errorf("%s is cancelled: %v", name, o.Context().Err()))
+	return status.NewErrorStatusWithCode(status.Canceled, diags.Err())
+}
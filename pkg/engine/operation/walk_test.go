@@ -0,0 +1,115 @@
+package operation
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/third_party/terraform/dag"
+	"kusionstack.io/kusion/third_party/terraform/tfdiags"
+)
+
+func TestWalkLimiter_Parallelism(t *testing.T) {
+	g := &dag.AcyclicGraph{}
+	root := g.Add(&graph.RootNode{})
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		rn, s := graph.NewResourceNode(id, nil, opsmodels.Update)
+		assert.Nil(t, s)
+		g.Connect(dag.BasicEdge(root, g.Add(rn)))
+	}
+
+	var running, max, executed int32
+	fn := func(v dag.Vertex) tfdiags.Diagnostics {
+		if _, ok := v.(*graph.ResourceNode); !ok {
+			return nil
+		}
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&executed, 1)
+		return nil
+	}
+
+	w := &dag.Walker{Callback: newWalkLimiter(&opsmodels.Operation{Parallelism: 2}).wrap(fn)}
+	w.Update(g)
+	assert.False(t, w.Wait().HasErrors())
+	assert.EqualValues(t, 6, executed)
+	assert.EqualValues(t, 2, max)
+}
+
+func TestWalkLimiter_ContinueOnError(t *testing.T) {
+	tests := []struct {
+		name            string
+		continueOnError bool
+		want            []string
+	}{
+		{
+			name: "fail fast",
+			want: []string{"failed", "slow"},
+		},
+		{
+			name:            "continue on error",
+			continueOnError: true,
+			want:            []string{"failed", "slow", "after-slow"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the failed node fails after the slow node starts, and the dependent of the slow node is ready after the failure
+			g := &dag.AcyclicGraph{}
+			root := g.Add(&graph.RootNode{})
+			nodes := map[string]dag.Vertex{}
+			for _, id := range []string{"failed", "after-failed", "slow", "after-slow"} {
+				rn, s := graph.NewResourceNode(id, nil, opsmodels.Update)
+				assert.Nil(t, s)
+				nodes[id] = g.Add(rn)
+			}
+			g.Connect(dag.BasicEdge(root, nodes["failed"]))
+			g.Connect(dag.BasicEdge(nodes["failed"], nodes["after-failed"]))
+			g.Connect(dag.BasicEdge(root, nodes["slow"]))
+			g.Connect(dag.BasicEdge(nodes["slow"], nodes["after-slow"]))
+
+			var lock sync.Mutex
+			var executed []string
+			started := make(chan struct{})
+			fn := func(v dag.Vertex) (diags tfdiags.Diagnostics) {
+				rn, ok := v.(*graph.ResourceNode)
+				if !ok {
+					return nil
+				}
+				id := rn.Hashcode().(string)
+				lock.Lock()
+				executed = append(executed, id)
+				lock.Unlock()
+				switch id {
+				case "failed":
+					<-started
+					return diags.Append(errors.New("apply failed"))
+				case "slow":
+					close(started)
+					time.Sleep(50 * time.Millisecond)
+				}
+				return nil
+			}
+
+			l := newWalkLimiter(&opsmodels.Operation{ContinueOnError: tt.continueOnError})
+			w := &dag.Walker{Callback: l.wrap(fn)}
+			w.Update(g)
+			assert.True(t, w.Wait().HasErrors())
+			// dependents of failed nodes are never executed
+			assert.ElementsMatch(t, tt.want, executed)
+		})
+	}
+}
@@ -51,11 +51,11 @@ func (wo *WatchOperation) Watch(req *WatchRequest) error {
 		res := &resources[i]
 		t := res.Type
 
-		// Save id first, might have TF resources
+		// Save id first, might have unsupported resources
 		ids[i] = res.ResourceKey()
 
-		// Get watchers, only support k8s resources
-		resp := runtimes[t].Watch(ctx, &runtime.WatchRequest{Resource: res})
+		// Get watchers
+		resp := runtimes[t].Watch(ctx, &runtime.WatchRequest{Resource: res, Stack: req.Stack})
 		if resp == nil {
 			log.Debug("unsupported resource type: %s", t)
 			continue
@@ -85,7 +85,7 @@ func (wo *WatchOperation) Watch(req *WatchRequest) error {
 	// Start go routine for each table
 	for _, id := range ids {
 		sw, ok := msgChs[id]
-		if !ok { // Terraform resource, skip
+		if !ok { // Unsupported resource, skip
 			continue
 		}
 		// New target table
@@ -143,7 +143,7 @@ func (wo *WatchOperation) Watch(req *WatchRequest) error {
 		}(id, sw.Watchers, table)
 	}
 
-	// No k8s resources
+	// No supported resources
 	if len(tables) == 0 {
 		wo.printTables(writer, ids, tables)
 		return nil
@@ -178,8 +178,8 @@ func (wo *WatchOperation) printTables(w *uilive.Writer, ids []string, tables map
 
 		table, ok := tables[id]
 		if !ok {
-			// Terraform resource, leave a hint
-			_, _ = fmt.Fprintln(w, "Skip monitoring Terraform resources")
+			// Unsupported resource, leave a hint
+			_, _ = fmt.Fprintln(w, "Skip monitoring unsupported resources")
 		} else {
 			// Print table
 			data := table.Print()
@@ -10,6 +10,7 @@ import (
 func init() {
 	registerConvertor(convertor.ToK8s)
 	registerConvertor(convertor.ToOAM)
+	registerConvertor(convertor.ToTerraform)
 }
 
 type Convertor func(o *unstructured.Unstructured) runtime.Object
@@ -0,0 +1,18 @@
+package convertor
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TerraformGroupVersion is the group version of unstructured objects which represent Terraform resources watched by
+// the Terraform runtime
+var TerraformGroupVersion = schema.GroupVersion{Group: "terraform.kusionstack.io", Version: "v1"}
+
+func ToTerraform(o *unstructured.Unstructured) runtime.Object {
+	if o.GroupVersionKind().GroupVersion() != TerraformGroupVersion {
+		return nil
+	}
+	return o
+}
@@ -11,6 +11,7 @@ var tg = printer.NewTableGenerator()
 func init() {
 	tg.With(printer.AddK8sHandlers)
 	tg.With(printer.AddOAMHandlers)
+	tg.With(printer.AddTerraformHandlers)
 }
 
 func Generate(obj runtime.Object) (string, bool) {
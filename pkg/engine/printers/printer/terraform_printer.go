@@ -0,0 +1,16 @@
+package printer
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func AddTerraformHandlers(h PrintHandler) {
+	h.TableHandler(printTerraformResource)
+}
+
+// printTerraformResource prints the readiness computed by the Terraform runtime, which is kept in the status
+func printTerraformResource(obj *unstructured.Unstructured) (string, bool) {
+	detail, _, _ := unstructured.NestedString(obj.Object, "status", "detail")
+	ready, _, _ := unstructured.NestedBool(obj.Object, "status", "ready")
+	return detail, ready
+}
@@ -0,0 +1,239 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var _ runtime.Runtime = &ExecRuntime{}
+
+const (
+	// InputHashAttribute is the attribute filled by the runtime, which is the hash of all declared attributes
+	InputHashAttribute = "inputHash"
+
+	// OutputAttribute is the attribute filled by the runtime, which is the stdout of the apply command
+	OutputAttribute = "output"
+)
+
+// script is the commands described by attributes of an Exec resource
+type script struct {
+	// Apply is the command to create or update the resource, which is required
+	Apply string `json:"apply"`
+
+	// Read is the command to check whether the resource exists, the resource is regarded as deleted if it fails
+	Read string `json:"read,omitempty"`
+
+	// Delete is the command to delete the resource
+	Delete string `json:"delete,omitempty"`
+
+	// Env contains environment variables of all commands, and values can be implicit references of other resources
+	Env map[string]interface{} `json:"env,omitempty"`
+
+	// Dir is the working directory of all commands, relative paths are relative to the stack directory
+	Dir string `json:"dir,omitempty"`
+}
+
+// ExecRuntime runs commands declared in attributes of Exec resources with "sh -c". The hash of declared attributes is
+// recorded in the State, and the apply command is not run again until any declared attribute changes
+type ExecRuntime struct{}
+
+func NewExecRuntime() (runtime.Runtime, error) {
+	return &ExecRuntime{}, nil
+}
+
+// Apply runs the apply command and captures its stdout. Dry runs return the prior resource if the input hash is
+// unchanged, so that the apply command is skipped
+func (e *ExecRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	plan := request.PlanResource
+	s, err := newScript(plan)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	hash, err := inputHash(plan)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+	}
+
+	if request.DryRun {
+		prior := request.PriorResource
+		if prior != nil && prior.Attributes[InputHashAttribute] == hash {
+			return &runtime.ApplyResponse{Resource: execResource(plan, hash, prior.Attributes[OutputAttribute])}
+		}
+		// the output is unknown until applied
+		return &runtime.ApplyResponse{Resource: execResource(plan, hash, nil)}
+	}
+
+	stdout, err := s.run(ctx, s.Apply, request.Stack)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(fmt.Errorf("apply command of %s failed: %v", plan.ResourceKey(), err))}
+	}
+	log.Infof("apply command of %s succeeded", plan.ResourceKey())
+	return &runtime.ApplyResponse{Resource: execResource(plan, hash, stdout)}
+}
+
+// Read returns the prior resource if the read command succeeds or is not declared, and returns nil if the resource has
+// not been applied or the read command fails. The read command runs with the env of the plan resource, since sensitive
+// values in the env of the prior resource are redacted
+func (e *ExecRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	prior := request.PriorResource
+	if prior == nil {
+		return &runtime.ReadResponse{}
+	}
+	s, err := newScript(prior)
+	if err != nil {
+		return &runtime.ReadResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	if plan := request.PlanResource; plan != nil {
+		planScript, err := newScript(plan)
+		if err != nil {
+			return &runtime.ReadResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+		}
+		s.Env = planScript.Env
+	}
+	if redacted := redactedEnv(s.Env); len(redacted) != 0 {
+		// the resource is being deleted, and the read command can't run without plaintext values
+		log.Infof("skip read command of %s since values of env %v are redacted", prior.ResourceKey(), redacted)
+		return &runtime.ReadResponse{Resource: prior.DeepCopy()}
+	}
+	if s.Read != "" {
+		if _, err = s.run(ctx, s.Read, request.Stack); err != nil {
+			log.Infof("read command of %s failed, regard it as deleted: %v", prior.ResourceKey(), err)
+			return &runtime.ReadResponse{}
+		}
+	}
+	return &runtime.ReadResponse{Resource: prior.DeepCopy()}
+}
+
+// Import is not supported, since there is nothing to adopt without running the apply command
+func (e *ExecRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	return &runtime.ImportResponse{Status: status.NewErrorStatusWithCode(status.Unimplemented,
+		fmt.Errorf("can not import Exec resource %s", request.PlanResource.ResourceKey()))}
+}
+
+// Delete runs the delete command if it is declared
+func (e *ExecRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	s, err := newScript(request.Resource)
+	if err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	if s.Delete == "" {
+		return &runtime.DeleteResponse{}
+	}
+	if redacted := redactedEnv(s.Env); len(redacted) != 0 {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf(
+			"can not run delete command of %s since values of env %v are redacted in the State, delete it manually and remove it from the State by `kusion state rm`",
+			request.Resource.ResourceKey(), redacted))}
+	}
+	if _, err = s.run(ctx, s.Delete, request.Stack); err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatus(
+			fmt.Errorf("delete command of %s failed: %v", request.Resource.ResourceKey(), err))}
+	}
+	return &runtime.DeleteResponse{}
+}
+
+// Watch is not supported, commands are done once they exit
+func (e *ExecRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	return nil
+}
+
+func newScript(resource *models.Resource) (*script, error) {
+	data, err := json.Marshal(resource.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	s := &script{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("illegal attributes of Exec resource %s: %v", resource.ResourceKey(), err)
+	}
+	if s.Apply == "" {
+		return nil, fmt.Errorf("the apply command is required in attributes of Exec resource %s", resource.ResourceKey())
+	}
+	return s, nil
+}
+
+// run the command with "sh -c" and returns its stdout without the trailing newline
+func (s *script) run(ctx context.Context, command string, stack *projectstack.Stack) (string, error) {
+	cmd := osexec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = s.Dir
+	if stack != nil && !filepath.IsAbs(s.Dir) {
+		cmd.Dir = filepath.Join(stack.GetPath(), s.Dir)
+	}
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%v", k, s.Env[k]))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// redactedEnv returns sorted names of env whose values are hashes of sensitive values
+func redactedEnv(env map[string]interface{}) []string {
+	var names []string
+	for k, v := range env {
+		if models.HasSensitiveHash(v) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// inputHash returns the hash of all declared attributes, which are all attributes except those filled by the runtime
+func inputHash(resource *models.Resource) (string, error) {
+	declared := make(map[string]interface{}, len(resource.Attributes))
+	for k, v := range resource.Attributes {
+		if k != InputHashAttribute && k != OutputAttribute {
+			declared[k] = v
+		}
+	}
+	// keys of maps are sorted by json.Marshal
+	data, err := json.Marshal(declared)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// execResource returns the resource with the input hash and the output filled
+func execResource(resource *models.Resource, hash string, output interface{}) *models.Resource {
+	out := resource.DeepCopy()
+	if out.Attributes == nil {
+		out.Attributes = map[string]interface{}{}
+	}
+	out.Attributes[InputHashAttribute] = hash
+	delete(out.Attributes, OutputAttribute)
+	if output != nil {
+		out.Attributes[OutputAttribute] = output
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func newExecResource(attributes map[string]interface{}) *models.Resource {
+	return &models.Resource{ID: "exec:migrate", Type: runtime.Exec, Attributes: attributes}
+}
+
+func TestExecRuntime(t *testing.T) {
+	e := &ExecRuntime{}
+	ctx := context.TODO()
+	stack := &projectstack.Stack{Path: t.TempDir()}
+	plan := newExecResource(map[string]interface{}{
+		"apply":  `echo "$VERSION" > migrated && echo "migrated to $VERSION"`,
+		"read":   "test -f migrated",
+		"delete": "rm migrated",
+		"env":    map[string]interface{}{"VERSION": "v1"},
+	})
+
+	var applied *models.Resource
+	t.Run("Apply", func(t *testing.T) {
+		response := e.Apply(ctx, &runtime.ApplyRequest{PlanResource: plan, Stack: stack})
+		assert.Nil(t, response.Status)
+		applied = response.Resource
+		assert.Equal(t, "migrated to v1", applied.Attributes[OutputAttribute])
+		assert.NotEmpty(t, applied.Attributes[InputHashAttribute])
+
+		data, err := os.ReadFile(filepath.Join(stack.Path, "migrated"))
+		assert.NoError(t, err)
+		assert.Equal(t, "v1\n", string(data))
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		// unchanged inputs keep the prior output
+		response := e.Apply(ctx, &runtime.ApplyRequest{PriorResource: applied, PlanResource: plan, Stack: stack, DryRun: true})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, applied, response.Resource)
+
+		changed := plan.DeepCopy()
+		changed.Attributes["env"] = map[string]interface{}{"VERSION": "v2"}
+		response = e.Apply(ctx, &runtime.ApplyRequest{PriorResource: applied, PlanResource: changed, Stack: stack, DryRun: true})
+		assert.Nil(t, response.Status)
+		assert.NotEqual(t, applied.Attributes[InputHashAttribute], response.Resource.Attributes[InputHashAttribute])
+		assert.NotContains(t, response.Resource.Attributes, OutputAttribute)
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		response := e.Read(ctx, &runtime.ReadRequest{PlanResource: plan, Stack: stack})
+		assert.Nil(t, response.Status)
+		assert.Nil(t, response.Resource)
+
+		response = e.Read(ctx, &runtime.ReadRequest{PlanResource: plan, PriorResource: applied, Stack: stack})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, applied, response.Resource)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		response := e.Delete(ctx, &runtime.DeleteRequest{Resource: applied, Stack: stack})
+		assert.Nil(t, response.Status)
+
+		// the read command fails after deleted
+		readResponse := e.Read(ctx, &runtime.ReadRequest{PriorResource: applied, Stack: stack})
+		assert.Nil(t, readResponse.Status)
+		assert.Nil(t, readResponse.Resource)
+
+		response = e.Delete(ctx, &runtime.DeleteRequest{Resource: applied, Stack: stack})
+		assert.True(t, status.IsErr(response.Status))
+	})
+}
+
+func TestExecRuntime_RedactedEnv(t *testing.T) {
+	e := &ExecRuntime{}
+	ctx := context.TODO()
+	stack := &projectstack.Stack{Path: t.TempDir()}
+	plan := newExecResource(map[string]interface{}{
+		"apply":  "true",
+		"read":   `test "$TOKEN" = secret`,
+		"delete": "true",
+		"env":    map[string]interface{}{"TOKEN": "secret"},
+	})
+	// sensitive values are stored as hashes in the State
+	prior := plan.DeepCopy()
+	prior.Attributes["env"] = map[string]interface{}{"TOKEN": models.SensitiveValuePrefix + "2bb80d53"}
+
+	// the read command runs with the env of the plan resource
+	response := e.Read(ctx, &runtime.ReadRequest{PlanResource: plan, PriorResource: prior, Stack: stack})
+	assert.Nil(t, response.Status)
+	assert.Equal(t, prior, response.Resource)
+
+	// the read command is skipped without the plan resource
+	response = e.Read(ctx, &runtime.ReadRequest{PriorResource: prior, Stack: stack})
+	assert.Nil(t, response.Status)
+	assert.Equal(t, prior, response.Resource)
+
+	deleteResponse := e.Delete(ctx, &runtime.DeleteRequest{Resource: prior, Stack: stack})
+	if assert.True(t, status.IsErr(deleteResponse.Status)) {
+		assert.Contains(t, deleteResponse.Status.Message(), "TOKEN")
+	}
+}
+
+func TestExecRuntime_ApplyFailed(t *testing.T) {
+	e := &ExecRuntime{}
+	stack := &projectstack.Stack{Path: t.TempDir()}
+
+	response := e.Apply(context.TODO(), &runtime.ApplyRequest{
+		PlanResource: newExecResource(map[string]interface{}{"apply": "echo connection refused >&2; exit 1"}),
+		Stack:        stack,
+	})
+	if assert.True(t, status.IsErr(response.Status)) {
+		assert.Contains(t, response.Status.Message(), "connection refused")
+	}
+
+	response = e.Apply(context.TODO(), &runtime.ApplyRequest{
+		PlanResource: newExecResource(map[string]interface{}{"read": "true"}),
+		Stack:        stack,
+	})
+	if assert.True(t, status.IsErr(response.Status)) {
+		assert.Equal(t, status.IllegalManifest, response.Status.Code())
+	}
+}
+
+func TestInputHash(t *testing.T) {
+	resource := newExecResource(map[string]interface{}{"apply": "true", "env": map[string]interface{}{"A": "1", "B": "2"}})
+	hash, err := inputHash(resource)
+	assert.NoError(t, err)
+
+	// attributes filled by the runtime don't change the hash
+	resource.Attributes[OutputAttribute] = "done"
+	resource.Attributes[InputHashAttribute] = hash
+	actual, err := inputHash(resource)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, actual)
+
+	resource.Attributes["dir"] = "scripts"
+	actual, err = inputHash(resource)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, actual)
+}
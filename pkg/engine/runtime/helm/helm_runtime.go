@@ -0,0 +1,225 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var _ runtime.Runtime = &HelmRuntime{}
+
+// HelmRuntime manages Helm releases with the helm CLI. Attributes of a Helm resource describe the release, and the
+// runtime fills the ManifestsAttribute with objects rendered from the chart, so that previews show the diff of
+// rendered objects. Objects of releases are watched by the Kubernetes runtime
+type HelmRuntime struct {
+	kubernetes runtime.Runtime
+}
+
+func NewHelmRuntime() (runtime.Runtime, error) {
+	k, err := kubernetes.NewKubernetesRuntime()
+	if err != nil {
+		return nil, err
+	}
+	return &HelmRuntime{kubernetes: k}, nil
+}
+
+// Apply installs or upgrades the release, and renders the chart with helm template in dry runs
+func (h *HelmRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	plan := request.PlanResource
+	r, err := newRelease(plan, request.Stack)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+
+	if request.DryRun {
+		manifests, err := r.template(ctx)
+		if err != nil {
+			return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+		}
+		return &runtime.ApplyResponse{Resource: releaseResource(plan, r.Values, manifests)}
+	}
+
+	if err = r.upgrade(ctx); err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+	}
+	log.Infof("helm release %s of resource %s is upgraded", r.Name, plan.ResourceKey())
+	response := h.Read(ctx, &runtime.ReadRequest{PlanResource: plan, Stack: request.Stack})
+	if status.IsErr(response.Status) {
+		return &runtime.ApplyResponse{Status: response.Status}
+	}
+	if response.Resource == nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(fmt.Errorf("helm release %s is not found after upgrade", r.Name))}
+	}
+	return &runtime.ApplyResponse{Resource: response.Resource}
+}
+
+// Read the user supplied values and the manifest of the release, and returns a nil Resource if it doesn't exist
+func (h *HelmRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	resource := request.PlanResource
+	if resource == nil {
+		resource = request.PriorResource
+	}
+	r, err := newRelease(resource, request.Stack)
+	if err != nil {
+		return &runtime.ReadResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+
+	values, manifests, found, err := r.get(ctx)
+	if err != nil {
+		return &runtime.ReadResponse{Status: status.NewErrorStatus(err)}
+	}
+	if !found {
+		return &runtime.ReadResponse{}
+	}
+	return &runtime.ReadResponse{Resource: releaseResource(resource, values, manifests)}
+}
+
+// Import adopts the existing release with the same name
+func (h *HelmRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	response := h.Read(ctx, &runtime.ReadRequest{PlanResource: request.PlanResource, Stack: request.Stack})
+	if status.IsErr(response.Status) {
+		return &runtime.ImportResponse{Status: response.Status}
+	}
+	if response.Resource == nil {
+		return &runtime.ImportResponse{Status: status.NewErrorStatusWithCode(status.NotFound,
+			fmt.Errorf("helm release of resource %s is not found", request.PlanResource.ResourceKey()))}
+	}
+	return &runtime.ImportResponse{Resource: response.Resource}
+}
+
+// Delete uninstalls the release
+func (h *HelmRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	r, err := newRelease(request.Resource, request.Stack)
+	if err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	if err = r.uninstall(ctx); err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.DeleteResponse{}
+}
+
+// Watch all objects in the manifest of the release with the Kubernetes runtime
+func (h *HelmRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	response := h.Read(ctx, &runtime.ReadRequest{PlanResource: request.Resource, Stack: request.Stack})
+	if status.IsErr(response.Status) {
+		return &runtime.WatchResponse{Status: response.Status}
+	}
+	watchers := runtime.NewWatchers()
+	if response.Resource == nil {
+		return &runtime.WatchResponse{Watchers: watchers}
+	}
+
+	r, _ := newRelease(request.Resource, request.Stack)
+	manifests, _ := response.Resource.Attributes[ManifestsAttribute].([]interface{})
+	for _, m := range manifests {
+		obj, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// objects in the manifest don't have namespaces, and the live object tells whether it is cluster scoped
+		u := &unstructured.Unstructured{Object: obj}
+		if u.GetNamespace() == "" && r.Namespace != "" {
+			u.SetNamespace(r.Namespace)
+		}
+		live := h.kubernetes.Read(ctx, &runtime.ReadRequest{PlanResource: objectResource(u, r), Stack: request.Stack})
+		if status.IsErr(live.Status) {
+			return &runtime.WatchResponse{Status: live.Status}
+		}
+		if live.Resource == nil {
+			continue
+		}
+
+		u = &unstructured.Unstructured{Object: live.Resource.Attributes}
+		objResponse := h.kubernetes.Watch(ctx, &runtime.WatchRequest{Resource: objectResource(u, r), Stack: request.Stack})
+		if objResponse == nil {
+			continue
+		}
+		if status.IsErr(objResponse.Status) {
+			return &runtime.WatchResponse{Status: objResponse.Status}
+		}
+		for i, id := range objResponse.Watchers.IDs {
+			watchers.Insert(id, objResponse.Watchers.Watchers[i])
+		}
+	}
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+// releaseResource returns the resource with values and manifests of the release. Data of Secrets in manifests are
+// sensitive, so that they are redacted in States and previews
+func releaseResource(resource *models.Resource, values map[string]interface{}, manifests []interface{}) *models.Resource {
+	out := resource.DeepCopy()
+	if out.Attributes == nil {
+		out.Attributes = map[string]interface{}{}
+	}
+	delete(out.Attributes, ValuesAttribute)
+	if len(values) != 0 {
+		out.Attributes[ValuesAttribute] = values
+	}
+	out.Attributes[ManifestsAttribute] = manifests
+
+	// paths of manifests in the prior resource are replaced by paths of the current manifests
+	var paths []string
+	manifestsPointer := models.JSONPointer(ManifestsAttribute) + "/"
+	for _, path := range out.SensitivePaths {
+		if !strings.HasPrefix(path, manifestsPointer) {
+			paths = append(paths, path)
+		}
+	}
+	out.SensitivePaths = append(paths, secretPaths(manifests)...)
+	if len(out.SensitivePaths) == 0 {
+		out.SensitivePaths = nil
+	}
+	return out
+}
+
+// secretPaths returns JSON pointers of all keys in data and stringData of Secrets in manifests
+func secretPaths(manifests []interface{}) []string {
+	var paths []string
+	for i, m := range manifests {
+		obj, ok := m.(map[string]interface{})
+		if !ok || obj["apiVersion"] != "v1" || obj["kind"] != "Secret" {
+			continue
+		}
+		for _, field := range []string{"data", "stringData"} {
+			data, _ := obj[field].(map[string]interface{})
+			keys := make([]string, 0, len(data))
+			for key := range data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				paths = append(paths, models.JSONPointer(ManifestsAttribute, strconv.Itoa(i), field, key))
+			}
+		}
+	}
+	return paths
+}
+
+// objectResource returns the Kubernetes resource of the object in the release, which is in the same cluster
+func objectResource(u *unstructured.Unstructured, r *release) *models.Resource {
+	extensions := map[string]interface{}{}
+	if r.kubeConfig != "" {
+		extensions[kubernetes.KubeConfigExtension] = r.kubeConfig
+	}
+	if r.context != "" {
+		extensions[kubernetes.ContextExtension] = r.context
+	}
+	return &models.Resource{
+		ID:         engine.BuildIDForKubernetes(u),
+		Type:       runtime.Kubernetes,
+		Attributes: u.Object,
+		Extensions: extensions,
+	}
+}
@@ -0,0 +1,250 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const manifest = `---
+# Source: nginx/templates/clusterrole.yaml
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: nginx
+---
+# Source: nginx/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  replicas: 2
+`
+
+// fakeHelm keeps the release in memory and records args of all helm commands
+type fakeHelm struct {
+	installed bool
+	values    string
+	calls     [][]string
+}
+
+func (f *fakeHelm) command(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, args)
+	switch args[0] {
+	case "template":
+		return []byte(manifest), nil
+	case "upgrade":
+		f.installed = true
+		f.values = "null"
+		for i, arg := range args {
+			if arg == "--values" {
+				data, err := os.ReadFile(args[i+1])
+				if err != nil {
+					return nil, err
+				}
+				f.values = string(data)
+			}
+		}
+		return nil, nil
+	case "get":
+		if !f.installed {
+			return nil, errors.New("Error: release: not found")
+		}
+		if args[1] == "manifest" {
+			return []byte(manifest), nil
+		}
+		return []byte(f.values), nil
+	case "uninstall":
+		if !f.installed {
+			return nil, errors.New("Error: uninstall: Release not loaded: nginx: release: not found")
+		}
+		f.installed = false
+		return nil, nil
+	}
+	return nil, errors.New("unknown command")
+}
+
+// fakeKubernetesRuntime reads objects without namespaces for cluster scoped kinds, and records watched resources
+type fakeKubernetesRuntime struct {
+	runtime.Runtime
+	watched []*models.Resource
+}
+
+func (f *fakeKubernetesRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	live := request.PlanResource.DeepCopy()
+	if live.Attributes["kind"] == "ClusterRole" {
+		delete(live.Attributes["metadata"].(map[string]interface{}), "namespace")
+	}
+	return &runtime.ReadResponse{Resource: live}
+}
+
+func (f *fakeKubernetesRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	f.watched = append(f.watched, request.Resource)
+	watchers := runtime.NewWatchers()
+	watchers.Insert(request.Resource.ID, make(chan k8swatch.Event))
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+func newHelmResource() *models.Resource {
+	return &models.Resource{
+		ID:   "helm:default:nginx",
+		Type: runtime.Helm,
+		Attributes: map[string]interface{}{
+			"release":         "nginx",
+			"namespace":       "default",
+			"chart":           "nginx",
+			"repo":            "https://charts.bitnami.com/bitnami",
+			"version":         "15.0.0",
+			"values":          map[string]interface{}{"replicaCount": float64(2)},
+			"createNamespace": true,
+		},
+		Extensions: map[string]interface{}{kubernetes.ContextExtension: "dev"},
+	}
+}
+
+func decode(t *testing.T, s string) []interface{} {
+	manifests, err := decodeManifests([]byte(s))
+	assert.NoError(t, err)
+	return manifests
+}
+
+func TestHelmRuntime(t *testing.T) {
+	f := &fakeHelm{}
+	defer func(c func(context.Context, string, ...string) ([]byte, error)) { helmCommand = c }(helmCommand)
+	helmCommand = f.command
+	k := &fakeKubernetesRuntime{}
+	h := &HelmRuntime{kubernetes: k}
+	ctx := context.TODO()
+	stack := &projectstack.Stack{Path: t.TempDir()}
+	resource := newHelmResource()
+	expected := resource.DeepCopy()
+	expected.Attributes[ManifestsAttribute] = decode(t, manifest)
+
+	t.Run("DryRun", func(t *testing.T) {
+		response := h.Apply(ctx, &runtime.ApplyRequest{PlanResource: resource, Stack: stack, DryRun: true})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, expected, response.Resource)
+		assert.False(t, f.installed)
+		assert.Equal(t, []string{"template", "nginx", "nginx", "--repo", "https://charts.bitnami.com/bitnami", "--version", "15.0.0"},
+			f.calls[0][:7])
+		assert.Contains(t, f.calls[0], "--no-hooks")
+	})
+
+	t.Run("ReadNotFound", func(t *testing.T) {
+		response := h.Read(ctx, &runtime.ReadRequest{PlanResource: resource, Stack: stack})
+		assert.Nil(t, response.Status)
+		assert.Nil(t, response.Resource)
+
+		importResponse := h.Import(ctx, &runtime.ImportRequest{PlanResource: resource, Stack: stack})
+		assert.Equal(t, status.NotFound, importResponse.Status.Code())
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		response := h.Apply(ctx, &runtime.ApplyRequest{PlanResource: resource, Stack: stack})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, expected, response.Resource)
+		upgrade := f.calls[len(f.calls)-3]
+		assert.Equal(t, []string{"upgrade", "--install", "nginx", "nginx"}, upgrade[:4])
+		assert.Subset(t, upgrade, []string{"--namespace", "default", "--kube-context", "dev", "--create-namespace"})
+		assert.JSONEq(t, `{"replicaCount": 2}`, f.values)
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		// the prior resource is used in deletions
+		response := h.Read(ctx, &runtime.ReadRequest{PriorResource: expected, Stack: stack})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, expected, response.Resource)
+
+		importResponse := h.Import(ctx, &runtime.ImportRequest{PlanResource: resource, Stack: stack})
+		assert.Nil(t, importResponse.Status)
+		assert.Equal(t, expected, importResponse.Resource)
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		response := h.Watch(ctx, &runtime.WatchRequest{Resource: resource, Stack: stack})
+		assert.Nil(t, response.Status)
+		assert.Equal(t, []string{
+			"rbac.authorization.k8s.io/v1:ClusterRole:nginx",
+			"apps/v1:Deployment:default:nginx",
+		}, response.Watchers.IDs)
+		assert.Equal(t, map[string]interface{}{kubernetes.ContextExtension: "dev"}, k.watched[0].Extensions)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		response := h.Delete(ctx, &runtime.DeleteRequest{Resource: expected, Stack: stack})
+		assert.Nil(t, response.Status)
+		assert.False(t, f.installed)
+
+		// deleting a release not found is a success
+		response = h.Delete(ctx, &runtime.DeleteRequest{Resource: expected, Stack: stack})
+		assert.Nil(t, response.Status)
+	})
+}
+
+func TestReleaseResource_Secrets(t *testing.T) {
+	resource := newHelmResource()
+	// the password in values is resolved from a secret reference, and the stale path of manifests is dropped
+	resource.SensitivePaths = []string{"/values/password", "/manifests/5/data/token"}
+	manifests := decode(t, `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: nginx
+data:
+  password: cGFzc3dvcmQ=
+stringData:
+  token: token
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx
+data:
+  config: config
+`)
+
+	out := releaseResource(resource, map[string]interface{}{"password": "password"}, manifests)
+	assert.Equal(t, []string{
+		"/values/password",
+		"/manifests/0/data/password",
+		"/manifests/0/stringData/token",
+	}, out.SensitivePaths)
+
+	// Secret data in manifests are hashed in the State
+	redacted := out.Redacted()
+	secret := redacted.Attributes[ManifestsAttribute].([]interface{})[0].(map[string]interface{})
+	assert.True(t, models.HasSensitiveHash(secret["data"].(map[string]interface{})["password"]))
+	assert.True(t, models.HasSensitiveHash(secret["stringData"].(map[string]interface{})["token"]))
+	assert.True(t, models.HasSensitiveHash(redacted.Attributes[ValuesAttribute].(map[string]interface{})["password"]))
+	configMap := redacted.Attributes[ManifestsAttribute].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, "config", configMap["data"].(map[string]interface{})["config"])
+}
+
+func TestNewRelease(t *testing.T) {
+	stack := &projectstack.Stack{Path: "/stack"}
+	resource := newHelmResource()
+	resource.Extensions[kubernetes.KubeConfigExtension] = "kubeconfig"
+	r, err := newRelease(resource, stack)
+	assert.NoError(t, err)
+	assert.Equal(t, "/stack/kubeconfig", r.kubeConfig)
+	assert.Equal(t, "/stack", r.dir)
+
+	delete(resource.Attributes, "chart")
+	_, err = newRelease(resource, stack)
+	assert.Error(t, err)
+
+	resource.Attributes["values"] = "replicaCount: 2"
+	_, err = newRelease(resource, stack)
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+const (
+	// ValuesAttribute is the attribute of values of the release
+	ValuesAttribute = "values"
+
+	// ManifestsAttribute is the attribute filled by the runtime, which contains objects rendered from the chart
+	ManifestsAttribute = "manifests"
+
+	// releaseNotFound is the error message of helm when the release doesn't exist
+	releaseNotFound = "release: not found"
+)
+
+// release is the Helm release described by attributes of a Helm resource
+type release struct {
+	// Name of the release
+	Name string `json:"release"`
+
+	// Namespace of the release, the namespace of the current context is used if it is empty
+	Namespace string `json:"namespace,omitempty"`
+
+	// Chart is a chart reference like "bitnami/nginx", a URL, or a path relative to the stack directory
+	Chart string `json:"chart"`
+
+	// Repo is the URL of the chart repository, which is optional
+	Repo string `json:"repo,omitempty"`
+
+	// Version of the chart, the latest version is used if it is empty
+	Version string `json:"version,omitempty"`
+
+	// Values overrides the default values of the chart
+	Values map[string]interface{} `json:"values,omitempty"`
+
+	// CreateNamespace creates the namespace of the release if it doesn't exist
+	CreateNamespace bool `json:"createNamespace,omitempty"`
+
+	// kubeConfig and context select the cluster, which are set by extensions of the resource
+	kubeConfig string
+	context    string
+
+	// dir is the directory where helm runs, which is the stack directory
+	dir string
+}
+
+// helmCommand runs helm with args in dir and returns its stdout. It is a variable to be faked in tests
+var helmCommand = func(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "helm", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if e, ok := err.(*exec.ExitError); ok {
+		return nil, errors.New(strings.TrimSpace(string(e.Stderr)))
+	}
+	return out, err
+}
+
+func newRelease(resource *models.Resource, stack *projectstack.Stack) (*release, error) {
+	data, err := json.Marshal(resource.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	r := &release{}
+	if err = json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("illegal attributes of Helm resource %s: %v", resource.ResourceKey(), err)
+	}
+	if r.Name == "" || r.Chart == "" {
+		return nil, fmt.Errorf("release and chart are required in attributes of Helm resource %s", resource.ResourceKey())
+	}
+
+	if stack != nil {
+		r.dir = stack.GetPath()
+	}
+	r.kubeConfig, _ = resource.Extensions[kubernetes.KubeConfigExtension].(string)
+	if r.kubeConfig != "" && !filepath.IsAbs(r.kubeConfig) && r.dir != "" {
+		r.kubeConfig = filepath.Join(r.dir, r.kubeConfig)
+	}
+	r.context, _ = resource.Extensions[kubernetes.ContextExtension].(string)
+	return r, nil
+}
+
+// globalFlags returns flags selecting the cluster and the namespace
+func (r *release) globalFlags() []string {
+	var flags []string
+	if r.kubeConfig != "" {
+		flags = append(flags, "--kubeconfig", r.kubeConfig)
+	}
+	if r.context != "" {
+		flags = append(flags, "--kube-context", r.context)
+	}
+	if r.Namespace != "" {
+		flags = append(flags, "--namespace", r.Namespace)
+	}
+	return flags
+}
+
+// chartFlags returns flags locating the chart and its values. The returned func removes the values file
+func (r *release) chartFlags() ([]string, func(), error) {
+	flags := []string{r.Name, r.Chart}
+	if r.Repo != "" {
+		flags = append(flags, "--repo", r.Repo)
+	}
+	if r.Version != "" {
+		flags = append(flags, "--version", r.Version)
+	}
+	if len(r.Values) == 0 {
+		return flags, func() {}, nil
+	}
+
+	// JSON is also YAML, so that values keep the same types as in attributes
+	data, err := json.Marshal(r.Values)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.CreateTemp("", "kusion-helm-values-*.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	remove := func() { _ = os.Remove(f.Name()) }
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return nil, nil, err
+	}
+	return append(flags, "--values", f.Name()), remove, nil
+}
+
+// template renders the chart without hooks, which are not in the manifest of releases either
+func (r *release) template(ctx context.Context) ([]interface{}, error) {
+	flags, remove, err := r.chartFlags()
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+	out, err := helmCommand(ctx, r.dir, append(append([]string{"template"}, flags...), append(r.globalFlags(), "--no-hooks")...)...)
+	if err != nil {
+		return nil, fmt.Errorf("helm template %s failed: %v", r.Name, err)
+	}
+	return decodeManifests(out)
+}
+
+// upgrade installs the release if it doesn't exist, or upgrades it
+func (r *release) upgrade(ctx context.Context) error {
+	flags, remove, err := r.chartFlags()
+	if err != nil {
+		return err
+	}
+	defer remove()
+	args := append(append([]string{"upgrade", "--install"}, flags...), r.globalFlags()...)
+	if r.CreateNamespace {
+		args = append(args, "--create-namespace")
+	}
+	if _, err = helmCommand(ctx, r.dir, args...); err != nil {
+		return fmt.Errorf("helm upgrade %s failed: %v", r.Name, err)
+	}
+	return nil
+}
+
+// get returns user supplied values and objects in the manifest of the release, and false if the release is not found
+func (r *release) get(ctx context.Context) (map[string]interface{}, []interface{}, bool, error) {
+	out, err := helmCommand(ctx, r.dir, append([]string{"get", "manifest", r.Name}, r.globalFlags()...)...)
+	if err != nil {
+		if strings.Contains(err.Error(), releaseNotFound) {
+			return nil, nil, false, nil
+		}
+		return nil, nil, false, fmt.Errorf("helm get manifest %s failed: %v", r.Name, err)
+	}
+	manifests, err := decodeManifests(out)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	out, err = helmCommand(ctx, r.dir, append([]string{"get", "values", r.Name, "--output", "json"}, r.globalFlags()...)...)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("helm get values %s failed: %v", r.Name, err)
+	}
+	var values map[string]interface{}
+	if err = json.Unmarshal(out, &values); err != nil {
+		return nil, nil, false, fmt.Errorf("illegal values of release %s: %v", r.Name, err)
+	}
+	return values, manifests, true, nil
+}
+
+// uninstall the release, and it's a success if the release is not found
+func (r *release) uninstall(ctx context.Context) error {
+	_, err := helmCommand(ctx, r.dir, append([]string{"uninstall", r.Name}, r.globalFlags()...)...)
+	if err != nil && !strings.Contains(err.Error(), releaseNotFound) {
+		return fmt.Errorf("helm uninstall %s failed: %v", r.Name, err)
+	}
+	return nil
+}
+
+// decodeManifests decodes objects in the multi-document YAML
+func decodeManifests(data []byte) ([]interface{}, error) {
+	manifests := []interface{}{}
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		obj := make(map[string]interface{})
+		if err := decoder.Decode(&obj); err != nil {
+			if err == io.EOF {
+				return manifests, nil
+			}
+			return nil, fmt.Errorf("error parsing manifests: %v", err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		manifests = append(manifests, obj)
+	}
+}
@@ -3,45 +3,112 @@ package init
 import (
 	"fmt"
 	"reflect"
+	"sync"
 
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/exec"
+	"kusionstack.io/kusion/pkg/engine/runtime/helm"
 	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform"
+	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/status"
 )
 
 var SupportRuntimes = map[models.Type]InitFn{
 	runtime.Kubernetes: kubernetes.NewKubernetesRuntime,
 	runtime.Terraform:  terraform.NewTerraformRuntime,
+	runtime.Helm:       helm.NewHelmRuntime,
+	runtime.Exec:       exec.NewExecRuntime,
 }
 
 // InitFn runtime init func
 type InitFn func() (runtime.Runtime, error)
 
+var (
+	// plugins are runtime plugins launched by this process, which are reused by all operations until Close is called
+	plugins   = map[models.Type]*plugin.GRPCRuntime{}
+	pluginsMu sync.Mutex
+)
+
+// Runtimes returns runtimes of all resource types. Types not in SupportRuntimes are managed by runtime plugins, which
+// are launched once per process and stopped by Close
 func Runtimes(resources models.Resources) (map[models.Type]runtime.Runtime, status.Status) {
 	runtimesMap := map[models.Type]runtime.Runtime{}
 	if resources == nil {
 		return runtimesMap, nil
 	}
 
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	// plugins launched by this call are stopped if any runtime fails to init
+	launched := map[models.Type]*plugin.GRPCRuntime{}
+	s := initRuntimes(resources, runtimesMap, launched)
+	if status.IsErr(s) {
+		for _, r := range launched {
+			_ = r.Close()
+		}
+		return nil, s
+	}
+	for rt, r := range launched {
+		plugins[rt] = r
+	}
+	return runtimesMap, nil
+}
+
+func initRuntimes(resources models.Resources, runtimesMap map[models.Type]runtime.Runtime, launched map[models.Type]*plugin.GRPCRuntime) status.Status {
 	for _, resource := range resources {
 		rt := resource.Type
 		if rt == "" {
-			return nil, status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("no resource type in resource: %v", resource.ID))
+			return status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("no resource type in resource: %v", resource.ID))
+		}
+		if runtimesMap[rt] != nil {
+			continue
 		}
 
-		if SupportRuntimes[rt] == nil {
-			return nil, status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("unknow resource type: %s. Currently supported resource types are: %v",
-				rt, reflect.ValueOf(SupportRuntimes).MapKeys()))
-		} else if runtimesMap[rt] == nil {
+		if SupportRuntimes[rt] != nil {
 			r, err := SupportRuntimes[rt]()
 			if err != nil {
-				return nil, status.NewErrorStatus(fmt.Errorf("init %s runtime failed", rt))
+				return status.NewErrorStatus(fmt.Errorf("init %s runtime failed", rt))
 			}
 			runtimesMap[rt] = r
+			continue
 		}
+
+		if r := plugins[rt]; r != nil {
+			runtimesMap[rt] = r
+			continue
+		}
+		path, err := plugin.Lookup(rt)
+		if err != nil {
+			return status.NewErrorStatus(fmt.Errorf("look up %s runtime plugin failed: %v", rt, err))
+		}
+		if path == "" {
+			discovered, _ := plugin.Discover()
+			return status.NewErrorStatusWithCode(status.IllegalManifest, fmt.Errorf("unknow resource type: %s. Currently supported resource types are: %v, and runtime plugins: %v",
+				rt, reflect.ValueOf(SupportRuntimes).MapKeys(), discovered))
+		}
+		r, err := plugin.Launch(path)
+		if err != nil {
+			return status.NewErrorStatus(fmt.Errorf("init %s runtime failed: %v", rt, err))
+		}
+		launched[rt] = r
+		runtimesMap[rt] = r
 	}
+	return nil
+}
 
-	return runtimesMap, nil
+// Close stops all runtime plugins launched by Runtimes. It is called when the command finishes
+func Close() {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	for rt, r := range plugins {
+		if err := r.Close(); err != nil {
+			log.Warnf("close %s runtime plugin failed: %v", rt, err)
+		}
+		delete(plugins, rt)
+	}
 }
@@ -0,0 +1,68 @@
+//go:build !arm64
+// +build !arm64
+
+package init
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin"
+)
+
+func TestRuntimes_Plugins(t *testing.T) {
+	defer monkey.UnpatchAll()
+	defer Close()
+
+	var launched, closed []string
+	monkey.Patch(plugin.Lookup, func(t models.Type) (string, error) {
+		return "kusion-runtime-" + strings.ToLower(string(t)), nil
+	})
+	monkey.Patch(plugin.Launch, func(path string) (*plugin.GRPCRuntime, error) {
+		if strings.HasSuffix(path, "broken") {
+			return nil, errors.New("exited")
+		}
+		launched = append(launched, path)
+		conn, err := grpc.Dial("unix:///not-exist.sock", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, err
+		}
+		return plugin.NewGRPCRuntime(conn), nil
+	})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&plugin.GRPCRuntime{}), "Close", func(*plugin.GRPCRuntime) error {
+		closed = append(closed, "closed")
+		return nil
+	})
+
+	foo := models.Resource{ID: "foo", Type: "Foo"}
+	bar := models.Resource{ID: "bar", Type: "Bar"}
+	broken := models.Resource{ID: "broken", Type: "Broken"}
+
+	// plugins are launched once per process
+	first, s := Runtimes(models.Resources{foo})
+	assert.Nil(t, s)
+	second, s := Runtimes(models.Resources{foo})
+	assert.Nil(t, s)
+	assert.Same(t, first["Foo"], second["Foo"])
+	assert.Equal(t, []string{"kusion-runtime-foo"}, launched)
+
+	// plugins launched by a failed call are stopped and not reused
+	_, s = Runtimes(models.Resources{bar, broken})
+	assert.NotNil(t, s)
+	assert.Len(t, closed, 1)
+	_, s = Runtimes(models.Resources{foo, bar})
+	assert.Nil(t, s)
+	assert.Equal(t, []string{"kusion-runtime-foo", "kusion-runtime-bar", "kusion-runtime-bar"}, launched)
+
+	Close()
+	assert.Len(t, closed, 3)
+	assert.Empty(t, plugins)
+}
@@ -0,0 +1,323 @@
+package kubernetes
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/util/kube/config"
+)
+
+const (
+	// ClusterExtension is the key of the extension which refers to a cluster declared in the clusters of stack.yaml
+	ClusterExtension = "cluster"
+
+	// KubeConfigExtension is the key of the extension which specifies the kubeconfig file of the cluster the resource
+	// belongs to. The default kubeconfig is used if not set
+	KubeConfigExtension = "kubeconfig"
+
+	// ContextExtension is the key of the extension which specifies the context in the kubeconfig file of the cluster
+	// the resource belongs to. The current context is used if not set
+	ContextExtension = "context"
+
+	// ClusterSeparator separates the resource ID and the cluster name in IDs of resources which specify their clusters
+	ClusterSeparator = "@"
+
+	// implicitRefPrefix is the same as graph.ImplicitRefPrefix, which can't be imported since graph tests import this package
+	implicitRefPrefix = "$kusion_path."
+)
+
+// cluster contains clients of a Kubernetes cluster
+type cluster struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+
+	// newMapper builds a new RESTMapper which discovers resource types of the cluster again
+	newMapper func() (meta.RESTMapper, error)
+	lock      sync.RWMutex
+}
+
+// clusterKey identifies a cluster by the kubeconfig file and the context in it
+type clusterKey struct {
+	kubeConfig string
+	context    string
+}
+
+// newCluster builds clients of the cluster located by the kubeconfig file and the context
+var newCluster = func(key clusterKey) (*cluster, error) {
+	// build config
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: key.kubeConfig},
+		&clientcmd.ConfigOverrides{CurrentContext: key.context},
+	).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// DynamicRESTMapper can discover resource types at runtime dynamically
+	newMapper := func() (meta.RESTMapper, error) {
+		return apiutil.NewDynamicRESTMapper(cfg)
+	}
+	mapper, err := newMapper()
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare the dynamic client
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cluster{client: dyn, mapper: mapper, newMapper: newMapper}, nil
+}
+
+// restMapper returns the current RESTMapper of the cluster
+func (c *cluster) restMapper() meta.RESTMapper {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.mapper
+}
+
+// refreshMapper discovers resource types of the cluster again, so that types defined by CRDs applied in this operation
+// can be mapped
+func (c *cluster) refreshMapper() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.newMapper == nil {
+		if mapper, ok := c.mapper.(meta.ResettableRESTMapper); ok {
+			mapper.Reset()
+		}
+		return nil
+	}
+	mapper, err := c.newMapper()
+	if err != nil {
+		return err
+	}
+	c.mapper = mapper
+	return nil
+}
+
+// clusterOf returns clients of the cluster the resource belongs to. Clients are built at the first time and cached
+// by the kubeconfig file and the context
+func (k *KubernetesRuntime) clusterOf(resource *models.Resource) (*cluster, error) {
+	key, err := getClusterKey(resource)
+	if err != nil {
+		return nil, err
+	}
+	if key == (clusterKey{}) && k.client != nil {
+		return &cluster{client: k.client, mapper: k.mapper}, nil
+	}
+	if key.kubeConfig == "" {
+		key.kubeConfig = config.GetKubeConfig()
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if c, ok := k.clusters[key]; ok {
+		return c, nil
+	}
+	c, err := newCluster(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build clients of the cluster of %s: %v", resource.ResourceKey(), err)
+	}
+	if k.clusters == nil {
+		k.clusters = make(map[clusterKey]*cluster)
+	}
+	k.clusters[key] = c
+	return c, nil
+}
+
+// getClusterKey returns the kubeconfig file and the context specified by extensions of the resource
+func getClusterKey(resource *models.Resource) (clusterKey, error) {
+	kubeConfig, err := getStringExtension(resource, KubeConfigExtension)
+	if err != nil {
+		return clusterKey{}, err
+	}
+	context, err := getStringExtension(resource, ContextExtension)
+	if err != nil {
+		return clusterKey{}, err
+	}
+	return clusterKey{kubeConfig: kubeConfig, context: context}, nil
+}
+
+func getStringExtension(resource *models.Resource, key string) (string, error) {
+	v, ok := resource.Extensions[key]
+	if !ok || v == nil {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("the %s extension of resource %s should be a string", key, resource.ResourceKey())
+	}
+	return s, nil
+}
+
+// ScopedID returns the ID of the resource in the cluster
+func ScopedID(id, cluster string) string {
+	return id + ClusterSeparator + cluster
+}
+
+// ResolveClusters resolves clusters of Kubernetes resources in the Spec, so that the same manifest can be applied to
+// multiple clusters in one operation.
+//
+// A resource specifies its cluster by the cluster extension which refers to the clusters in stack.yaml, or by the
+// kubeconfig and context extensions directly. The former is converted to the latter, and the cluster name, which is
+// the context or the kubeconfig if the context is not set in the latter case, is appended to the resource ID. References
+// to the original ID in dependsOn and implicit references are redirected to the resource in the same cluster, which
+// is located by the kubeconfig and context, as the referrer, or to the only one of resources with the original ID.
+func ResolveClusters(spec *models.Spec, stack *projectstack.Stack) error {
+	if spec == nil {
+		return nil
+	}
+
+	// original ID -> cluster -> scoped ID
+	scoped := map[string]map[clusterKey]string{}
+	clusters := make([]clusterKey, len(spec.Resources))
+	for i := range spec.Resources {
+		resource := &spec.Resources[i]
+		if resource.Type != runtime.Kubernetes {
+			continue
+		}
+		name, err := resolveCluster(resource, stack)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		clusters[i], _ = getClusterKey(resource)
+		if strings.HasSuffix(resource.ID, ClusterSeparator+name) {
+			continue
+		}
+		if scoped[resource.ID] == nil {
+			scoped[resource.ID] = map[clusterKey]string{}
+		}
+		scoped[resource.ID][clusters[i]] = ScopedID(resource.ID, name)
+		resource.ID = ScopedID(resource.ID, name)
+	}
+	if len(scoped) == 0 {
+		return nil
+	}
+
+	redirect := func(referrer *models.Resource, cluster clusterKey, id string) (string, error) {
+		candidates, ok := scoped[id]
+		if !ok {
+			return id, nil
+		}
+		if to, ok := candidates[cluster]; ok {
+			return to, nil
+		}
+		if len(candidates) == 1 {
+			for _, to := range candidates {
+				return to, nil
+			}
+		}
+		return "", fmt.Errorf("%s referred by %s is ambiguous, since it is deployed to multiple clusters",
+			id, referrer.ResourceKey())
+	}
+	for i := range spec.Resources {
+		resource := &spec.Resources[i]
+		for j, dependency := range resource.DependsOn {
+			to, err := redirect(resource, clusters[i], dependency)
+			if err != nil {
+				return err
+			}
+			resource.DependsOn[j] = to
+		}
+		if _, err := redirectImplicitRefs(resource.Attributes, func(id string) (string, error) {
+			return redirect(resource, clusters[i], id)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveCluster converts the cluster extension of the resource to the kubeconfig and context extensions, and returns
+// the name of the cluster. An empty name is returned if the resource belongs to the default cluster
+func resolveCluster(resource *models.Resource, stack *projectstack.Stack) (string, error) {
+	name, err := getStringExtension(resource, ClusterExtension)
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		var c *projectstack.KubernetesCluster
+		if stack != nil {
+			c = stack.Clusters[name]
+		}
+		if c == nil {
+			return "", fmt.Errorf("cluster %s of resource %s is not declared in %s", name, resource.ResourceKey(), projectstack.StackFile)
+		}
+		kubeConfig := c.KubeConfig
+		if kubeConfig != "" && !filepath.IsAbs(kubeConfig) && stack.Path != "" {
+			kubeConfig = filepath.Join(stack.Path, kubeConfig)
+		}
+		setExtension(resource, KubeConfigExtension, kubeConfig)
+		setExtension(resource, ContextExtension, c.Context)
+		return name, nil
+	}
+
+	key, err := getClusterKey(resource)
+	if err != nil {
+		return "", err
+	}
+	if key.context != "" {
+		return key.context, nil
+	}
+	return key.kubeConfig, nil
+}
+
+func setExtension(resource *models.Resource, key, value string) {
+	if value == "" {
+		delete(resource.Extensions, key)
+		return
+	}
+	resource.Extensions[key] = value
+}
+
+// redirectImplicitRefs replaces resource IDs in implicit references of the value by the result of redirect
+func redirectImplicitRefs(v interface{}, redirect func(string) (string, error)) (interface{}, error) {
+	switch value := v.(type) {
+	case string:
+		if !strings.HasPrefix(value, implicitRefPrefix) {
+			return value, nil
+		}
+		ref := strings.SplitN(strings.TrimPrefix(value, implicitRefPrefix), ".", 2)
+		id, err := redirect(ref[0])
+		if err != nil {
+			return nil, err
+		}
+		ref[0] = id
+		return implicitRefPrefix + strings.Join(ref, "."), nil
+	case []interface{}:
+		for i, item := range value {
+			redirected, err := redirectImplicitRefs(item, redirect)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = redirected
+		}
+		return value, nil
+	case map[string]interface{}:
+		for key, item := range value {
+			redirected, err := redirectImplicitRefs(item, redirect)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = redirected
+		}
+		return value, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,225 @@
+package kubernetes
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/util/kube/config"
+)
+
+func newResource(id string, extensions map[string]interface{}, dependsOn ...string) models.Resource {
+	return models.Resource{
+		ID:         id,
+		Type:       runtime.Kubernetes,
+		Attributes: map[string]interface{}{"spec": map[string]interface{}{"ref": "$kusion_path.v1:Namespace:default.metadata.name"}},
+		DependsOn:  dependsOn,
+		Extensions: extensions,
+	}
+}
+
+func TestResolveClusters(t *testing.T) {
+	const ns = "v1:Namespace:default"
+	stack := &projectstack.Stack{
+		StackConfiguration: projectstack.StackConfiguration{
+			Name: "dev",
+			Clusters: map[string]*projectstack.KubernetesCluster{
+				"hz": {KubeConfig: "hz.kubeconfig", Context: "hz-dev"},
+				"sh": {Context: "sh-dev"},
+			},
+		},
+		Path: "/stack",
+	}
+
+	tests := []struct {
+		name          string
+		resources     models.Resources
+		wantIDs       []string
+		wantDependsOn [][]string
+		wantRefs      []string
+		wantErr       bool
+	}{
+		{
+			name:          "default_cluster",
+			resources:     models.Resources{newResource(ns, nil), newResource("v1:ConfigMap:default:c", nil, ns)},
+			wantIDs:       []string{ns, "v1:ConfigMap:default:c"},
+			wantDependsOn: [][]string{nil, {ns}},
+			wantRefs:      []string{"$kusion_path." + ns + ".metadata.name", "$kusion_path." + ns + ".metadata.name"},
+		},
+		{
+			name: "same_manifest_in_multiple_clusters",
+			resources: models.Resources{
+				newResource(ns, map[string]interface{}{ClusterExtension: "hz"}),
+				newResource(ns, map[string]interface{}{ClusterExtension: "sh"}),
+				newResource("v1:ConfigMap:default:c", map[string]interface{}{ClusterExtension: "hz"}, ns),
+				newResource("v1:ConfigMap:default:c", map[string]interface{}{ContextExtension: "sh-dev"}, ns),
+			},
+			wantIDs: []string{
+				ns + "@hz", ns + "@sh", "v1:ConfigMap:default:c@hz", "v1:ConfigMap:default:c@sh-dev",
+			},
+			wantDependsOn: [][]string{nil, nil, {ns + "@hz"}, {ns + "@sh"}},
+			wantRefs: []string{
+				"$kusion_path." + ns + "@hz.metadata.name",
+				"$kusion_path." + ns + "@sh.metadata.name",
+				"$kusion_path." + ns + "@hz.metadata.name",
+				"$kusion_path." + ns + "@sh.metadata.name",
+			},
+		},
+		{
+			name: "only_one_in_other_cluster",
+			resources: models.Resources{
+				newResource(ns, map[string]interface{}{KubeConfigExtension: "/kubeconfig"}),
+				newResource("v1:ConfigMap:default:c", nil, ns),
+			},
+			wantIDs:       []string{ns + "@/kubeconfig", "v1:ConfigMap:default:c"},
+			wantDependsOn: [][]string{nil, {ns + "@/kubeconfig"}},
+			wantRefs: []string{
+				"$kusion_path." + ns + "@/kubeconfig.metadata.name",
+				"$kusion_path." + ns + "@/kubeconfig.metadata.name",
+			},
+		},
+		{
+			name: "ambiguous",
+			resources: models.Resources{
+				newResource(ns, map[string]interface{}{ClusterExtension: "hz"}),
+				newResource(ns, map[string]interface{}{ClusterExtension: "sh"}),
+				newResource("v1:ConfigMap:default:c", nil, ns),
+			},
+			wantErr: true,
+		},
+		{
+			name:      "undeclared_cluster",
+			resources: models.Resources{newResource(ns, map[string]interface{}{ClusterExtension: "bj"})},
+			wantErr:   true,
+		},
+		{
+			name:      "illegal_context",
+			resources: models.Resources{newResource(ns, map[string]interface{}{ContextExtension: 1})},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &models.Spec{Resources: tt.resources}
+			err := ResolveClusters(spec, stack)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for i, resource := range spec.Resources {
+				assert.Equal(t, tt.wantIDs[i], resource.ID)
+				assert.Equal(t, tt.wantDependsOn[i], resource.DependsOn)
+				assert.Equal(t, tt.wantRefs[i], resource.Attributes["spec"].(map[string]interface{})["ref"])
+			}
+
+			// resolving twice makes no difference
+			assert.NoError(t, ResolveClusters(spec, stack))
+			for i, resource := range spec.Resources {
+				assert.Equal(t, tt.wantIDs[i], resource.ID)
+			}
+		})
+	}
+
+	spec := &models.Spec{Resources: models.Resources{newResource(ns, map[string]interface{}{ClusterExtension: "hz"})}}
+	assert.NoError(t, ResolveClusters(spec, stack))
+	assert.Equal(t, map[string]interface{}{
+		ClusterExtension:    "hz",
+		KubeConfigExtension: filepath.Join("/stack", "hz.kubeconfig"),
+		ContextExtension:    "hz-dev",
+	}, spec.Resources[0].Extensions)
+}
+
+func TestKubernetesRuntime_ClusterOf(t *testing.T) {
+	var built []clusterKey
+	defer func(f func(clusterKey) (*cluster, error)) { newCluster = f }(newCluster)
+	newCluster = func(key clusterKey) (*cluster, error) {
+		built = append(built, key)
+		return &cluster{
+			client: dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme()),
+			mapper: meta.NewDefaultRESTMapper(nil),
+		}, nil
+	}
+
+	hz := newResource("hz", map[string]interface{}{KubeConfigExtension: "/hz", ContextExtension: "dev"})
+	sh := newResource("sh", map[string]interface{}{KubeConfigExtension: "/hz", ContextExtension: "sh"})
+	defaultCluster := newResource("default", nil)
+
+	k := &KubernetesRuntime{}
+	c1, err := k.clusterOf(&hz)
+	assert.NoError(t, err)
+	c2, err := k.clusterOf(&hz)
+	assert.NoError(t, err)
+	assert.Same(t, c1, c2)
+	c3, err := k.clusterOf(&sh)
+	assert.NoError(t, err)
+	assert.NotSame(t, c1, c3)
+	_, err = k.clusterOf(&defaultCluster)
+	assert.NoError(t, err)
+	assert.Equal(t, []clusterKey{
+		{kubeConfig: "/hz", context: "dev"},
+		{kubeConfig: "/hz", context: "sh"},
+		{kubeConfig: config.GetKubeConfig()},
+	}, built)
+
+	// the preset default cluster is used by resources without clusters
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	k = &KubernetesRuntime{client: client}
+	c, err := k.clusterOf(&defaultCluster)
+	assert.NoError(t, err)
+	assert.Equal(t, client, c.client)
+	assert.Len(t, built, 3)
+}
+
+func TestKubernetesRuntime_WatchBySelector(t *testing.T) {
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+
+	// the client of the cluster the resource belongs to
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	var namespace, selector string
+	client.PrependWatchReactor("configmaps", func(action k8stesting.Action) (bool, k8swatch.Interface, error) {
+		namespace = action.GetNamespace()
+		selector = action.(k8stesting.WatchAction).GetWatchRestrictions().Labels.String()
+		w := k8swatch.NewFakeWithChanSize(1, false)
+		w.Add(&unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"}})
+		return true, w, nil
+	})
+	defer func(f func(clusterKey) (*cluster, error)) { newCluster = f }(newCluster)
+	newCluster = func(key clusterKey) (*cluster, error) {
+		assert.Equal(t, clusterKey{kubeConfig: "/hz", context: "dev"}, key)
+		return &cluster{client: client, mapper: mapper}, nil
+	}
+
+	resource := &models.Resource{
+		ID:   "v1:ConfigMap:foo:bar",
+		Type: runtime.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "bar", "namespace": "foo"},
+		},
+		Extensions: map[string]interface{}{KubeConfigExtension: "/hz", ContextExtension: "dev"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	k := &KubernetesRuntime{client: dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())}
+	ch, err := k.WatchBySelector(ctx, resource, configMapGVK, "app=bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "ConfigMap", (<-ch).Object.(*unstructured.Unstructured).GetKind())
+	assert.Equal(t, "foo", namespace)
+	assert.Equal(t, "app=bar", selector)
+}
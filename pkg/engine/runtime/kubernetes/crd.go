@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	// crdEstablishedTimeout is the max duration of waiting for an applied CRD to be established
+	crdEstablishedTimeout = time.Minute
+	// crdEstablishedInterval is the interval of checking whether an applied CRD is established
+	crdEstablishedInterval = time.Second
+)
+
+// isCRD returns whether the object is a CustomResourceDefinition
+func isCRD(obj *unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().GroupKind() == crdGroupKind
+}
+
+// waitForCRD waits until the applied CRD is established, and refreshes the RESTMapper of the cluster, so that custom
+// resources of the CRD can be applied right after it
+func (c *cluster) waitForCRD(ctx context.Context, obj *unstructured.Unstructured, resource dynamic.ResourceInterface) error {
+	err := wait.PollUntilContextTimeout(ctx, crdEstablishedInterval, crdEstablishedTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			crd, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					return false, nil
+				}
+				return false, err
+			}
+			conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+			for _, condition := range conditions {
+				cond, ok := condition.(map[string]interface{})
+				if ok && cond["type"] == "Established" && cond["status"] == "True" {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+	if err != nil {
+		return fmt.Errorf("CRD %s is not established: %v", obj.GetName(), err)
+	}
+	return c.refreshMapper()
+}
@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/kube/config"
+)
+
+func TestKubernetesRuntime_ApplyCRD(t *testing.T) {
+	defer func(interval, timeout time.Duration) {
+		crdEstablishedInterval, crdEstablishedTimeout = interval, timeout
+	}(crdEstablishedInterval, crdEstablishedTimeout)
+	crdEstablishedInterval, crdEstablishedTimeout = 10*time.Millisecond, 200*time.Millisecond
+
+	crd := newManifest("crd", "apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "foos.example.com",
+		map[string]interface{}{"spec": map[string]interface{}{"group": "example.com", "names": map[string]interface{}{"kind": "Foo"}}})
+	established := crd.DeepCopy()
+	established.Attributes["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Established", "status": "True"}},
+	}
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		gets        int
+		wantRefresh int
+		wantErr     bool
+	}{
+		{name: "established", gets: 2, wantRefresh: 1},
+		{name: "dry_run", dryRun: true, gets: 2, wantRefresh: 0},
+		{name: "not_established", gets: 1000, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gvk := crdGroupKind.WithVersion("v1")
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+			mapper.Add(gvk, meta.RESTScopeRoot)
+
+			client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+			client.PrependReactor("patch", "customresourcedefinitions",
+				func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+					return true, &unstructured.Unstructured{Object: crd.DeepCopy().Attributes}, nil
+				})
+			gets := 0
+			client.PrependReactor("get", "customresourcedefinitions",
+				func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+					gets++
+					if gets < tt.gets {
+						return true, &unstructured.Unstructured{Object: crd.DeepCopy().Attributes}, nil
+					}
+					return true, &unstructured.Unstructured{Object: established.DeepCopy().Attributes}, nil
+				})
+
+			refreshed := 0
+			c := &cluster{client: client, mapper: mapper, newMapper: func() (meta.RESTMapper, error) {
+				refreshed++
+				return mapper, nil
+			}}
+			k := &KubernetesRuntime{clusters: map[clusterKey]*cluster{{kubeConfig: config.GetKubeConfig()}: c}}
+
+			response := k.Apply(context.TODO(), &runtime.ApplyRequest{
+				PlanResource:    &crd,
+				DryRun:          tt.dryRun,
+				ServerSideApply: true,
+			})
+			assert.Equal(t, tt.wantErr, status.IsErr(response.Status))
+			assert.Equal(t, tt.wantRefresh, refreshed)
+		})
+	}
+}
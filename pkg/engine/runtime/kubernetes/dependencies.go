@@ -0,0 +1,166 @@
+package kubernetes
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/printers/convertor"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+// crdGroupKind is the GroupKind of CustomResourceDefinition
+var crdGroupKind = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+
+// objectKey locates an object of the kind in a cluster. Group is the API group of the kind, or the group of custom
+// resources for CustomResourceDefinitions
+type objectKey struct {
+	cluster   clusterKey
+	group     string
+	kind      string
+	namespace string
+	name      string
+}
+
+// ImplicitDependencies returns dependencies between Kubernetes resources in the same cluster which are not declared
+// explicitly, keyed by resource IDs:
+//   - objects depend on their Namespaces
+//   - custom resources depend on their CustomResourceDefinitions
+//   - workloads depend on ServiceAccounts, ConfigMaps and Secrets referred by their pod specs
+func ImplicitDependencies(resources models.Resources) map[string][]string {
+	objects := make(map[string]*unstructured.Unstructured, len(resources))
+	clusters := make(map[string]clusterKey, len(resources))
+	providers := map[objectKey]string{}
+	for i := range resources {
+		resource := &resources[i]
+		if resource.Type != runtime.Kubernetes || resource.Attributes == nil {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: resource.Attributes}
+		cluster, _ := getClusterKey(resource)
+		objects[resource.ID] = obj
+		clusters[resource.ID] = cluster
+
+		gvk := obj.GroupVersionKind()
+		switch {
+		case gvk.GroupKind() == crdGroupKind:
+			group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+			kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+			providers[objectKey{cluster: cluster, group: group, kind: kind}] = resource.ID
+		case gvk.Group == corev1.GroupName:
+			switch gvk.Kind {
+			case convertor.Namespace, convertor.ServiceAccount, convertor.ConfigMap, convertor.Secret:
+				providers[objectKey{cluster: cluster, kind: gvk.Kind, namespace: obj.GetNamespace(), name: obj.GetName()}] = resource.ID
+			}
+		}
+	}
+
+	dependencies := map[string][]string{}
+	for id, obj := range objects {
+		cluster := clusters[id]
+		gvk := obj.GroupVersionKind()
+		required := []objectKey{{cluster: cluster, group: gvk.Group, kind: gvk.Kind}}
+		if namespace := obj.GetNamespace(); namespace != "" {
+			required = append(required, objectKey{cluster: cluster, kind: convertor.Namespace, name: namespace})
+			for _, ref := range podSpecReferences(obj) {
+				ref.cluster = cluster
+				ref.namespace = namespace
+				required = append(required, ref)
+			}
+		}
+
+		deps := map[string]bool{}
+		for _, key := range required {
+			if dep, ok := providers[key]; ok && dep != id {
+				deps[dep] = true
+			}
+		}
+		for dep := range deps {
+			dependencies[id] = append(dependencies[id], dep)
+		}
+		sort.Strings(dependencies[id])
+	}
+	return dependencies
+}
+
+// podSpecReferences returns keys of ServiceAccounts, ConfigMaps and Secrets referred by the pod spec of the workload,
+// without clusters and namespaces
+func podSpecReferences(obj *unstructured.Unstructured) []objectKey {
+	var fields []string
+	switch obj.GetKind() {
+	case convertor.Pod:
+		fields = []string{"spec"}
+	case convertor.Deployment, convertor.StatefulSet, convertor.DaemonSet, convertor.ReplicaSet,
+		convertor.ReplicationController, convertor.Job:
+		fields = []string{"spec", "template", "spec"}
+	case convertor.CronJob:
+		fields = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+	raw, found, err := unstructured.NestedMap(obj.Object, fields...)
+	if !found || err != nil {
+		return nil
+	}
+	podSpec := &corev1.PodSpec{}
+	if err = k8sruntime.DefaultUnstructuredConverter.FromUnstructured(raw, podSpec); err != nil {
+		return nil
+	}
+
+	var refs []objectKey
+	add := func(kind, name string) {
+		if name != "" {
+			refs = append(refs, objectKey{kind: kind, name: name})
+		}
+	}
+	add(convertor.ServiceAccount, podSpec.ServiceAccountName)
+	add(convertor.ServiceAccount, podSpec.DeprecatedServiceAccount)
+	for _, secret := range podSpec.ImagePullSecrets {
+		add(convertor.Secret, secret.Name)
+	}
+	for _, volume := range podSpec.Volumes {
+		if volume.ConfigMap != nil {
+			add(convertor.ConfigMap, volume.ConfigMap.Name)
+		}
+		if volume.Secret != nil {
+			add(convertor.Secret, volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add(convertor.ConfigMap, source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					add(convertor.Secret, source.Secret.Name)
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				add(convertor.ConfigMap, env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				add(convertor.Secret, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add(convertor.ConfigMap, envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				add(convertor.Secret, envFrom.SecretRef.Name)
+			}
+		}
+	}
+	return refs
+}
@@ -0,0 +1,81 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+func newManifest(id, apiVersion, kind, namespace, name string, fields map[string]interface{}) models.Resource {
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	attributes := map[string]interface{}{"apiVersion": apiVersion, "kind": kind, "metadata": metadata}
+	for k, v := range fields {
+		attributes[k] = v
+	}
+	return models.Resource{ID: id, Type: runtime.Kubernetes, Attributes: attributes}
+}
+
+func TestImplicitDependencies(t *testing.T) {
+	podSpec := map[string]interface{}{
+		"serviceAccountName": "sa",
+		"imagePullSecrets":   []interface{}{map[string]interface{}{"name": "registry"}},
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "config"}},
+			map[string]interface{}{"name": "projected", "projected": map[string]interface{}{
+				"sources": []interface{}{map[string]interface{}{"secret": map[string]interface{}{"name": "token"}}},
+			}},
+		},
+		"containers": []interface{}{map[string]interface{}{
+			"name": "nginx",
+			"env": []interface{}{map[string]interface{}{
+				"name":      "PASSWORD",
+				"valueFrom": map[string]interface{}{"secretKeyRef": map[string]interface{}{"name": "password", "key": "p"}},
+			}},
+			"envFrom": []interface{}{map[string]interface{}{"configMapRef": map[string]interface{}{"name": "env"}}},
+		}},
+	}
+	crd := newManifest("crd", "apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "foos.example.com",
+		map[string]interface{}{"spec": map[string]interface{}{"group": "example.com", "names": map[string]interface{}{"kind": "Foo"}}})
+	otherCluster := newManifest("ns@other", "v1", "Namespace", "", "default", nil)
+	otherCluster.Extensions = map[string]interface{}{ContextExtension: "other"}
+
+	resources := models.Resources{
+		newManifest("ns", "v1", "Namespace", "", "default", nil),
+		otherCluster,
+		crd,
+		newManifest("foo", "example.com/v1", "Foo", "default", "foo", nil),
+		newManifest("sa", "v1", "ServiceAccount", "default", "sa", nil),
+		newManifest("registry", "v1", "Secret", "default", "registry", nil),
+		newManifest("config", "v1", "ConfigMap", "default", "config", nil),
+		newManifest("env", "v1", "ConfigMap", "default", "env", nil),
+		newManifest("token", "v1", "Secret", "default", "token", nil),
+		newManifest("password", "v1", "Secret", "default", "password", nil),
+		newManifest("other-ns-config", "v1", "ConfigMap", "other", "config", nil),
+		newManifest("deployment", "apps/v1", "Deployment", "default", "nginx",
+			map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": podSpec}}}),
+		newManifest("cronjob", "batch/v1", "CronJob", "default", "job", map[string]interface{}{
+			"spec": map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{
+				"template": map[string]interface{}{"spec": map[string]interface{}{"serviceAccountName": "sa"}},
+			}}},
+		}),
+		{ID: "terraform", Type: runtime.Terraform, Attributes: map[string]interface{}{"namespace": "default"}},
+	}
+
+	assert.Equal(t, map[string][]string{
+		"foo":        {"crd", "ns"},
+		"sa":         {"ns"},
+		"registry":   {"ns"},
+		"config":     {"ns"},
+		"env":        {"ns"},
+		"token":      {"ns"},
+		"password":   {"ns"},
+		"deployment": {"config", "env", "ns", "password", "registry", "sa", "token"},
+		"cronjob":    {"ns", "sa"},
+	}, ImplicitDependencies(resources))
+}
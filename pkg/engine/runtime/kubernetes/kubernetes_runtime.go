@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"context"
 	"errors"
+	"sync"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	yamlv2 "gopkg.in/yaml.v2"
@@ -21,8 +22,6 @@ import (
 	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
 	k8swatch "k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/tools/clientcmd"
-	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"kusionstack.io/kusion/pkg/engine"
 	"kusionstack.io/kusion/pkg/engine/models"
@@ -31,33 +30,32 @@ import (
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/status"
 	jsonutil "kusionstack.io/kusion/pkg/util/json"
-	"kusionstack.io/kusion/pkg/util/kube/config"
 )
 
-var _ runtime.Runtime = (*KubernetesRuntime)(nil)
+var (
+	_ runtime.Runtime = (*KubernetesRuntime)(nil)
+	_ runtime.Retrier = (*KubernetesRuntime)(nil)
+)
 
 type KubernetesRuntime struct {
+	// client and mapper of the default cluster, built from the default kubeconfig if not set
 	client dynamic.Interface
 	mapper meta.RESTMapper
+
+	// clusters caches clients of clusters by the kubeconfig file and the context
+	clusters map[clusterKey]*cluster
+	lock     sync.Mutex
 }
 
-// NewKubernetesRuntime create a new KubernetesRuntime
+// NewKubernetesRuntime create a new KubernetesRuntime. Clients of clusters are built when they are used for the first
+// time, since resources may specify their own clusters by the kubeconfig and context extensions
 func NewKubernetesRuntime() (runtime.Runtime, error) {
-	client, mapper, err := getKubernetesClient()
-	if err != nil {
-		return nil, err
-	}
-
-	return &KubernetesRuntime{
-		client: client,
-		mapper: mapper,
-	}, nil
+	return &KubernetesRuntime{}, nil
 }
 
 // Apply kubernetes Resource by client-go
 func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
 	planState := request.PlanResource
-	priorState := request.PriorResource
 
 	// Don`t consider delete case, so plan state must be not empty
 	if planState == nil {
@@ -65,10 +63,40 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyReq
 	}
 
 	// Get kubernetes Resource interface from plan state
-	planObj, resource, err := k.buildKubernetesResourceByState(planState)
+	c, err := k.clusterOf(planState)
 	if err != nil {
 		return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
 	}
+	planObj, resource, err := c.buildKubernetesResourceByState(planState)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+	}
+
+	var response *runtime.ApplyResponse
+	if request.ServerSideApply {
+		response = serverSideApply(ctx, request, planObj, resource)
+	} else {
+		response = k.clientSideApply(ctx, request, planObj, resource)
+	}
+
+	// Custom resources of the applied CRD can't be mapped until the RESTMapper of the cluster is refreshed
+	if !request.DryRun && !status.IsErr(response.Status) && isCRD(planObj) {
+		if err = c.waitForCRD(ctx, planObj, resource); err != nil {
+			return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+		}
+	}
+	return response
+}
+
+// clientSideApply applies the resource by the 3-way merge patch, like kubectl apply
+func (k *KubernetesRuntime) clientSideApply(
+	ctx context.Context,
+	request *runtime.ApplyRequest,
+	planObj *unstructured.Unstructured,
+	resource dynamic.ResourceInterface,
+) *runtime.ApplyResponse {
+	planState := request.PlanResource
+	priorState := request.PriorResource
 
 	// Get live state
 	response := k.Read(ctx, &runtime.ReadRequest{PlanResource: planState})
@@ -143,7 +171,7 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyReq
 			_, err = resource.Create(ctx, planObj, metav1.CreateOptions{})
 		} else {
 			// LiveState isn't nil, continue to patch liveObj
-			_, err = resource.Patch(ctx, planObj.GetName(), types.MergePatchType, patchBody, metav1.PatchOptions{FieldManager: "kusion"})
+			_, err = resource.Patch(ctx, planObj.GetName(), types.MergePatchType, patchBody, metav1.PatchOptions{FieldManager: FieldManager})
 		}
 		if err != nil {
 			return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
@@ -301,13 +329,23 @@ func (k *KubernetesRuntime) Delete(ctx context.Context, request *runtime.DeleteR
 	return &runtime.DeleteResponse{}
 }
 
+// Retryable retries failures of the API server, e.g. throttled requests and conflicts of the resource version. Mutations
+// are retried too, since applying and deleting objects are idempotent
+func (k *KubernetesRuntime) Retryable(s status.Status, _ bool) bool {
+	return runtime.IsTransient(s, runtime.TransientPatterns)
+}
+
 // Watch kubernetes resource by client-go
 func (k *KubernetesRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
 	if request == nil || request.Resource == nil {
 		return &runtime.WatchResponse{Status: status.NewErrorStatus(errors.New("requestResource is nil"))}
 	}
 
-	reqObj, resource, err := k.buildKubernetesResourceByState(request.Resource)
+	c, err := k.clusterOf(request.Resource)
+	if err != nil {
+		return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
+	}
+	reqObj, resource, err := c.buildKubernetesResourceByState(request.Resource)
 	if err != nil {
 		return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
 	}
@@ -326,21 +364,21 @@ func (k *KubernetesRuntime) Watch(ctx context.Context, request *runtime.WatchReq
 	watchers.Insert(engine.BuildIDForKubernetes(reqObj), rootCh)
 
 	if reqObj.GetKind() == convertor.Service { // Watch Endpoints or EndpointSlice
-		if gvk, err := k.mapper.KindFor(schema.GroupVersionResource{
+		if gvk, err := c.restMapper().KindFor(schema.GroupVersionResource{
 			Group:    discoveryv1.SchemeGroupVersion.Group,
 			Version:  discoveryv1.SchemeGroupVersion.Version,
 			Resource: convertor.EndpointSlice,
 		}); gvk.Empty() || err != nil { // Watch Endpoints
 			log.Errorf("k8s runtime has no kind for EndpointSlice, err: %v", err)
 			namedGVK := getNamedGVK(reqObj.GroupVersionKind())
-			ch, dependent, err := k.WatchByRelation(ctx, reqObj, namedGVK, namedBy)
+			ch, dependent, err := c.watchByRelation(ctx, reqObj, namedGVK, namedBy)
 			if err != nil {
 				return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
 			}
 			watchers.Insert(engine.BuildIDForKubernetes(dependent), ch)
 		} else { // Watch EndpointSlice
 			dependentGVK := getDependentGVK(reqObj.GroupVersionKind())
-			ch, dependent, err := k.WatchByRelation(ctx, reqObj, dependentGVK, ownedBy)
+			ch, dependent, err := c.watchByRelation(ctx, reqObj, dependentGVK, ownedBy)
 			if err != nil {
 				return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
 			}
@@ -352,7 +390,7 @@ func (k *KubernetesRuntime) Watch(ctx context.Context, request *runtime.WatchReq
 		if !dependentGVK.Empty() {
 			owner := reqObj
 			for !dependentGVK.Empty() {
-				ch, dependent, err := k.WatchByRelation(ctx, owner, dependentGVK, ownedBy)
+				ch, dependent, err := c.watchByRelation(ctx, owner, dependentGVK, ownedBy)
 				if err != nil {
 					return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
 				}
@@ -373,31 +411,17 @@ func (k *KubernetesRuntime) Watch(ctx context.Context, request *runtime.WatchReq
 	return &runtime.WatchResponse{Watchers: watchers}
 }
 
-// getKubernetesClient get kubernetes client
-func getKubernetesClient() (dynamic.Interface, meta.RESTMapper, error) {
-	// build config
-	cfg, err := clientcmd.BuildConfigFromFlags("", config.GetKubeConfig())
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// DynamicRESTMapper can discover resource types at runtime dynamically
-	mapper, err := apiutil.NewDynamicRESTMapper(cfg)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Prepare the dynamic client
-	dyn, err := dynamic.NewForConfig(cfg)
+// buildKubernetesResourceByState get resource by attribute in the cluster the resource belongs to
+func (k *KubernetesRuntime) buildKubernetesResourceByState(resourceState *models.Resource) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
+	c, err := k.clusterOf(resourceState)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	return dyn, mapper, nil
+	return c.buildKubernetesResourceByState(resourceState)
 }
 
-// buildKubernetesResourceByState get resource by attribute
-func (k *KubernetesRuntime) buildKubernetesResourceByState(resourceState *models.Resource) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
+// buildKubernetesResourceByState get resource by attribute in this cluster
+func (c *cluster) buildKubernetesResourceByState(resourceState *models.Resource) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
 	// Convert interface{} to unstructured
 	rYaml, err := yamlv2.Marshal(resourceState.Attributes)
 	if err != nil {
@@ -412,7 +436,7 @@ func (k *KubernetesRuntime) buildKubernetesResourceByState(resourceState *models
 	// Get resource by unstructured
 	var resource dynamic.ResourceInterface
 
-	resource, err = buildDynamicResource(k.client, k.mapper, gvk, obj.GetNamespace())
+	resource, err = buildDynamicResource(c.client, c.restMapper(), gvk, obj.GetNamespace())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -457,14 +481,32 @@ func convertString2Unstructured(yamlContent []byte) (*unstructured.Unstructured,
 	return obj, gvk, nil
 }
 
-// WatchBySelector watch resources by gvk and filter by selector
+// WatchBySelector watch resources by gvk and filter by selector in the cluster and the namespace of the resource
 func (k *KubernetesRuntime) WatchBySelector(
+	ctx context.Context,
+	resource *models.Resource,
+	gvk schema.GroupVersionKind,
+	labelStr string,
+) (<-chan k8swatch.Event, error) {
+	c, err := k.clusterOf(resource)
+	if err != nil {
+		return nil, err
+	}
+	o, _, err := c.buildKubernetesResourceByState(resource)
+	if err != nil {
+		return nil, err
+	}
+	return c.watchBySelector(ctx, o, gvk, labelStr)
+}
+
+// watchBySelector watch resources by gvk and filter by selector in this cluster
+func (c *cluster) watchBySelector(
 	ctx context.Context,
 	o *unstructured.Unstructured,
 	gvk schema.GroupVersionKind,
 	labelStr string,
 ) (<-chan k8swatch.Event, error) {
-	clientForResource, err := buildDynamicResource(k.client, k.mapper, &gvk, o.GetNamespace())
+	clientForResource, err := buildDynamicResource(c.client, c.restMapper(), &gvk, o.GetNamespace())
 	if err != nil {
 		return nil, err
 	}
@@ -477,32 +519,37 @@ func (k *KubernetesRuntime) WatchBySelector(
 	return doWatch(ctx, w, nil), nil
 }
 
-// WatchByRelation watched resources by giving gvk if related() return true
-func (k *KubernetesRuntime) WatchByRelation(
+// watchByRelation watched resources by giving gvk if related() return true
+func (c *cluster) watchByRelation(
 	ctx context.Context,
 	cur *unstructured.Unstructured,
 	gvk schema.GroupVersionKind,
 	related func(watched, cur *unstructured.Unstructured) bool,
 ) (<-chan k8swatch.Event, *unstructured.Unstructured, error) {
-	mapping, err := k.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	mapping, err := c.restMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	clientForResource := k.client.Resource(mapping.Resource).Namespace(cur.GetNamespace())
-	w, err := clientForResource.Watch(context.TODO(), metav1.ListOptions{})
+	clientForResource := c.client.Resource(mapping.Resource).Namespace(cur.GetNamespace())
+	w, err := clientForResource.Watch(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var next *unstructured.Unstructured
-	return doWatch(ctx, w, func(watched *unstructured.Unstructured) bool {
+	ch := doWatch(ctx, w, func(watched *unstructured.Unstructured) bool {
 		ok := related(watched, cur)
 		if ok {
 			next = watched
 		}
 		return ok
-	}), next, nil
+	})
+	// Canceled before any related resource is watched
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return ch, next, nil
 }
 
 // doWatch send watched object if check ok
@@ -523,9 +570,13 @@ func doWatch(ctx context.Context, watcher k8swatch.Interface, checker func(watch
 				}
 				// Check
 				if checker == nil || checker != nil && checker(dependent) {
-					resultCh <- e
+					select {
+					case resultCh <- e:
+					case <-ctx.Done():
+						return
+					}
 					if first {
-						signal <- struct{}{}
+						close(signal)
 						first = false
 					}
 				}
@@ -536,7 +587,10 @@ func doWatch(ctx context.Context, watcher k8swatch.Interface, checker func(watch
 	}()
 
 	// Owner&Dependent check pass, return the dependent Obj
-	<-signal
+	select {
+	case <-signal:
+	case <-ctx.Done():
+	}
 
 	return resultCh
 }
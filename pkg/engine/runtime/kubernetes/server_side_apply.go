@@ -0,0 +1,83 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// FieldManager is the name of the field manager of resources applied by kusion
+const FieldManager = "kusion"
+
+// serverSideApply applies the plan object by the server-side apply, which merges lists by their keys and tracks the
+// ownership of fields with field managers. The dry-run is also performed by the server-side apply, so that previews
+// match the result of apply
+func serverSideApply(
+	ctx context.Context,
+	request *runtime.ApplyRequest,
+	planObj *unstructured.Unstructured,
+	resource dynamic.ResourceInterface,
+) *runtime.ApplyResponse {
+	planState := request.PlanResource
+	data, err := planObj.MarshalJSON()
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+	}
+
+	options := serverSideApplyOptions(request.DryRun, request.ForceConflicts)
+	res, err := resource.Patch(ctx, planObj.GetName(), types.ApplyPatchType, data, options)
+	if err != nil {
+		if k8serrors.IsConflict(err) {
+			return &runtime.ApplyResponse{Status: status.NewErrorStatusWithCode(status.Conflict, conflictError(planState.ID, err))}
+		}
+		return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+	}
+	// Save modified like the client-side apply, the result of dry-run is returned to diff
+	if !request.DryRun {
+		res = planObj
+	}
+
+	return &runtime.ApplyResponse{Resource: &models.Resource{
+		ID:         planState.ResourceKey(),
+		Type:       planState.Type,
+		Attributes: res.Object,
+		DependsOn:  planState.DependsOn,
+		Extensions: planState.Extensions,
+	}}
+}
+
+func serverSideApplyOptions(dryRun, force bool) metav1.PatchOptions {
+	options := metav1.PatchOptions{FieldManager: FieldManager, Force: &force}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
+
+// conflictError lists fields managed by other field managers in the conflict error of the server-side apply
+func conflictError(id string, err error) error {
+	var conflicts []string
+	if apiStatus, ok := err.(k8serrors.APIStatus); ok && apiStatus.Status().Details != nil {
+		for _, cause := range apiStatus.Status().Details.Causes {
+			if cause.Type == metav1.CauseTypeFieldManagerConflict {
+				conflicts = append(conflicts, cause.Message)
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return fmt.Errorf("apply %s conflicts with other field managers: %v. "+
+			"Please use flag `--force-conflicts` to take the ownership of these fields", id, err)
+	}
+	return fmt.Errorf("apply %s conflicts with other field managers:\n  - %s\n"+
+		"Please use flag `--force-conflicts` to take the ownership of these fields", id, strings.Join(conflicts, "\n  - "))
+}
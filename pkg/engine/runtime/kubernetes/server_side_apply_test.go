@@ -0,0 +1,106 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var deployment = &models.Resource{
+	ID:   "apps/v1:Deployment:default:nginx",
+	Type: runtime.Kubernetes,
+	Attributes: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "nginx", "namespace": "default"},
+		"spec":       map[string]interface{}{"replicas": 2},
+	},
+}
+
+func newFakeRuntime(reactor k8stesting.ReactionFunc) *KubernetesRuntime {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+	mapper.Add(gvk, meta.RESTScopeNamespace)
+
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	client.PrependReactor("patch", "deployments", reactor)
+	return &KubernetesRuntime{client: client, mapper: mapper}
+}
+
+func TestKubernetesRuntime_ServerSideApply(t *testing.T) {
+	conflict := k8serrors.NewApplyConflict([]metav1.StatusCause{{
+		Type:    metav1.CauseTypeFieldManagerConflict,
+		Message: `conflict with "kubectl": .spec.replicas`,
+		Field:   ".spec.replicas",
+	}}, `Apply failed with 1 conflict: conflict with "kubectl": .spec.replicas`)
+
+	tests := []struct {
+		name         string
+		dryRun       bool
+		patchErr     error
+		wantReplicas int64
+		wantCode     status.Code
+		wantContains string
+	}{
+		{name: "dry_run", dryRun: true, wantReplicas: 3},
+		{name: "apply", dryRun: false, wantReplicas: 2},
+		{name: "conflict", patchErr: conflict, wantCode: status.Conflict, wantContains: `conflict with "kubectl": .spec.replicas`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patchType types.PatchType
+			k := newFakeRuntime(func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+				patchType = action.(k8stesting.PatchAction).GetPatchType()
+				if tt.patchErr != nil {
+					return true, nil, tt.patchErr
+				}
+				// the server merges the plan with fields managed by others
+				obj := &unstructured.Unstructured{Object: deployment.DeepCopy().Attributes}
+				_ = unstructured.SetNestedField(obj.Object, int64(3), "spec", "replicas")
+				return true, obj, nil
+			})
+
+			response := k.Apply(context.TODO(), &runtime.ApplyRequest{
+				PlanResource:    deployment,
+				DryRun:          tt.dryRun,
+				ServerSideApply: true,
+			})
+			assert.Equal(t, types.ApplyPatchType, patchType)
+			if tt.wantCode != "" {
+				assert.True(t, status.IsErr(response.Status))
+				assert.Equal(t, tt.wantCode, response.Status.Code())
+				assert.Contains(t, response.Status.Message(), tt.wantContains)
+				assert.Contains(t, response.Status.Message(), "--force-conflicts")
+				return
+			}
+			assert.Nil(t, response.Status)
+			replicas, _, _ := unstructured.NestedFieldNoCopy(response.Resource.Attributes, "spec", "replicas")
+			assert.EqualValues(t, tt.wantReplicas, replicas)
+		})
+	}
+}
+
+func Test_serverSideApplyOptions(t *testing.T) {
+	options := serverSideApplyOptions(true, false)
+	assert.Equal(t, FieldManager, options.FieldManager)
+	assert.Equal(t, []string{metav1.DryRunAll}, options.DryRun)
+	assert.False(t, *options.Force)
+
+	options = serverSideApplyOptions(false, true)
+	assert.Empty(t, options.DryRun)
+	assert.True(t, *options.Force)
+}
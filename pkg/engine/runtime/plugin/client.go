@@ -0,0 +1,250 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcstatus "google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var (
+	_ runtime.Runtime = &GRPCRuntime{}
+	_ runtime.Retrier = &GRPCRuntime{}
+)
+
+// launchTimeout is the timeout of waiting for a launched plugin to serve
+var launchTimeout = 10 * time.Second
+
+// socketSeq makes socket paths of plugins launched by this process unique
+var socketSeq int32
+
+// GRPCRuntime forwards all requests to a runtime served by a plugin
+type GRPCRuntime struct {
+	conn *grpc.ClientConn
+
+	// stdin and exited belong to the plugin process started by Launch
+	stdin  io.Closer
+	exited chan struct{}
+}
+
+// NewGRPCRuntime returns a runtime which forwards requests to the plugin connected by conn
+func NewGRPCRuntime(conn *grpc.ClientConn) *GRPCRuntime {
+	return &GRPCRuntime{conn: conn}
+}
+
+// Launch starts the plugin at path and connects to it. The plugin exits after Close is called, or when this process
+// exits and the stdin of the plugin is closed
+func Launch(path string) (*GRPCRuntime, error) {
+	socket := filepath.Join(os.TempDir(), fmt.Sprintf("kusion-plugin-%d-%d.sock", os.Getpid(), atomic.AddInt32(&socketSeq, 1)))
+	stdin, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer stdin.Close()
+
+	stderr := &logWriter{plugin: filepath.Base(path)}
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), EnvPluginSocket+"="+socket)
+	cmd.Stdin = stdin
+	cmd.Stderr = stderr
+	if err = cmd.Start(); err != nil {
+		_ = stdinWriter.Close()
+		return nil, fmt.Errorf("start runtime plugin %s failed: %v", path, err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), launchTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-exited:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	conn, err := grpc.DialContext(ctx, "unix://"+socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.Config{
+			BaseDelay:  50 * time.Millisecond,
+			Multiplier: 1.6,
+			MaxDelay:   time.Second,
+		}}),
+	)
+	if err != nil {
+		_ = stdinWriter.Close()
+		_ = cmd.Process.Kill()
+		<-exited
+		return nil, fmt.Errorf("connect to runtime plugin %s failed: %v, last output: %s", path, err, stderr.last())
+	}
+	log.Infof("launched runtime plugin %s on %s", path, socket)
+	return &GRPCRuntime{conn: conn, stdin: stdinWriter, exited: exited}, nil
+}
+
+// Close closes the connection and stops the plugin started by Launch
+func (g *GRPCRuntime) Close() error {
+	err := g.conn.Close()
+	if g.stdin != nil {
+		_ = g.stdin.Close()
+		<-g.exited
+	}
+	return err
+}
+
+func (g *GRPCRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	response, s := g.invoke(ctx, "Apply", request)
+	if s != nil {
+		return &runtime.ApplyResponse{Status: s}
+	}
+	return &runtime.ApplyResponse{Resource: response.Resource, Status: response.Status.toStatus()}
+}
+
+func (g *GRPCRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	response, s := g.invoke(ctx, "Read", request)
+	if s != nil {
+		return &runtime.ReadResponse{Status: s}
+	}
+	return &runtime.ReadResponse{Resource: response.Resource, Status: response.Status.toStatus()}
+}
+
+func (g *GRPCRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	response, s := g.invoke(ctx, "Import", request)
+	if s != nil {
+		return &runtime.ImportResponse{Status: s}
+	}
+	return &runtime.ImportResponse{Resource: response.Resource, Status: response.Status.toStatus()}
+}
+
+func (g *GRPCRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	response, s := g.invoke(ctx, "Delete", request)
+	if s != nil {
+		return &runtime.DeleteResponse{Status: s}
+	}
+	return &runtime.DeleteResponse{Status: response.Status.toStatus()}
+}
+
+// Retryable only retries failures with transient status codes, which are reported by plugins or the connection. Timed
+// out mutations are not retried, since the plugin may have changed resources before it was cancelled
+func (g *GRPCRuntime) Retryable(s status.Status, mutation bool) bool {
+	if mutation && s.Code() == status.DeadlineExceeded {
+		return false
+	}
+	return runtime.IsTransient(s, nil)
+}
+
+// Watch returns nil if the runtime of the plugin doesn't support watching. Watchers are closed when the plugin finishes
+// the stream or ctx is done
+func (g *GRPCRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	stream, err := g.conn.NewStream(ctx, &serviceDesc.Streams[0], fullMethod("Watch"), grpc.CallContentSubtype(codecName))
+	if err == nil {
+		err = stream.SendMsg(request)
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	first := &WatchMessage{}
+	if err == nil {
+		err = stream.RecvMsg(first)
+	}
+	if err != nil {
+		if grpcstatus.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return &runtime.WatchResponse{Status: unavailable("Watch", err)}
+	}
+	if status.IsErr(first.Status.toStatus()) || first.IDs == nil {
+		return &runtime.WatchResponse{Status: first.Status.toStatus()}
+	}
+
+	watchers := runtime.NewWatchers()
+	channels := make([]chan k8swatch.Event, len(first.IDs))
+	for i, id := range first.IDs {
+		channels[i] = make(chan k8swatch.Event)
+		watchers.Insert(id, channels[i])
+	}
+	go func() {
+		defer func() {
+			for _, ch := range channels {
+				close(ch)
+			}
+		}()
+		for {
+			msg := &WatchMessage{}
+			if err := stream.RecvMsg(msg); err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					log.Errorf("receive watch events of %s failed: %v", request.Resource.ResourceKey(), err)
+				}
+				return
+			}
+			if msg.Event == nil || msg.Event.Index < 0 || msg.Event.Index >= len(channels) {
+				continue
+			}
+			e := k8swatch.Event{Type: msg.Event.Type, Object: &unstructured.Unstructured{Object: msg.Event.Object}}
+			select {
+			case channels[msg.Event.Index] <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &runtime.WatchResponse{Watchers: watchers, Status: first.Status.toStatus()}
+}
+
+func (g *GRPCRuntime) invoke(ctx context.Context, method string, request interface{}) (*ResourceResponse, status.Status) {
+	response := &ResourceResponse{}
+	if err := g.conn.Invoke(ctx, fullMethod(method), request, response, grpc.CallContentSubtype(codecName)); err != nil {
+		return nil, unavailable(method, err)
+	}
+	return response, nil
+}
+
+func unavailable(method string, err error) status.Status {
+	return status.NewErrorStatusWithCode(status.Unavailable, fmt.Errorf("call %s of the runtime plugin failed: %v", method, err))
+}
+
+// logWriter writes the output of a plugin to the log, and keeps the last line for error messages
+type logWriter struct {
+	plugin string
+
+	lock     sync.Mutex
+	lastLine string
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	lines := strings.Split(strings.TrimRight(string(p), "\n"), "\n")
+	for _, line := range lines {
+		log.Infof("[%s] %s", w.plugin, line)
+	}
+	w.lock.Lock()
+	w.lastLine = lines[len(lines)-1]
+	w.lock.Unlock()
+	return len(p), nil
+}
+
+func (w *logWriter) last() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.lastLine
+}
@@ -0,0 +1,145 @@
+// Package conformance verifies runtimes follow the semantics of runtime.Runtime expected by Kusion. Authors of runtime
+// plugins can run it in their tests against the runtime served over gRPC, like what the tests of package plugin do.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// Case is a resource to create and the desired state to update it to. Resource and Updated should have the same ID
+// and should not exist in the actual infrastructure before the test
+type Case struct {
+	Resource *models.Resource
+	Updated  *models.Resource
+	Stack    *projectstack.Stack
+}
+
+// Run creates, reads, imports, updates and deletes the resource of the case with the runtime. Attributes read from
+// the runtime should contain all attributes applied, while runtimes are free to add other attributes
+func Run(t *testing.T, rt runtime.Runtime, c Case) {
+	ctx := context.Background()
+
+	t.Run("delete_not_found", func(t *testing.T) {
+		response := rt.Delete(ctx, &runtime.DeleteRequest{Resource: c.Resource, Stack: c.Stack})
+		assertSuccess(t, response.Status)
+		assertNotFound(t, rt, c.Resource, c.Stack)
+	})
+
+	t.Run("dry_run", func(t *testing.T) {
+		response := rt.Apply(ctx, &runtime.ApplyRequest{PlanResource: c.Resource, Stack: c.Stack, DryRun: true})
+		if assertSuccess(t, response.Status) && assert.NotNil(t, response.Resource) {
+			assert.Equal(t, c.Resource.ID, response.Resource.ID)
+		}
+		assertNotFound(t, rt, c.Resource, c.Stack)
+	})
+
+	var created *models.Resource
+	t.Run("create", func(t *testing.T) {
+		response := rt.Apply(ctx, &runtime.ApplyRequest{PlanResource: c.Resource, Stack: c.Stack})
+		if assertSuccess(t, response.Status) && assert.NotNil(t, response.Resource) {
+			created = response.Resource
+			assertContains(t, created, c.Resource)
+		}
+		assertLive(t, rt, c.Resource, c.Stack)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		response := rt.Import(ctx, &runtime.ImportRequest{PlanResource: c.Resource, Stack: c.Stack})
+		if assertSuccess(t, response.Status) && assert.NotNil(t, response.Resource) {
+			assert.Equal(t, c.Resource.ID, response.Resource.ID)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		response := rt.Apply(ctx, &runtime.ApplyRequest{PriorResource: created, PlanResource: c.Updated, Stack: c.Stack})
+		if assertSuccess(t, response.Status) && assert.NotNil(t, response.Resource) {
+			assertContains(t, response.Resource, c.Updated)
+		}
+		assertLive(t, rt, c.Updated, c.Stack)
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		// watching is optional
+		if response := rt.Watch(watchCtx, &runtime.WatchRequest{Resource: c.Updated, Stack: c.Stack}); response != nil {
+			assertSuccess(t, response.Status)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		response := rt.Delete(ctx, &runtime.DeleteRequest{Resource: c.Updated, Stack: c.Stack})
+		assertSuccess(t, response.Status)
+		assertNotFound(t, rt, c.Updated, c.Stack)
+	})
+}
+
+func assertSuccess(t *testing.T, s status.Status) bool {
+	t.Helper()
+	if status.IsErr(s) {
+		return assert.Fail(t, "unexpected error status", s.String())
+	}
+	return true
+}
+
+// assertNotFound asserts the runtime reads a nil resource without errors
+func assertNotFound(t *testing.T, rt runtime.Runtime, resource *models.Resource, stack *projectstack.Stack) {
+	t.Helper()
+	response := rt.Read(context.Background(), &runtime.ReadRequest{PlanResource: resource, Stack: stack})
+	if assertSuccess(t, response.Status) {
+		assert.Nil(t, response.Resource)
+	}
+}
+
+// assertLive asserts the runtime reads the live resource which contains attributes of the resource
+func assertLive(t *testing.T, rt runtime.Runtime, resource *models.Resource, stack *projectstack.Stack) {
+	t.Helper()
+	response := rt.Read(context.Background(), &runtime.ReadRequest{PlanResource: resource, Stack: stack})
+	if assertSuccess(t, response.Status) && assert.NotNil(t, response.Resource) {
+		assertContains(t, response.Resource, resource)
+	}
+}
+
+func assertContains(t *testing.T, actual, expected *models.Resource) {
+	t.Helper()
+	assert.Equal(t, expected.ID, actual.ID)
+	// compare in the JSON form, since numbers may be decoded as other types by runtimes
+	if !contains(normalize(actual.Attributes), normalize(expected.Attributes)) {
+		assert.Fail(t, "attributes are not applied", "expected: %v\nactual: %v", expected.Attributes, actual.Attributes)
+	}
+}
+
+func normalize(v interface{}) interface{} {
+	data, _ := json.Marshal(v)
+	var out interface{}
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+// contains returns whether actual contains all fields of expected recursively
+func contains(actual, expected interface{}) bool {
+	expectedMap, ok := expected.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(actual, expected)
+	}
+	actualMap, ok := actual.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for k, v := range expectedMap {
+		if !contains(actualMap[k], v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/util/kfile"
+)
+
+const (
+	// PluginsDir is the directory of plugins under the Kusion data folder
+	PluginsDir = "plugins"
+
+	// PluginPrefix is the prefix of executable names of plugins, which is followed by the lowercase resource type
+	PluginPrefix = "kusion-runtime-"
+)
+
+// Dir returns the directory of plugins
+func Dir() (string, error) {
+	kusionDataDir, err := kfile.KusionDataFolder()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(kusionDataDir, PluginsDir), nil
+}
+
+// Lookup returns the path of the plugin which manages resources of type t, or an empty path if there is no such plugin
+func Lookup(t models.Type) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, PluginPrefix+strings.ToLower(string(t)))
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		return "", fmt.Errorf("runtime plugin %s is not executable", path)
+	}
+	return path, nil
+}
+
+// Discover returns lowercase resource types of all plugins in the plugins directory
+func Discover() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var types []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), PluginPrefix) {
+			types = append(types, strings.TrimPrefix(entry.Name(), PluginPrefix))
+		}
+	}
+	sort.Strings(types)
+	return types, nil
+}
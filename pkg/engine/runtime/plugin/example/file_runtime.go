@@ -0,0 +1,124 @@
+// Package example contains a runtime which manages local files, as an example of runtime plugins
+package example
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// File is the resource type managed by FileRuntime
+const File models.Type = "File"
+
+const (
+	// PathAttribute is the path of the file, relative paths are relative to the stack directory
+	PathAttribute = "path"
+
+	// ContentAttribute is the content of the file
+	ContentAttribute = "content"
+)
+
+var _ runtime.Runtime = &FileRuntime{}
+
+// FileRuntime manages local files. Attributes of File resources are the path and the content of files
+type FileRuntime struct{}
+
+func NewFileRuntime() (runtime.Runtime, error) {
+	return &FileRuntime{}, nil
+}
+
+func (f *FileRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	path, err := filePath(request.PlanResource, request.Stack)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	content, _ := request.PlanResource.Attributes[ContentAttribute].(string)
+	if !request.DryRun {
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err == nil {
+			err = os.WriteFile(path, []byte(content), 0o644)
+		}
+		if err != nil {
+			return &runtime.ApplyResponse{Status: status.NewErrorStatus(err)}
+		}
+	}
+	return &runtime.ApplyResponse{Resource: fileResource(request.PlanResource, content)}
+}
+
+// Read returns a nil Resource if the file doesn't exist
+func (f *FileRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	resource := request.PlanResource
+	if resource == nil {
+		resource = request.PriorResource
+	}
+	path, err := filePath(resource, request.Stack)
+	if err != nil {
+		return &runtime.ReadResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &runtime.ReadResponse{}
+	}
+	if err != nil {
+		return &runtime.ReadResponse{Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.ReadResponse{Resource: fileResource(resource, string(content))}
+}
+
+func (f *FileRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
+	response := f.Read(ctx, &runtime.ReadRequest{PlanResource: request.PlanResource, Stack: request.Stack})
+	if status.IsErr(response.Status) {
+		return &runtime.ImportResponse{Status: response.Status}
+	}
+	if response.Resource == nil {
+		return &runtime.ImportResponse{Status: status.NewErrorStatusWithCode(status.NotFound,
+			fmt.Errorf("file of resource %s is not found", request.PlanResource.ResourceKey()))}
+	}
+	return &runtime.ImportResponse{Resource: response.Resource}
+}
+
+func (f *FileRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	path, err := filePath(request.Resource, request.Stack)
+	if err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatusWithCode(status.IllegalManifest, err)}
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.DeleteResponse{}
+}
+
+// Watch is not supported, files are ready once written
+func (f *FileRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	return nil
+}
+
+func filePath(resource *models.Resource, stack *projectstack.Stack) (string, error) {
+	path, ok := resource.Attributes[PathAttribute].(string)
+	if !ok || path == "" {
+		return "", fmt.Errorf("the %s attribute of resource %s should be a non-empty string", PathAttribute, resource.ResourceKey())
+	}
+	if !filepath.IsAbs(path) && stack != nil {
+		path = filepath.Join(stack.Path, path)
+	}
+	return path, nil
+}
+
+func fileResource(resource *models.Resource, content string) *models.Resource {
+	return &models.Resource{
+		ID:   resource.ID,
+		Type: resource.Type,
+		Attributes: map[string]interface{}{
+			PathAttribute:    resource.Attributes[PathAttribute],
+			ContentAttribute: content,
+		},
+		DependsOn:  resource.DependsOn,
+		Extensions: resource.Extensions,
+	}
+}
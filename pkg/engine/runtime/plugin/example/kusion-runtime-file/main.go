@@ -0,0 +1,20 @@
+// kusion-runtime-file is a runtime plugin which manages File resources, install it by:
+//
+//	go build -o $KUSION_PATH/plugins/kusion-runtime-file ./pkg/engine/runtime/plugin/example/kusion-runtime-file
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin/example"
+)
+
+func main() {
+	rt, _ := example.NewFileRuntime()
+	if err := plugin.Serve(rt); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
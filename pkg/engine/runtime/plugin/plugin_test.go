@@ -0,0 +1,195 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin/conformance"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin/example"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/kfile"
+)
+
+// TestMain serves the example runtime when the test binary is launched as a plugin
+func TestMain(m *testing.M) {
+	if os.Getenv(EnvPluginSocket) != "" {
+		rt, _ := example.NewFileRuntime()
+		if err := Serve(rt); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLaunch_Conformance(t *testing.T) {
+	rt, err := Launch(os.Args[0])
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		assert.NoError(t, rt.Close())
+	}()
+
+	newFile := func(content string) *models.Resource {
+		return &models.Resource{ID: "hello", Type: example.File, Attributes: map[string]interface{}{
+			example.PathAttribute:    "hello.txt",
+			example.ContentAttribute: content,
+		}}
+	}
+	conformance.Run(t, rt, conformance.Case{
+		Resource: newFile("hello"),
+		Updated:  newFile("hello world"),
+		Stack:    &projectstack.Stack{Path: t.TempDir()},
+	})
+}
+
+func TestLaunch_Failed(t *testing.T) {
+	_, err := Launch(filepath.Join(t.TempDir(), "not-exist"))
+	assert.Error(t, err)
+
+	// the plugin exits without serving
+	script := filepath.Join(t.TempDir(), "kusion-runtime-broken")
+	assert.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho broken >&2\nexit 1\n"), 0o755))
+	_, err = Launch(script)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "broken")
+	}
+}
+
+// fakeWatchRuntime sends events in watch and closes the watcher
+type fakeWatchRuntime struct {
+	runtime.Runtime
+	status status.Status
+	events []k8swatch.Event
+}
+
+func (f *fakeWatchRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	if f.status != nil {
+		return &runtime.WatchResponse{Status: f.status}
+	}
+	if f.events == nil {
+		return nil
+	}
+	ch := make(chan k8swatch.Event, len(f.events))
+	for _, e := range f.events {
+		ch <- e
+	}
+	close(ch)
+	watchers := runtime.NewWatchers()
+	watchers.Insert("v1:Pod:default:nginx", ch)
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+func TestGRPCRuntime_Watch(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "nginx", "namespace": "default"},
+	}}
+	tests := []struct {
+		name       string
+		runtime    *fakeWatchRuntime
+		wantNil    bool
+		wantErr    bool
+		wantEvents []k8swatch.Event
+	}{
+		{
+			name: "events",
+			runtime: &fakeWatchRuntime{events: []k8swatch.Event{
+				{Type: k8swatch.Added, Object: pod},
+				{Type: k8swatch.Deleted, Object: pod},
+			}},
+			wantEvents: []k8swatch.Event{
+				{Type: k8swatch.Added, Object: pod},
+				{Type: k8swatch.Deleted, Object: pod},
+			},
+		},
+		{
+			name:    "unsupported",
+			runtime: &fakeWatchRuntime{},
+			wantNil: true,
+		},
+		{
+			name:    "error",
+			runtime: &fakeWatchRuntime{status: status.NewErrorStatusWithMsg(status.NotFound, "not found")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := serveInProcess(t, tt.runtime)
+			response := rt.Watch(context.Background(), &runtime.WatchRequest{Resource: &models.Resource{ID: "nginx"}})
+			if tt.wantNil {
+				assert.Nil(t, response)
+				return
+			}
+			assert.Equal(t, tt.wantErr, status.IsErr(response.Status))
+			if tt.wantErr {
+				return
+			}
+
+			assert.Equal(t, []string{"v1:Pod:default:nginx"}, response.Watchers.IDs)
+			var events []k8swatch.Event
+			for e := range response.Watchers.Watchers[0] {
+				events = append(events, e)
+			}
+			assert.Equal(t, tt.wantEvents, events)
+		})
+	}
+}
+
+func serveInProcess(t *testing.T, rt runtime.Runtime) *GRPCRuntime {
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	Register(server, rt)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return NewGRPCRuntime(conn)
+}
+
+func TestLookup(t *testing.T) {
+	t.Setenv(kfile.EnvKusionPath, t.TempDir())
+	dir, err := Dir()
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "kusion-runtime-file"), []byte("#!/bin/sh\n"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "kusion-runtime-helm"), []byte("#!/bin/sh\n"), 0o644))
+
+	path, err := Lookup(example.File)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "kusion-runtime-file"), path)
+
+	_, err = Lookup("Helm")
+	assert.Error(t, err)
+
+	path, err = Lookup("Exec")
+	assert.NoError(t, err)
+	assert.Empty(t, path)
+
+	types, err := Discover()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file", "helm"}, types)
+}
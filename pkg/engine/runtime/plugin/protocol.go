@@ -0,0 +1,92 @@
+// Package plugin serves runtimes implemented in external binaries over gRPC, so that Kusion can orchestrate new kinds
+// of infrastructure without being forked.
+//
+// A plugin is an executable named "kusion-runtime-<type>" in the plugins directory under the Kusion data folder,
+// e.g. $KUSION_PATH/plugins/kusion-runtime-file manages resources of type "File". Kusion launches the plugin with the
+// path of a unix socket in the EnvPluginSocket environment variable, and the plugin serves a runtime.Runtime on it by
+// calling Serve. The plugin should exit when its stdin is closed, which is done by Serve.
+//
+// Messages are encoded in JSON, so that plugins don't depend on generated code and the request types in package
+// runtime are the messages on the wire.
+package plugin
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const (
+	// EnvPluginSocket is the environment variable which tells the plugin where to listen
+	EnvPluginSocket = "KUSION_PLUGIN_SOCKET"
+
+	// ServiceName is the full name of the gRPC service served by plugins
+	ServiceName = "kusion.runtime.v1.Runtime"
+
+	codecName = "json"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec encodes gRPC messages in JSON
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+// Status is the status.Status on the wire
+type Status struct {
+	Kind    status.Kind `json:"kind"`
+	Code    status.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+func fromStatus(s status.Status) *Status {
+	if s == nil {
+		return nil
+	}
+	return &Status{Kind: s.Kind(), Code: s.Code(), Message: s.Message()}
+}
+
+func (s *Status) toStatus() status.Status {
+	if s == nil {
+		return nil
+	}
+	return status.NewBaseStatus(s.Kind, s.Code, s.Message)
+}
+
+// ResourceResponse is the response of Apply, Read, Import and Delete
+type ResourceResponse struct {
+	Resource *models.Resource `json:"resource,omitempty"`
+	Status   *Status          `json:"status,omitempty"`
+}
+
+// WatchMessage is a message in the stream of Watch. The first message contains IDs of all watchers or the Status of
+// the failure, and the following messages contain events sent by watchers
+type WatchMessage struct {
+	IDs    []string    `json:"ids,omitempty"`
+	Status *Status     `json:"status,omitempty"`
+	Event  *WatchEvent `json:"event,omitempty"`
+}
+
+// WatchEvent is an event sent by the watcher at Index of the IDs
+type WatchEvent struct {
+	Index  int                    `json:"index"`
+	Type   k8swatch.EventType     `json:"type"`
+	Object map[string]interface{} `json:"object"`
+}
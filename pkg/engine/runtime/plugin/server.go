@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*runtime.Runtime)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Apply",
+			Handler: unaryHandler("Apply", func() interface{} { return &runtime.ApplyRequest{} },
+				func(ctx context.Context, rt runtime.Runtime, in interface{}) *ResourceResponse {
+					response := rt.Apply(ctx, in.(*runtime.ApplyRequest))
+					return &ResourceResponse{Resource: response.Resource, Status: fromStatus(response.Status)}
+				}),
+		},
+		{
+			MethodName: "Read",
+			Handler: unaryHandler("Read", func() interface{} { return &runtime.ReadRequest{} },
+				func(ctx context.Context, rt runtime.Runtime, in interface{}) *ResourceResponse {
+					response := rt.Read(ctx, in.(*runtime.ReadRequest))
+					return &ResourceResponse{Resource: response.Resource, Status: fromStatus(response.Status)}
+				}),
+		},
+		{
+			MethodName: "Import",
+			Handler: unaryHandler("Import", func() interface{} { return &runtime.ImportRequest{} },
+				func(ctx context.Context, rt runtime.Runtime, in interface{}) *ResourceResponse {
+					response := rt.Import(ctx, in.(*runtime.ImportRequest))
+					return &ResourceResponse{Resource: response.Resource, Status: fromStatus(response.Status)}
+				}),
+		},
+		{
+			MethodName: "Delete",
+			Handler: unaryHandler("Delete", func() interface{} { return &runtime.DeleteRequest{} },
+				func(ctx context.Context, rt runtime.Runtime, in interface{}) *ResourceResponse {
+					response := rt.Delete(ctx, in.(*runtime.DeleteRequest))
+					return &ResourceResponse{Status: fromStatus(response.Status)}
+				}),
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       watchHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+// Serve serves the runtime on the socket specified by Kusion, and returns after Kusion closes the stdin of the plugin.
+// It is supposed to be called in the main function of plugins
+func Serve(rt runtime.Runtime) error {
+	socket := os.Getenv(EnvPluginSocket)
+	if socket == "" {
+		return fmt.Errorf("%s is not set, the plugin should be launched by Kusion", EnvPluginSocket)
+	}
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	Register(server, rt)
+	go func() {
+		// Kusion closes the stdin when it is done with the plugin or exits
+		_, _ = io.Copy(io.Discard, os.Stdin)
+		server.Stop()
+	}()
+	return server.Serve(lis)
+}
+
+// Register registers the runtime to the gRPC server
+func Register(server *grpc.Server, rt runtime.Runtime) {
+	server.RegisterService(&serviceDesc, rt)
+}
+
+func unaryHandler(
+	method string,
+	newRequest func() interface{},
+	call func(ctx context.Context, rt runtime.Runtime, in interface{}) *ResourceResponse,
+) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := newRequest()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, in interface{}) (interface{}, error) {
+			return call(ctx, srv.(runtime.Runtime), in), nil
+		}
+		if interceptor == nil {
+			return handler(ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod(method)}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+// watchHandler sends IDs of watchers in the first message, and then events of all watchers until they are closed
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := &runtime.WatchRequest{}
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	response := srv.(runtime.Runtime).Watch(ctx, in)
+	if response == nil {
+		return grpcstatus.Error(codes.Unimplemented, "watch is not supported by this runtime")
+	}
+
+	first := &WatchMessage{Status: fromStatus(response.Status)}
+	if response.Watchers != nil {
+		first.IDs = response.Watchers.IDs
+	}
+	if err := stream.SendMsg(first); err != nil {
+		return err
+	}
+	if status.IsErr(response.Status) || response.Watchers == nil {
+		return nil
+	}
+
+	cases := make([]reflect.SelectCase, 0, len(response.Watchers.Watchers)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, ch := range response.Watchers.Watchers {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	for open := len(response.Watchers.Watchers); open > 0; {
+		chosen, recv, recvOK := reflect.Select(cases)
+		if chosen == 0 {
+			return ctx.Err()
+		}
+		if !recvOK {
+			cases[chosen].Chan = reflect.ValueOf((<-chan k8swatch.Event)(nil))
+			open--
+			continue
+		}
+
+		e := recv.Interface().(k8swatch.Event)
+		obj, err := k8sruntime.DefaultUnstructuredConverter.ToUnstructured(e.Object)
+		if err != nil {
+			return err
+		}
+		if err = stream.SendMsg(&WatchMessage{Event: &WatchEvent{Index: chosen - 1, Type: e.Type, Object: obj}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fullMethod(method string) string {
+	return "/" + ServiceName + "/" + method
+}
@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"strings"
+
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// Retrier is an optional interface of runtimes whose calls are safe to repeat. Failed calls of runtimes not implementing
+// it are never retried, since they may run user commands which are not idempotent
+type Retrier interface {
+	// Retryable returns whether the call failed with the status may succeed if retried. mutation is true for calls which
+	// may change resources, i.e. Apply except dry runs, Delete and Import
+	Retryable(s status.Status, mutation bool) bool
+}
+
+// unstartedStatus is the status of a call which failed before it started to change resources
+type unstartedStatus struct {
+	status.Status
+}
+
+// Unstarted marks the status of a call which failed before it started to change resources, so that retrying the call
+// can't repeat changes made by the failed attempt
+func Unstarted(s status.Status) status.Status {
+	if s == nil {
+		return nil
+	}
+	return unstartedStatus{Status: s}
+}
+
+// IsUnstarted returns whether the status is marked by Unstarted
+func IsUnstarted(s status.Status) bool {
+	_, ok := s.(unstartedStatus)
+	return ok
+}
+
+// TransientPatterns are lowercase fragments of error messages of transient failures reported by API servers, since
+// runtimes report most errors of API servers without specific status codes
+var TransientPatterns = []string{
+	"too many requests",
+	"rate limit",
+	"ratelimit",
+	"throttl",
+	"timeout",
+	"timed out",
+	"deadline exceeded",
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"the server is currently unable to handle the request",
+	"service unavailable",
+	"an error on the server",
+	"internal error occurred",
+	"the object has been modified",
+}
+
+// IsTransient returns whether the status has a transient code, or a message containing any of patterns if it has no
+// specific code
+func IsTransient(s status.Status, patterns []string) bool {
+	switch s.Code() {
+	case status.Unavailable, status.DeadlineExceeded:
+		return true
+	case status.Canceled, status.InvalidArgument, status.IllegalManifest, status.Unimplemented, status.NotFound,
+		status.AlreadyExists, status.PermissionDenied, status.Unauthenticated, status.Conflict:
+		return false
+	}
+	msg := strings.ToLower(s.Message())
+	for _, pattern := range patterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
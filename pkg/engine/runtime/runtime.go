@@ -13,6 +13,8 @@ import (
 const (
 	Kubernetes models.Type = "Kubernetes"
 	Terraform  models.Type = "Terraform"
+	Helm       models.Type = "Helm"
+	Exec       models.Type = "Exec"
 )
 
 // Runtime represents an actual infrastructure runtime managed by Kusion and every runtime implements this interface can be orchestrated
@@ -54,6 +56,13 @@ type ApplyRequest struct {
 
 	// DryRun means this a dry-run request and will not make any changes in actual infra
 	DryRun bool
+
+	// ServerSideApply means the PlanResource is merged by the actual infra instead of a patch computed by the runtime,
+	// like the Kubernetes server-side apply. Runtimes don't support it will ignore this field
+	ServerSideApply bool
+
+	// ForceConflicts means to take the ownership of fields managed by others in the server-side apply
+	ForceConflicts bool
 }
 
 type ApplyResponse struct {
@@ -115,6 +124,9 @@ type DeleteResponse struct {
 type WatchRequest struct {
 	// Resource represents the resource we want to watch from the actual infra
 	Resource *models.Resource
+
+	// Stack contains info about where this command is invoked
+	Stack *projectstack.Stack
 }
 
 type WatchResponse struct {
@@ -2,9 +2,10 @@ package terraform
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
-	"sync"
+	"strconv"
 
 	"github.com/spf13/afero"
 
@@ -12,56 +13,115 @@ import (
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
 )
 
-var _ runtime.Runtime = &TerraformRuntime{}
+var (
+	_ runtime.Runtime = &TerraformRuntime{}
+	_ runtime.Retrier = &TerraformRuntime{}
+)
+
+// ImportIDExtension is the key of the extension which specifies the import id of an existing infrastructure object,
+// so that it can be adopted by kusion instead of being created. The object is imported right before the resource is
+// applied for the first time, since reading resources in previews must not change the workspace, and the resource is
+// shown as created in previews
+const ImportIDExtension = "importID"
+
+// EnvParallelism is the environment variable which limits the number of Terraform resources operated concurrently
+const EnvParallelism = "KUSION_TERRAFORM_PARALLELISM"
 
+// defaultParallelism is the same as the default parallelism of terraform apply
+const defaultParallelism = 10
+
+// TerraformRuntime operates every Terraform resource in its own workspace, so that independent resources can be
+// planned and applied in parallel. The number of resources operated concurrently is limited by EnvParallelism
 type TerraformRuntime struct {
-	tfops.WorkSpace
-	mu *sync.Mutex
+	fs afero.Afero
+	// slots limits the number of workspaces in use concurrently
+	slots chan struct{}
 }
 
 func NewTerraformRuntime() (runtime.Runtime, error) {
-	fs := afero.Afero{Fs: afero.NewOsFs()}
-	ws := tfops.NewWorkSpace(fs)
-	TFRuntime := &TerraformRuntime{
-		WorkSpace: *ws,
-		mu:        &sync.Mutex{},
+	parallelism := defaultParallelism
+	if v := os.Getenv(EnvParallelism); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p <= 0 {
+			return nil, fmt.Errorf("invalid %s: %s, it should be a positive integer", EnvParallelism, v)
+		}
+		parallelism = p
 	}
-	return TFRuntime, nil
+	return newTerraformRuntime(afero.Afero{Fs: afero.NewOsFs()}, parallelism), nil
 }
 
-// Apply Terraform resource
-func (t *TerraformRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+func newTerraformRuntime(fs afero.Afero, parallelism int) *TerraformRuntime {
+	return &TerraformRuntime{
+		fs:    fs,
+		slots: make(chan struct{}, parallelism),
+	}
+}
 
-	plan := request.PlanResource
-	stackPath := request.Stack.GetPath()
-	tfCacheDir := filepath.Join(stackPath, "."+plan.ResourceKey())
-	t.WorkSpace.SetStackDir(stackPath)
-	t.WorkSpace.SetCacheDir(tfCacheDir)
-	t.WorkSpace.SetResource(plan)
+// acquireWorkSpace waits for a free slot and returns a workspace of the resource in the stack. The returned release
+// func must be called after the workspace is no longer used
+func (t *TerraformRuntime) acquireWorkSpace(
+	ctx context.Context,
+	stack *projectstack.Stack,
+	resource *models.Resource,
+) (*tfops.WorkSpace, func(), error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
 
-	if err := t.WorkSpace.WriteHCL(); err != nil {
-		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	stackPath := stack.GetPath()
+	ws := tfops.NewWorkSpace(t.fs)
+	ws.SetStackDir(stackPath)
+	ws.SetCacheDir(filepath.Join(stackPath, "."+resource.ResourceKey()))
+	ws.SetResource(resource)
+	return ws, func() { <-t.slots }, nil
+}
+
+// prepareWorkSpace acquires the workspace of the resource, writes main.tf.json and initializes the workspace if needed
+func (t *TerraformRuntime) prepareWorkSpace(
+	ctx context.Context,
+	stack *projectstack.Stack,
+	resource *models.Resource,
+) (*tfops.WorkSpace, func(), error) {
+	ws, release, err := t.acquireWorkSpace(ctx, stack, resource)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	_, err := os.Stat(filepath.Join(tfCacheDir, tfops.LockHCLFile))
+	if err = ws.WriteHCL(); err != nil {
+		release()
+		return nil, nil, err
+	}
+	_, err = os.Stat(filepath.Join(stack.GetPath(), "."+resource.ResourceKey(), tfops.LockHCLFile))
 	if err != nil {
 		if os.IsNotExist(err) {
-			if err := t.WorkSpace.InitWorkSpace(ctx); err != nil {
-				return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
-			}
-		} else {
-			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+			err = ws.InitWorkSpace(ctx)
 		}
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+	}
+	return ws, release, nil
+}
+
+// Apply Terraform resource
+func (t *TerraformRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	plan := request.PlanResource
+	ws, release, err := t.prepareWorkSpace(ctx, request.Stack, plan)
+	if err != nil {
+		return &runtime.ApplyResponse{Resource: nil, Status: runtime.Unstarted(status.NewErrorStatus(err))}
 	}
+	defer release()
 
 	// dry run by terraform plan
 	if request.DryRun {
-		pr, err := t.WorkSpace.Plan(ctx)
+		pr, err := ws.Plan(ctx)
 		if err != nil {
 			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 		}
@@ -83,13 +143,22 @@ func (t *TerraformRuntime) Apply(ctx context.Context, request *runtime.ApplyRequ
 		}
 	}
 
-	tfstate, err := t.WorkSpace.Apply(ctx)
+	// adopt the existing infrastructure object which is not managed by kusion yet
+	if importID, ok := getImportID(plan); ok && request.PriorResource == nil {
+		if _, err = ws.Import(ctx, importID); err != nil {
+			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(
+				fmt.Errorf("import TF resource %s with import id %s failed: %v", plan.ID, importID, err))}
+		}
+		log.Infof("import TF resource %s with import id %s", plan.ID, importID)
+	}
+
+	tfstate, err := ws.Apply(ctx)
 	if err != nil {
 		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
 
 	// get terraform provider version
-	providerAddr, err := t.WorkSpace.GetProvider()
+	providerAddr, err := ws.GetProvider()
 	if err != nil {
 		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
@@ -130,38 +199,21 @@ func (t *TerraformRuntime) Read(ctx context.Context, request *runtime.ReadReques
 		}
 	}
 	if priorResource == nil {
+		// objects to import are imported in Apply, since terraform import changes the workspace
 		return &runtime.ReadResponse{Resource: nil, Status: nil}
 	}
-	var tfstate *tfops.StateRepresentation
-
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	stackPath := request.Stack.GetPath()
-	tfCacheDir := filepath.Join(stackPath, "."+planResource.ResourceKey())
-	t.WorkSpace.SetStackDir(stackPath)
-	t.WorkSpace.SetCacheDir(tfCacheDir)
-	t.WorkSpace.SetResource(planResource)
-
-	if err := t.WorkSpace.WriteHCL(); err != nil {
-		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
-	}
-	_, err := os.Stat(filepath.Join(tfCacheDir, tfops.LockHCLFile))
+	ws, release, err := t.prepareWorkSpace(ctx, request.Stack, planResource)
 	if err != nil {
-		if os.IsNotExist(err) {
-			if err := t.WorkSpace.InitWorkSpace(ctx); err != nil {
-				return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
-			}
-		} else {
-			return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
-		}
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
+	defer release()
 
 	// priorResource overwrite tfstate in workspace
-	if err = t.WorkSpace.WriteTFState(priorResource); err != nil {
+	if err = ws.WriteTFState(priorResource); err != nil {
 		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
 
-	tfstate, err = t.WorkSpace.RefreshOnly(ctx)
+	tfstate, err := ws.RefreshOnly(ctx)
 	if err != nil {
 		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
@@ -171,7 +223,7 @@ func (t *TerraformRuntime) Read(ctx context.Context, request *runtime.ReadReques
 	}
 
 	// get terraform provider addr
-	providerAddr, err := t.WorkSpace.GetProvider()
+	providerAddr, err := ws.GetProvider()
 	if err != nil {
 		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
 	}
@@ -189,35 +241,85 @@ func (t *TerraformRuntime) Read(ctx context.Context, request *runtime.ReadReques
 	}
 }
 
+// Import the existing infrastructure object identified by the importID extension of the resource with terraform import
 func (t *TerraformRuntime) Import(ctx context.Context, request *runtime.ImportRequest) *runtime.ImportResponse {
-	// TODO change to terraform cli import
-	log.Info("skip import TF resource:%s", request.PlanResource.ID)
-	return nil
+	plan := request.PlanResource
+	importID, ok := getImportID(plan)
+	if !ok {
+		return &runtime.ImportResponse{Status: status.NewErrorStatus(
+			fmt.Errorf("can not import TF resource %s without the %s extension", plan.ID, ImportIDExtension))}
+	}
+
+	ws, release, err := t.prepareWorkSpace(ctx, request.Stack, plan)
+	if err != nil {
+		return &runtime.ImportResponse{Resource: nil, Status: runtime.Unstarted(status.NewErrorStatus(err))}
+	}
+	defer release()
+
+	tfstate, err := ws.Import(ctx, importID)
+	if err != nil {
+		return &runtime.ImportResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	if tfstate == nil || tfstate.Values == nil {
+		return &runtime.ImportResponse{Resource: nil, Status: status.NewErrorStatus(
+			fmt.Errorf("no TF resource %s found with import id %s", plan.ID, importID))}
+	}
+
+	// get terraform provider addr
+	providerAddr, err := ws.GetProvider()
+	if err != nil {
+		return &runtime.ImportResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+
+	r := tfops.ConvertTFState(tfstate, providerAddr)
+	log.Infof("import TF resource %s with import id %s", plan.ID, importID)
+	return &runtime.ImportResponse{
+		Resource: &models.Resource{
+			ID:         plan.ID,
+			Type:       plan.Type,
+			Attributes: r.Attributes,
+			DependsOn:  plan.DependsOn,
+			Extensions: plan.Extensions,
+		},
+		Status: nil,
+	}
+}
+
+// getImportID returns the import id in extensions of the resource
+func getImportID(resource *models.Resource) (string, bool) {
+	if resource == nil {
+		return "", false
+	}
+	importID, ok := resource.Extensions[ImportIDExtension].(string)
+	return importID, ok && importID != ""
 }
 
 // Delete terraform resource and remove workspace
 func (t *TerraformRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) (res *runtime.DeleteResponse) {
-	stackPath := request.Stack.GetPath()
-	tfCacheDir := filepath.Join(stackPath, "."+request.Resource.ResourceKey())
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	t.WorkSpace.SetStackDir(stackPath)
-	t.WorkSpace.SetCacheDir(tfCacheDir)
-	t.WorkSpace.SetResource(request.Resource)
-	if err := t.WorkSpace.Destroy(ctx); err != nil {
+	ws, release, err := t.acquireWorkSpace(ctx, request.Stack, request.Resource)
+	if err != nil {
+		return &runtime.DeleteResponse{Status: runtime.Unstarted(status.NewErrorStatus(err))}
+	}
+	defer release()
+
+	if err = ws.Destroy(ctx); err != nil {
 		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
 	}
 
 	// delete tf directory after destroy operation is success
-	err := os.RemoveAll(tfCacheDir)
+	err = os.RemoveAll(filepath.Join(request.Stack.GetPath(), "."+request.Resource.ResourceKey()))
 	if err != nil {
 		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
 	}
 	return &runtime.DeleteResponse{Status: nil}
 }
 
-// Watch terraform resource
-func (t *TerraformRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
-	return nil
+// Retryable retries failures of providers calling cloud APIs, which are reported in the output of terraform. Mutations
+// are only retried if they failed before terraform started to change resources, since a terraform apply killed by the
+// timeout may have created objects without recording them in the tfstate, and retrying it creates them again
+func (t *TerraformRuntime) Retryable(s status.Status, mutation bool) bool {
+	if mutation && !runtime.IsUnstarted(s) {
+		return false
+	}
+	return runtime.IsTransient(s, runtime.TransientPatterns)
 }
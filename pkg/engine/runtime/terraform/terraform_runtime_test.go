@@ -3,11 +3,13 @@ package terraform
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"bou.ke/monkey"
 	"github.com/spf13/afero"
@@ -17,6 +19,7 @@ import (
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
 	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
 )
 
 var testResource = models.Resource{
@@ -45,10 +48,7 @@ func TestTerraformRuntime(t *testing.T) {
 		Path:               filepath.Join(cwd, "fakePath"),
 	}
 	defer os.RemoveAll(stack.GetPath())
-	tfRuntime := TerraformRuntime{
-		WorkSpace: *tfops.NewWorkSpace(afero.Afero{Fs: afero.NewOsFs()}),
-		mu:        &sync.Mutex{},
-	}
+	tfRuntime := newTerraformRuntime(afero.Afero{Fs: afero.NewOsFs()}, defaultParallelism)
 
 	t.Run("ApplyDryRun", func(t *testing.T) {
 		defer monkey.UnpatchAll()
@@ -90,6 +90,54 @@ func TestTerraformRuntime(t *testing.T) {
 		assert.Equalf(t, nil, response.Status, "Execute(%v)", "Read")
 	})
 
+	t.Run("Import", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+
+		mockImportSetup()
+
+		response := tfRuntime.Import(context.TODO(), &runtime.ImportRequest{PlanResource: importResource(), Stack: stack})
+		assert.Equalf(t, nil, response.Status, "Execute(%v)", "Import")
+		assert.Equal(t, "kusion", response.Resource.Attributes["content"])
+		assert.Equal(t, testResource.ID, response.Resource.ID)
+
+		// reading the resource doesn't import it
+		imported := 0
+		monkey.Patch((*tfops.WorkSpace).Import, func(ws *tfops.WorkSpace, ctx context.Context, importID string) (*tfops.StateRepresentation, error) {
+			imported++
+			return fakeSR, nil
+		})
+		readResponse := tfRuntime.Read(context.TODO(), &runtime.ReadRequest{PlanResource: importResource(), Stack: stack})
+		assert.Nil(t, readResponse.Status)
+		assert.Nil(t, readResponse.Resource)
+		assert.Equal(t, 0, imported)
+
+		// the existing object is adopted when the resource is applied for the first time
+		mockApplySetup()
+		applyResponse := tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{PlanResource: importResource(), Stack: stack})
+		assert.Nil(t, applyResponse.Status)
+		assert.Equal(t, 1, imported)
+		applyResponse = tfRuntime.Apply(context.TODO(), &runtime.ApplyRequest{
+			PriorResource: applyResponse.Resource,
+			PlanResource:  importResource(),
+			Stack:         stack,
+		})
+		assert.Nil(t, applyResponse.Status)
+		assert.Equal(t, 1, imported)
+	})
+
+	t.Run("ImportWithoutID", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+
+		mockImportSetup()
+
+		response := tfRuntime.Import(context.TODO(), &runtime.ImportRequest{PlanResource: &testResource, Stack: stack})
+		assert.NotNil(t, response.Status)
+
+		readResponse := tfRuntime.Read(context.TODO(), &runtime.ReadRequest{PlanResource: &testResource, Stack: stack})
+		assert.Nil(t, readResponse.Status)
+		assert.Nil(t, readResponse.Resource)
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		defer monkey.UnpatchAll()
 
@@ -117,3 +165,119 @@ func mockApplySetup() {
 		return "registry.terraform.io/hashicorp/local/2.2.3", nil
 	})
 }
+
+func TestNewTerraformRuntime(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism string
+		want        int
+		wantErr     bool
+	}{
+		{name: "default", parallelism: "", want: defaultParallelism},
+		{name: "custom", parallelism: "3", want: 3},
+		{name: "zero", parallelism: "0", wantErr: true},
+		{name: "invalid", parallelism: "many", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvParallelism, tt.parallelism)
+			rt, err := NewTerraformRuntime()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, cap(rt.(*TerraformRuntime).slots))
+		})
+	}
+}
+
+func TestTerraformRuntime_Parallelism(t *testing.T) {
+	defer monkey.UnpatchAll()
+
+	const parallelism = 2
+	var mu sync.Mutex
+	var running, maxRunning int
+	monkey.Patch((*tfops.WorkSpace).Destroy, func(ws *tfops.WorkSpace, ctx context.Context) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	stack := &projectstack.Stack{Path: t.TempDir()}
+	tfRuntime := newTerraformRuntime(afero.Afero{Fs: afero.NewOsFs()}, parallelism)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		r := testResource.DeepCopy()
+		r.ID = fmt.Sprintf("%s_%d", r.ID, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response := tfRuntime.Delete(context.TODO(), &runtime.DeleteRequest{Resource: r, Stack: stack})
+			assert.Nil(t, response.Status)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, parallelism, maxRunning)
+
+	// waiting for a free slot is canceled with the context
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	full := newTerraformRuntime(afero.Afero{Fs: afero.NewOsFs()}, 1)
+	full.slots <- struct{}{}
+	response := full.Delete(ctx, &runtime.DeleteRequest{Resource: &testResource, Stack: stack})
+	assert.NotNil(t, response.Status)
+}
+
+func importResource() *models.Resource {
+	r := testResource.DeepCopy()
+	r.Extensions[ImportIDExtension] = "test.txt"
+	return r
+}
+
+func mockImportSetup() {
+	monkey.Patch((*tfops.WorkSpace).InitWorkSpace, func(ws *tfops.WorkSpace, ctx context.Context) error {
+		return nil
+	})
+	monkey.Patch((*tfops.WorkSpace).Import, func(ws *tfops.WorkSpace, ctx context.Context, importID string) (*tfops.StateRepresentation, error) {
+		s := &tfops.StateRepresentation{}
+		err := json.Unmarshal([]byte(`{"values":{"root_module":{"resources":[{"address":"local_file.kusion_example",`+
+			`"type":"local_file","name":"kusion_example","values":{"content":"kusion","filename":"`+importID+`"}}]}}}`), s)
+		return s, err
+	})
+	monkey.Patch((*tfops.WorkSpace).GetProvider, func(ws *tfops.WorkSpace) (string, error) {
+		return "registry.terraform.io/hashicorp/local/2.2.3", nil
+	})
+}
+
+func TestTerraformRuntime_Retryable(t *testing.T) {
+	throttled := status.NewErrorStatus(errors.New("Error: creating RDS instance: Throttling: Rate exceeded"))
+	timedOut := status.NewErrorStatusWithCode(status.DeadlineExceeded, errors.New("apply timed out after 10m0s"))
+	tests := []struct {
+		name     string
+		s        status.Status
+		mutation bool
+		want     bool
+	}{
+		{name: "throttled read", s: throttled, want: true},
+		{name: "timed out dry run", s: timedOut, want: true},
+		{name: "throttled apply", s: throttled, mutation: true, want: false},
+		{name: "timed out apply", s: timedOut, mutation: true, want: false},
+		{name: "apply failed to init", s: runtime.Unstarted(status.NewErrorStatus(errors.New("connection reset by peer"))), mutation: true, want: true},
+		{name: "invalid config", s: runtime.Unstarted(status.NewErrorStatus(errors.New("Unsupported argument"))), mutation: true, want: false},
+	}
+	tfRuntime := newTerraformRuntime(afero.Afero{Fs: afero.NewMemMapFs()}, defaultParallelism)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tfRuntime.Retryable(tt.s, tt.mutation))
+		})
+	}
+}
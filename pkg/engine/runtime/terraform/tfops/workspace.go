@@ -10,6 +10,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
@@ -154,8 +155,15 @@ func (w *WorkSpace) WriteTFState(priorState *models.Resource) error {
 	return nil
 }
 
+// initLock serializes terraform init of all workspaces, since the shared provider plugin cache is not safe for
+// concurrent installations
+var initLock sync.Mutex
+
 // InitWorkSpace init terraform runtime workspace
 func (w *WorkSpace) InitWorkSpace(ctx context.Context) error {
+	initLock.Lock()
+	defer initLock.Unlock()
+
 	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
 	cmd := exec.CommandContext(ctx, "terraform", chdir, "init")
 	cmd.Dir = w.stackDir
@@ -321,6 +329,53 @@ func (w *WorkSpace) RefreshOnly(ctx context.Context) (*StateRepresentation, erro
 	return s, err
 }
 
+// Import imports the existing infrastructure object identified by importID into the workspace with the terraform cli
+// import command, and returns the imported Terraform State
+func (w *WorkSpace) Import(ctx context.Context, importID string) (*StateRepresentation, error) {
+	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
+	err := w.CleanAndInitWorkspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	address, err := w.resourceAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	// terraform refuses to import a resource which is already managed in the tfstate
+	if err = w.fs.Remove(filepath.Join(w.tfCacheDir, tfStateFile)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "terraform", chdir, "import", "-input=false", "-lock=false", address, importID)
+	cmd.Dir = w.stackDir
+	envs, err := w.initEnvs()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = envs
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, TFError(out)
+	}
+	s, err := w.ShowState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("terraform read state error: %v", err)
+	}
+	return s, err
+}
+
+// resourceAddress returns the address of the workspace resource in main.tf.json, e.g. local_file.kusion_example
+func (w *WorkSpace) resourceAddress() (string, error) {
+	resourceNames := strings.Split(w.resource.ResourceKey(), ":")
+	if len(resourceNames) < 4 {
+		return "", fmt.Errorf("illegial resource id:%s in Spec. "+
+			"Resource id format: providerNamespace:providerName:resourceType:resourceName", w.resource.ResourceKey())
+	}
+	return fmt.Sprintf("%s.%s", w.resource.Extensions["resourceType"].(string), resourceNames[len(resourceNames)-1]), nil
+}
+
 // Destroy make terraform destroy call.
 func (w *WorkSpace) Destroy(ctx context.Context) error {
 	chdir := fmt.Sprintf("-chdir=%s", w.tfCacheDir)
@@ -0,0 +1,163 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/printers/convertor"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// ReadinessExtension is the key of the extension which specifies the expected values of attributes when the resource
+// is ready, e.g. {"status": "Running"}. Nested attributes are separated by dots. A resource without readiness is ready
+// once it exists
+const ReadinessExtension = "readiness"
+
+// watchInterval is the interval of refreshing watched resources
+var watchInterval = 10 * time.Second
+
+// Watch Terraform resource by polling terraform apply -refresh-only on the workspace, until the resource is ready
+func (t *TerraformRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	if request == nil || request.Resource == nil {
+		return &runtime.WatchResponse{Status: status.NewErrorStatus(errors.New("requestResource is nil"))}
+	}
+	resource := request.Resource
+	readiness, err := getReadiness(resource)
+	if err != nil {
+		return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
+	}
+
+	obj := newWatchedObject(resource)
+	ch := make(chan k8swatch.Event, 1)
+	go func() {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		for first := true; ; first = false {
+			eventType := k8swatch.Modified
+			if first {
+				eventType = k8swatch.Added
+			}
+			watched := obj.DeepCopy()
+			attributes, err := t.refresh(ctx, request.Stack, resource)
+			var detail string
+			var ready bool
+			switch {
+			case err != nil:
+				detail = err.Error()
+				eventType = k8swatch.Error
+			case attributes == nil:
+				detail = "Not found"
+			default:
+				detail, ready = checkReadiness(attributes, readiness)
+			}
+			_ = unstructured.SetNestedField(watched.Object, detail, "status", "detail")
+			_ = unstructured.SetNestedField(watched.Object, ready, "status", "ready")
+
+			select {
+			case ch <- k8swatch.Event{Type: eventType, Object: watched}:
+			case <-ctx.Done():
+				return
+			}
+			if ready {
+				return
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	watchers := runtime.NewWatchers()
+	watchers.Insert(engine.BuildIDForKubernetes(obj), ch)
+	return &runtime.WatchResponse{Watchers: watchers}
+}
+
+// refresh returns the latest attributes of the resource applied in its workspace, nil if it is not applied yet
+func (t *TerraformRuntime) refresh(
+	ctx context.Context,
+	stack *projectstack.Stack,
+	resource *models.Resource,
+) (map[string]interface{}, error) {
+	ws, release, err := t.prepareWorkSpace(ctx, stack, resource)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tfstate, err := ws.RefreshOnly(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tfstate == nil || tfstate.Values == nil || len(tfstate.Values.RootModule.Resources) == 0 {
+		return nil, nil
+	}
+	return tfops.ConvertTFState(tfstate, "").Attributes, nil
+}
+
+// newWatchedObject returns the unstructured object which represents the resource in watch events
+func newWatchedObject(resource *models.Resource) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(convertor.TerraformGroupVersion.String())
+	resourceType, _ := resource.Extensions["resourceType"].(string)
+	obj.SetKind(resourceType)
+	names := strings.Split(resource.ResourceKey(), ":")
+	obj.SetName(names[len(names)-1])
+	return obj
+}
+
+// getReadiness returns the readiness extension of the resource
+func getReadiness(resource *models.Resource) (map[string]interface{}, error) {
+	v, ok := resource.Extensions[ReadinessExtension]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	readiness, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the %s extension of TF resource %s should be a map of attributes to their expected values",
+			ReadinessExtension, resource.ID)
+	}
+	return readiness, nil
+}
+
+// checkReadiness returns current values of attributes in readiness, and whether all of them are as expected
+func checkReadiness(attributes map[string]interface{}, readiness map[string]interface{}) (string, bool) {
+	if len(readiness) == 0 {
+		return "Created", true
+	}
+
+	keys := make([]string, 0, len(readiness))
+	for key := range readiness {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ready := true
+	details := make([]string, 0, len(keys))
+	for _, key := range keys {
+		actual, found, _ := unstructured.NestedFieldNoCopy(attributes, strings.Split(key, ".")...)
+		if !found || fmt.Sprint(actual) != fmt.Sprint(readiness[key]) {
+			ready = false
+		}
+		if !found {
+			actual = "<none>"
+		}
+		details = append(details, fmt.Sprintf("%s: %v", key, actual))
+	}
+	return strings.Join(details, ", "), ready
+}
@@ -0,0 +1,95 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"bou.ke/monkey"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+
+	"kusionstack.io/kusion/pkg/engine/printers"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+func TestTerraformRuntime_Watch(t *testing.T) {
+	defer monkey.UnpatchAll()
+	defer func(interval time.Duration) { watchInterval = interval }(watchInterval)
+	watchInterval = time.Millisecond
+
+	statuses := []string{"Pending", "Running"}
+	monkey.Patch((*tfops.WorkSpace).InitWorkSpace, func(ws *tfops.WorkSpace, ctx context.Context) error {
+		return nil
+	})
+	monkey.Patch((*tfops.WorkSpace).RefreshOnly, func(ws *tfops.WorkSpace, ctx context.Context) (*tfops.StateRepresentation, error) {
+		s := &tfops.StateRepresentation{}
+		err := json.Unmarshal([]byte(`{"values":{"root_module":{"resources":[{"type":"local_file","name":"kusion_example",`+
+			`"values":{"status":"`+statuses[0]+`"}}]}}}`), s)
+		statuses = statuses[1:]
+		return s, err
+	})
+
+	resource := testResource.DeepCopy()
+	resource.Extensions[ReadinessExtension] = map[string]interface{}{"status": "Running"}
+	tfRuntime := newTerraformRuntime(afero.Afero{Fs: afero.NewMemMapFs()}, defaultParallelism)
+	response := tfRuntime.Watch(context.TODO(), &runtime.WatchRequest{
+		Resource: resource,
+		Stack:    &projectstack.Stack{Path: t.TempDir()},
+	})
+	assert.Nil(t, response.Status)
+	assert.Equal(t, []string{"terraform.kusionstack.io/v1:local_file:kusion_example"}, response.Watchers.IDs)
+
+	ch := response.Watchers.Watchers[0]
+	tests := []struct {
+		eventType k8swatch.EventType
+		detail    string
+		ready     bool
+	}{
+		{eventType: k8swatch.Added, detail: "status: Pending", ready: false},
+		{eventType: k8swatch.Modified, detail: "status: Running", ready: true},
+	}
+	for _, tt := range tests {
+		select {
+		case e := <-ch:
+			assert.Equal(t, tt.eventType, e.Type)
+			detail, ready := printers.Generate(printers.Convert(e.Object.(*unstructured.Unstructured)))
+			assert.Equal(t, tt.detail, detail)
+			assert.Equal(t, tt.ready, ready)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for watch event")
+		}
+	}
+}
+
+func Test_checkReadiness(t *testing.T) {
+	attributes := map[string]interface{}{
+		"status": "Running",
+		"network": map[string]interface{}{
+			"ready": true,
+		},
+	}
+	tests := []struct {
+		name       string
+		readiness  map[string]interface{}
+		wantDetail string
+		wantReady  bool
+	}{
+		{name: "no_readiness", readiness: nil, wantDetail: "Created", wantReady: true},
+		{name: "ready", readiness: map[string]interface{}{"status": "Running", "network.ready": true}, wantDetail: "network.ready: true, status: Running", wantReady: true},
+		{name: "not_ready", readiness: map[string]interface{}{"status": "Stopped"}, wantDetail: "status: Running", wantReady: false},
+		{name: "missing", readiness: map[string]interface{}{"phase": "Running"}, wantDetail: "phase: <none>", wantReady: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, ready := checkReadiness(attributes, tt.readiness)
+			assert.Equal(t, tt.wantDetail, detail)
+			assert.Equal(t, tt.wantReady, ready)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package states
+
+import "fmt"
+
+// SerialConflictError is returned by StateStorage.Apply when the Serial of the State to apply doesn't follow the
+// Serial of the latest State in the storage, which means the State has been modified by others in the meantime
+type SerialConflictError struct {
+	// LatestSerial is the Serial of the latest State in the storage
+	LatestSerial uint64
+
+	// Serial is the Serial of the State to apply
+	Serial uint64
+}
+
+func (e *SerialConflictError) Error() string {
+	return fmt.Sprintf("state serial conflict: the latest serial is %d but the serial to apply is %d, "+
+		"the State may have been modified by others", e.LatestSerial, e.Serial)
+}
+
+// CheckSerial returns a SerialConflictError if serial is not the next Serial of the latest State.
+// A nil latest means there is no State in the storage and the first Serial is 1
+func CheckSerial(latest *State, serial uint64) error {
+	var latestSerial uint64
+	if latest != nil {
+		latestSerial = latest.Serial
+	}
+	if serial != latestSerial+1 {
+		return &SerialConflictError{LatestSerial: latestSerial, Serial: serial}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/vals"
+)
+
+// Config is the encryption config in the backend block of project.yaml. Exactly one of PassphraseEnv, KeyFile
+// and VaultTransitKey should be specified.
+//
+// Example:
+//
+//	backend:
+//	  storageType: s3
+//	  config:
+//	    ...
+//	  encryption:
+//	    passphraseEnv: KUSION_STATE_PASSPHRASE
+type Config struct {
+	// PassphraseEnv is the name of the environment variable holding the passphrase
+	PassphraseEnv string `json:"passphraseEnv,omitempty" yaml:"passphraseEnv,omitempty"`
+
+	// KeyFile is the path of the file holding a 32-byte key, either raw or encoded in base64.
+	// A relative path is relative to the work directory
+	KeyFile string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+
+	// VaultTransitKey is the name of the key in the Vault transit secrets engine. The Vault server
+	// is configured in secret_stores.vault of project.yaml
+	VaultTransitKey string `json:"vaultTransitKey,omitempty" yaml:"vaultTransitKey,omitempty"`
+
+	// VaultTransitMount is the mount path of the Vault transit secrets engine, default to transit
+	VaultTransitMount string `json:"vaultTransitMount,omitempty" yaml:"vaultTransitMount,omitempty"`
+}
+
+const defaultVaultTransitMount = "transit"
+
+// NewEncrypter returns the Encrypter described by config, dir is the work directory and ss is the secret stores
+// of the project. A nil Encrypter is returned if config is nil, which means encryption is disabled
+func NewEncrypter(config *Config, dir string, ss *vals.SecretStores) (Encrypter, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	var provider keyProvider
+	configured := 0
+	if config.PassphraseEnv != "" {
+		configured++
+		passphrase := os.Getenv(config.PassphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("passphraseEnv configured to read the passphrase from env %s, but it isn't set", config.PassphraseEnv)
+		}
+		provider = newPassphraseKey(passphrase)
+	}
+	if config.KeyFile != "" {
+		configured++
+		key, err := readKeyFile(config.KeyFile, dir)
+		if err != nil {
+			return nil, err
+		}
+		provider = &staticKey{key: key}
+	}
+	if config.VaultTransitKey != "" {
+		configured++
+		if ss == nil || ss.Vault == nil {
+			return nil, errors.New("vaultTransitKey is configured, but secret_stores.vault is not configured in the project")
+		}
+		client, err := ss.Vault.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		mount := config.VaultTransitMount
+		if mount == "" {
+			mount = defaultVaultTransitMount
+		}
+		provider = newVaultTransitKey(client, mount, config.VaultTransitKey)
+	}
+
+	if configured != 1 {
+		return nil, errors.New("exactly one of passphraseEnv, keyFile and vaultTransitKey should be configured in backend encryption")
+	}
+	return &aesGCM{provider: provider}, nil
+}
+
+// readKeyFile reads a 32-byte key for AES-256 from the file, the key can be either raw or encoded in base64
+func readKeyFile(path, dir string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read encryption key file failed: %v", err)
+	}
+	if len(content) == keySize {
+		return content, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("encryption key file %s should contain a %d-byte key, either raw or encoded in base64", path, keySize)
+	}
+	return key, nil
+}
@@ -0,0 +1,128 @@
+package encryption
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/log"
+)
+
+// ErrEncryptedState is returned when an encrypted State is read without encryption configured
+var ErrEncryptedState = errors.New("the State is encrypted, please configure encryption in the backend block of project.yaml")
+
+var _ states.StateStorage = &EncryptedState{}
+
+// EncryptedState wraps a StateStorage to encrypt Resources of States before they are stored, and decrypt them after
+// they are read. Other fields of States are kept in plaintext, so that the wrapped StateStorage can query them as usual.
+//
+// If the Encrypter is nil, encryption is disabled and an error is returned when reading an encrypted State instead of
+// treating it as a State without resources. If the Encrypter is not nil, plaintext States are still readable, so that
+// States stored before encryption is enabled can be encrypted by the next Apply.
+type EncryptedState struct {
+	Storage   states.StateStorage
+	Encrypter Encrypter
+}
+
+// NewEncryptedState returns an EncryptedState which stores States in storage
+func NewEncryptedState(storage states.StateStorage, encrypter Encrypter) *EncryptedState {
+	return &EncryptedState{Storage: storage, Encrypter: encrypter}
+}
+
+// Apply encrypts Resources of the State and applies it to the wrapped StateStorage. The State itself is not modified
+// except its ID
+func (s *EncryptedState) Apply(state *states.State) error {
+	if s.Encrypter == nil {
+		return s.Storage.Apply(state)
+	}
+
+	plaintext, err := json.Marshal(state.Resources)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := s.Encrypter.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("encrypt State failed: %v", err)
+	}
+
+	encrypted := *state
+	encrypted.Resources = nil
+	encrypted.Ciphertext = ciphertext
+	if err = s.Storage.Apply(&encrypted); err != nil {
+		return err
+	}
+	state.ID = encrypted.ID
+	return nil
+}
+
+func (s *EncryptedState) Delete(query *states.StateQuery, serial uint64) error {
+	return s.Storage.Delete(query, serial)
+}
+
+func (s *EncryptedState) GetLatestState(query *states.StateQuery) (*states.State, error) {
+	state, err := s.Storage.GetLatestState(query)
+	if err != nil || state == nil {
+		return state, err
+	}
+	return state, s.decrypt(state)
+}
+
+func (s *EncryptedState) GetStateHistory(query *states.StateQuery) ([]*states.State, error) {
+	history, err := s.Storage.GetStateHistory(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range history {
+		if err = s.decrypt(state); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+func (s *EncryptedState) GetStateBySerial(query *states.StateQuery, serial uint64) (*states.State, error) {
+	state, err := s.Storage.GetStateBySerial(query, serial)
+	if err != nil || state == nil {
+		return state, err
+	}
+	return state, s.decrypt(state)
+}
+
+func (s *EncryptedState) Lock(info *states.LockInfo) error {
+	return s.Storage.Lock(info)
+}
+
+func (s *EncryptedState) Unlock(info *states.LockInfo) error {
+	return s.Storage.Unlock(info)
+}
+
+// decrypt decrypts the Ciphertext of the State into its Resources in place
+func (s *EncryptedState) decrypt(state *states.State) error {
+	if !state.IsEncrypted() {
+		if s.Encrypter != nil {
+			log.Warnf("State %s/%s with serial %d is not encrypted, it will be encrypted by the next apply",
+				state.Project, state.Stack, state.Serial)
+		}
+		return nil
+	}
+	if s.Encrypter == nil {
+		return fmt.Errorf("read State %s/%s with serial %d failed: %w", state.Project, state.Stack, state.Serial, ErrEncryptedState)
+	}
+
+	plaintext, err := s.Encrypter.Decrypt(state.Ciphertext)
+	if err != nil {
+		return fmt.Errorf("read State %s/%s with serial %d failed: %v", state.Project, state.Stack, state.Serial, err)
+	}
+	var resources models.Resources
+	// JSON is a subset of YAML. Please check FileSystemState.GetLatestState for detail explanation
+	if err = yaml.Unmarshal(plaintext, &resources); err != nil {
+		return err
+	}
+	state.Resources = resources
+	state.Ciphertext = ""
+	return nil
+}
@@ -0,0 +1,93 @@
+package encryption
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+)
+
+var (
+	query    = &states.StateQuery{Tenant: "t", Project: "p", Stack: "s"}
+	resource = models.Resource{
+		ID:         "v1:Secret:default:password",
+		Type:       "Kubernetes",
+		Attributes: map[string]interface{}{"data": map[string]interface{}{"password": "MTIzNDU2"}, "replicas": 1},
+	}
+)
+
+func newState(serial uint64) *states.State {
+	return &states.State{Tenant: "t", Project: "p", Stack: "s", Serial: serial, Resources: models.Resources{resource}}
+}
+
+func TestEncryptedState(t *testing.T) {
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	encrypted := NewEncryptedState(storage, &aesGCM{provider: newPassphraseKey("passphrase")})
+
+	// a plaintext State stored before encryption is enabled is still readable
+	assert.NoError(t, storage.Apply(newState(1)))
+	latest, err := encrypted.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.False(t, latest.IsEncrypted())
+	assert.Equal(t, models.Resources{resource}, latest.Resources)
+
+	// the next State is encrypted at rest
+	state := newState(2)
+	assert.NoError(t, encrypted.Apply(state))
+	assert.Equal(t, models.Resources{resource}, state.Resources)
+	content, err := os.ReadFile(storage.Path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "MTIzNDU2")
+	stored, err := storage.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.True(t, stored.IsEncrypted())
+	assert.Empty(t, stored.Resources)
+
+	latest, err = encrypted.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.False(t, latest.IsEncrypted())
+	assert.Equal(t, models.Resources{resource}, latest.Resources)
+
+	// mixed encrypted and plaintext States in history
+	history, err := encrypted.GetStateHistory(query)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	for _, s := range history {
+		assert.Equal(t, models.Resources{resource}, s.Resources)
+	}
+	bySerial, err := encrypted.GetStateBySerial(query, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Resources{resource}, bySerial.Resources)
+
+	// encrypted States can't be read without encryption configured
+	plaintext := NewEncryptedState(storage, nil)
+	_, err = plaintext.GetLatestState(query)
+	assert.ErrorIs(t, err, ErrEncryptedState)
+	_, err = plaintext.GetStateHistory(query)
+	assert.ErrorIs(t, err, ErrEncryptedState)
+	state, err = plaintext.GetStateBySerial(query, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Resources{resource}, state.Resources)
+
+	// encrypted States can't be read with a wrong passphrase
+	wrong := NewEncryptedState(storage, &aesGCM{provider: newPassphraseKey("wrong")})
+	_, err = wrong.GetLatestState(query)
+	assert.Error(t, err)
+
+	// the serial is still checked by the wrapped StateStorage
+	var conflictErr *states.SerialConflictError
+	assert.ErrorAs(t, encrypted.Apply(newState(2)), &conflictErr)
+
+	info := states.NewLockInfo(query, "apply")
+	assert.NoError(t, encrypted.Lock(info))
+	assert.NoError(t, encrypted.Unlock(info))
+	assert.NoError(t, encrypted.Delete(query, 0))
+	latest, err = encrypted.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+}
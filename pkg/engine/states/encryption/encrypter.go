@@ -0,0 +1,205 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"kusionstack.io/kusion/pkg/engine/states"
+)
+
+// Encrypter encrypts and decrypts the Resources of States
+type Encrypter interface {
+	// Encrypt returns the ciphertext of plaintext, which starts with states.CiphertextPrefix
+	Encrypt(plaintext []byte) (string, error)
+
+	// Decrypt returns the plaintext of ciphertext returned by Encrypt
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+// keySize is the key size of AES-256
+const keySize = 32
+
+// envelope is the content of the ciphertext, which contains everything needed to decrypt it except the secret
+type envelope struct {
+	// Method is the way to get the key, and it should be the same as the configured one when decrypting
+	Method string `json:"method"`
+
+	// KeyInfo is the non-secret info to recover the key, e.g. the salt of the passphrase
+	KeyInfo string `json:"keyInfo,omitempty"`
+
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// keyProvider provides keys for AES-GCM
+type keyProvider interface {
+	// method returns the name of this way to get the key
+	method() string
+
+	// encryptionKey returns the key to encrypt and the keyInfo to recover this key when decrypting
+	encryptionKey() (key []byte, keyInfo string, err error)
+
+	// decryptionKey recovers the key from keyInfo
+	decryptionKey(keyInfo string) ([]byte, error)
+}
+
+// aesGCM encrypts plaintext with AES-256-GCM, and the key is provided by the keyProvider
+type aesGCM struct {
+	provider keyProvider
+}
+
+func (a *aesGCM) Encrypt(plaintext []byte) (string, error) {
+	key, keyInfo, err := a.provider.encryptionKey()
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	e := &envelope{
+		Method:  a.provider.method(),
+		KeyInfo: keyInfo,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plaintext, nil),
+	}
+	content, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return states.CiphertextPrefix + base64.StdEncoding.EncodeToString(content), nil
+}
+
+func (a *aesGCM) Decrypt(ciphertext string) ([]byte, error) {
+	if !strings.HasPrefix(ciphertext, states.CiphertextPrefix) {
+		return nil, fmt.Errorf("ciphertext should start with %s", states.CiphertextPrefix)
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, states.CiphertextPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("malformed ciphertext: %v", err)
+	}
+	e := &envelope{}
+	if err = json.Unmarshal(content, e); err != nil {
+		return nil, fmt.Errorf("malformed ciphertext: %v", err)
+	}
+	if e.Method != a.provider.method() {
+		return nil, fmt.Errorf("the State is encrypted with %s, but %s is configured", e.Method, a.provider.method())
+	}
+
+	key, err := a.provider.decryptionKey(e.KeyInfo)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("malformed ciphertext: invalid nonce size %d", len(e.Nonce))
+	}
+	plaintext, err := gcm.Open(nil, e.Nonce, e.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt State failed, the key may be wrong: %v", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// staticKey provides the key read from a key file
+type staticKey struct {
+	key []byte
+}
+
+func (s *staticKey) method() string {
+	return "keyFile"
+}
+
+func (s *staticKey) encryptionKey() ([]byte, string, error) {
+	return s.key, "", nil
+}
+
+func (s *staticKey) decryptionKey(string) ([]byte, error) {
+	return s.key, nil
+}
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN    = 1 << 15
+	scryptR    = 8
+	scryptP    = 1
+	saltLength = 16
+)
+
+// passphraseKey derives keys from the passphrase by scrypt. Deriving a key is slow by design, so the salt to encrypt
+// is generated once, and derived keys are cached by their salts
+type passphraseKey struct {
+	passphrase string
+
+	mu   sync.Mutex
+	salt string
+	keys map[string][]byte
+}
+
+func newPassphraseKey(passphrase string) *passphraseKey {
+	return &passphraseKey{passphrase: passphrase, keys: map[string][]byte{}}
+}
+
+func (p *passphraseKey) method() string {
+	return "passphrase"
+}
+
+func (p *passphraseKey) encryptionKey() ([]byte, string, error) {
+	p.mu.Lock()
+	if p.salt == "" {
+		salt := make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			p.mu.Unlock()
+			return nil, "", err
+		}
+		p.salt = base64.StdEncoding.EncodeToString(salt)
+	}
+	salt := p.salt
+	p.mu.Unlock()
+
+	key, err := p.decryptionKey(salt)
+	return key, salt, err
+}
+
+func (p *passphraseKey) decryptionKey(salt string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[salt]; ok {
+		return key, nil
+	}
+
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("malformed salt: %v", err)
+	}
+	key, err := scrypt.Key([]byte(p.passphrase), saltBytes, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	p.keys[salt] = key
+	return key, nil
+}
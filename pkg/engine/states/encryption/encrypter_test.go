@@ -0,0 +1,169 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/vals"
+)
+
+func TestNewEncrypter(t *testing.T) {
+	dir := t.TempDir()
+	key := make([]byte, keySize)
+	_, _ = rand.Read(key)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "raw.key"), key, 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "base64.key"), []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "short.key"), []byte("short"), 0o600))
+	t.Setenv("TEST_KUSION_PASSPHRASE", "passphrase")
+
+	tests := []struct {
+		name    string
+		config  *Config
+		ss      *vals.SecretStores
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "disabled", config: nil, wantNil: true},
+		{name: "passphrase", config: &Config{PassphraseEnv: "TEST_KUSION_PASSPHRASE"}},
+		{name: "passphrase_not_set", config: &Config{PassphraseEnv: "TEST_KUSION_PASSPHRASE_NOT_SET"}, wantErr: true},
+		{name: "raw_key_file", config: &Config{KeyFile: "raw.key"}},
+		{name: "base64_key_file", config: &Config{KeyFile: filepath.Join(dir, "base64.key")}},
+		{name: "short_key_file", config: &Config{KeyFile: "short.key"}, wantErr: true},
+		{name: "vault_not_configured", config: &Config{VaultTransitKey: "kusion"}, wantErr: true},
+		{name: "vault", config: &Config{VaultTransitKey: "kusion"}, ss: &vals.SecretStores{Vault: &vals.Vault{Address: "http://127.0.0.1:8200"}}},
+		{name: "none", config: &Config{}, wantErr: true},
+		{name: "both", config: &Config{PassphraseEnv: "TEST_KUSION_PASSPHRASE", KeyFile: "raw.key"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypter, err := NewEncrypter(tt.config, dir, tt.ss)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNil, encrypter == nil)
+		})
+	}
+}
+
+func TestAESGCM(t *testing.T) {
+	key := make([]byte, keySize)
+	_, _ = rand.Read(key)
+	otherKey := make([]byte, keySize)
+	_, _ = rand.Read(otherKey)
+	plaintext := []byte(`[{"id":"secret","attributes":{"password":"123456"}}]`)
+
+	tests := []struct {
+		name      string
+		encrypter *aesGCM
+		decrypter *aesGCM
+		wantErr   bool
+	}{
+		{
+			name:      "key_file",
+			encrypter: &aesGCM{provider: &staticKey{key: key}},
+			decrypter: &aesGCM{provider: &staticKey{key: key}},
+		},
+		{
+			name:      "wrong_key",
+			encrypter: &aesGCM{provider: &staticKey{key: key}},
+			decrypter: &aesGCM{provider: &staticKey{key: otherKey}},
+			wantErr:   true,
+		},
+		{
+			name:      "passphrase",
+			encrypter: &aesGCM{provider: newPassphraseKey("passphrase")},
+			decrypter: &aesGCM{provider: newPassphraseKey("passphrase")},
+		},
+		{
+			name:      "wrong_passphrase",
+			encrypter: &aesGCM{provider: newPassphraseKey("passphrase")},
+			decrypter: &aesGCM{provider: newPassphraseKey("wrong")},
+			wantErr:   true,
+		},
+		{
+			name:      "wrong_method",
+			encrypter: &aesGCM{provider: newPassphraseKey("passphrase")},
+			decrypter: &aesGCM{provider: &staticKey{key: key}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := tt.encrypter.Encrypt(plaintext)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(ciphertext, states.CiphertextPrefix))
+			assert.NotContains(t, ciphertext, "123456")
+
+			got, err := tt.decrypter.Decrypt(ciphertext)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, plaintext, got)
+		})
+	}
+
+	_, err := (&aesGCM{provider: &staticKey{key: key}}).Decrypt("not encrypted")
+	assert.Error(t, err)
+}
+
+// newTransitServer returns a fake Vault server which implements datakey and decrypt of the transit secrets engine
+// by prefixing data keys with the transit key name
+func newTransitServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		data := map[string]interface{}{}
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/kusion":
+			key := make([]byte, keySize)
+			_, _ = rand.Read(key)
+			data["plaintext"] = base64.StdEncoding.EncodeToString(key)
+			data["ciphertext"] = "vault:v1:" + base64.StdEncoding.EncodeToString(key)
+		case "/v1/transit/decrypt/kusion":
+			data["plaintext"] = strings.TrimPrefix(body["ciphertext"].(string), "vault:v1:")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["not found"]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+}
+
+func TestVaultTransitKey(t *testing.T) {
+	server := newTransitServer(t)
+	defer server.Close()
+	client, err := (&vals.Vault{Address: server.URL}).NewClient()
+	assert.NoError(t, err)
+
+	encrypter := &aesGCM{provider: newVaultTransitKey(client, "transit", "kusion")}
+	ciphertext, err := encrypter.Encrypt([]byte("plaintext"))
+	assert.NoError(t, err)
+
+	// decrypt by another instance which has to unwrap the data key by Vault
+	decrypter := &aesGCM{provider: newVaultTransitKey(client, "transit", "kusion")}
+	plaintext, err := decrypter.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext", string(plaintext))
+
+	// the transit key doesn't exist
+	wrongKey := &aesGCM{provider: newVaultTransitKey(client, "transit", "other")}
+	_, err = wrongKey.Encrypt([]byte("plaintext"))
+	assert.Error(t, err)
+	_, err = wrongKey.Decrypt(ciphertext)
+	assert.Error(t, err)
+}
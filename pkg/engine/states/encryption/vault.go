@@ -0,0 +1,98 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"sync"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// vaultTransitKey provides data keys generated and wrapped by the Vault transit secrets engine. The transit key never
+// leaves Vault, and only the wrapped data key is stored with the ciphertext. A data key is generated once and unwrapped
+// data keys are cached by their wrapped ones, so that Vault is not requested for every State
+type vaultTransitKey struct {
+	client *vaultapi.Client
+	mount  string
+	name   string
+
+	mu         sync.Mutex
+	wrappedKey string
+	keys       map[string][]byte
+}
+
+func newVaultTransitKey(client *vaultapi.Client, mount, name string) *vaultTransitKey {
+	return &vaultTransitKey{client: client, mount: mount, name: name, keys: map[string][]byte{}}
+}
+
+func (v *vaultTransitKey) method() string {
+	return "vaultTransit"
+}
+
+func (v *vaultTransitKey) encryptionKey() ([]byte, string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.wrappedKey != "" {
+		return v.keys[v.wrappedKey], v.wrappedKey, nil
+	}
+
+	secret, err := v.client.Logical().Write(path.Join(v.mount, "datakey", "plaintext", v.name), map[string]interface{}{
+		"bits": keySize * 8,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("generate data key by Vault transit key %s failed: %v", v.name, err)
+	}
+	key, err := decodeSecretField(secret, "plaintext")
+	if err != nil {
+		return nil, "", err
+	}
+	wrappedKey, ok := secret.Data["ciphertext"].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("no ciphertext returned by Vault transit key %s", v.name)
+	}
+
+	v.wrappedKey = wrappedKey
+	v.keys[wrappedKey] = key
+	return key, wrappedKey, nil
+}
+
+func (v *vaultTransitKey) decryptionKey(wrappedKey string) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[wrappedKey]; ok {
+		return key, nil
+	}
+
+	secret, err := v.client.Logical().Write(path.Join(v.mount, "decrypt", v.name), map[string]interface{}{
+		"ciphertext": wrappedKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data key by Vault transit key %s failed: %v", v.name, err)
+	}
+	key, err := decodeSecretField(secret, "plaintext")
+	if err != nil {
+		return nil, err
+	}
+	v.keys[wrappedKey] = key
+	return key, nil
+}
+
+// decodeSecretField decodes the base64 encoded field in the data of the secret returned by Vault
+func decodeSecretField(secret *vaultapi.Secret, field string) ([]byte, error) {
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no data returned by Vault")
+	}
+	encoded, ok := secret.Data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("no %s returned by Vault", field)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %s returned by Vault: %v", field, err)
+	}
+	if len(decoded) != keySize {
+		return nil, fmt.Errorf("the data key returned by Vault should be %d bytes, got %d", keySize, len(decoded))
+	}
+	return decoded, nil
+}
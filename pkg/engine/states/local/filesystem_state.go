@@ -2,8 +2,15 @@ package local
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -23,17 +30,26 @@ func NewFileSystemState() states.StateStorage {
 	return &FileSystemState{}
 }
 
-const KusionState = "kusion_state.json"
+const (
+	KusionState = "kusion_state.json"
+
+	// lockFileSuffix is appended to the state file path to build the lock file path
+	lockFileSuffix = ".lock"
+
+	// historyDirSuffix is appended to the state file path to build the directory that keeps every applied State
+	historyDirSuffix = ".history"
+)
 
 func (f *FileSystemState) GetLatestState(query *states.StateQuery) (*states.State, error) {
+	path := f.statePath(clusterOf(query))
 	// create a new state file if no file exists
-	file, err := os.OpenFile(f.Path, os.O_RDWR|os.O_CREATE, fs.ModePerm)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, fs.ModePerm)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	jsonFile, err := os.ReadFile(f.Path)
+	jsonFile, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -49,21 +65,85 @@ func (f *FileSystemState) GetLatestState(query *states.StateQuery) (*states.Stat
 		if err != nil {
 			return nil, err
 		}
+		if state.Cluster != clusterOf(query) {
+			// the state file of the default cluster may still keep the legacy State of another cluster
+			log.Infof("file %s keeps the State of cluster %s. Skip it", path, state.Cluster)
+			return nil, nil
+		}
 		return state, nil
 	} else {
-		log.Infof("file %s is empty. Skip unmarshal json", f.Path)
+		log.Infof("file %s is empty. Skip unmarshal json", path)
+		return f.legacyState(clusterOf(query))
+	}
+}
+
+// legacyState returns the State of the cluster in the state file of the default cluster, where States of all clusters
+// were saved before state files of clusters are separated. It returns nil for the default cluster, or if the legacy
+// State belongs to another cluster. The next apply of the cluster saves its State in the state file of the cluster
+func (f *FileSystemState) legacyState(cluster string) (*states.State, error) {
+	if cluster == "" {
+		return nil, nil
+	}
+	state, err := readState(f.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if state.Cluster != cluster {
 		return nil, nil
 	}
+	log.Infof("read the State of cluster %s in the legacy state file:%s", cluster, f.Path)
+	return state, nil
+}
+
+// moveLegacyState moves the legacy State of another cluster in the state file of the default cluster to the state file
+// of that cluster if it has none, so that the State is not lost when the default cluster overwrites the file
+func (f *FileSystemState) moveLegacyState() error {
+	legacy, err := readState(f.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if legacy.Cluster == "" {
+		return nil
+	}
+	path := f.statePath(legacy.Cluster)
+	info, err := os.Stat(path)
+	if err == nil && info.Size() != 0 {
+		return nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	jsonByte, err := json.MarshalIndent(legacy, "", "  ")
+	if err != nil {
+		return err
+	}
+	log.Infof("move the legacy State of cluster %s to the state file:%s", legacy.Cluster, path)
+	return os.WriteFile(path, jsonByte, fs.ModePerm)
 }
 
 func (f *FileSystemState) Apply(state *states.State) error {
 	now := time.Now()
 
+	if state.Cluster == "" {
+		if err := f.moveLegacyState(); err != nil {
+			return err
+		}
+	}
+
 	// don't change createTime in the state
-	oldState, err := f.GetLatestState(nil)
+	oldState, err := f.GetLatestState(&states.StateQuery{Cluster: state.Cluster})
 	if err != nil {
 		return err
 	}
+	if err = states.CheckSerial(oldState, state.Serial); err != nil {
+		return err
+	}
 
 	if oldState == nil || oldState.CreateTime.IsZero() {
 		state.CreateTime = now
@@ -76,14 +156,245 @@ func (f *FileSystemState) Apply(state *states.State) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(f.Path, jsonByte, fs.ModePerm)
+	if err = os.WriteFile(f.statePath(state.Cluster), jsonByte, fs.ModePerm); err != nil {
+		return err
+	}
+
+	// keep a copy of this State in the history directory
+	if err = os.MkdirAll(f.historyDir(state.Cluster), fs.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(f.historyPath(state.Cluster, state.Serial), jsonByte, fs.ModePerm)
+}
+
+func (f *FileSystemState) Delete(query *states.StateQuery, serial uint64) error {
+	cluster := clusterOf(query)
+	path := f.statePath(cluster)
+	if serial == 0 {
+		log.Infof("Delete state file:%s and its history", path)
+		if err := os.RemoveAll(f.historyDir(cluster)); err != nil {
+			return err
+		}
+		// the legacy State of the cluster is deleted too, or it would be read as the latest State again
+		legacy, err := f.legacyState(cluster)
+		if err != nil {
+			return err
+		}
+		if legacy != nil {
+			if err = os.Remove(f.Path); err != nil {
+				return err
+			}
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+
+	target, err := f.GetStateBySerial(query, serial)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("can not find State with serial %d", serial)
+	}
+	log.Infof("Delete state file:%s with serial:%d", path, serial)
+	if err = os.Remove(f.historyPath(cluster, serial)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// the previous State becomes the latest one if the latest State is deleted
+	latest, err := f.GetLatestState(query)
+	if err != nil || latest == nil || latest.Serial != serial {
+		return err
+	}
+	previous, err := f.previousState(query, serial)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return os.Remove(path)
+	}
+	jsonByte, err := json.MarshalIndent(previous, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, jsonByte, fs.ModePerm)
+}
+
+// previousState returns the newest State in the history whose serial is not the specified one
+func (f *FileSystemState) previousState(query *states.StateQuery, serial uint64) (*states.State, error) {
+	history, err := f.GetStateHistory(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range history {
+		if state.Serial != serial {
+			return state, nil
+		}
+	}
+	return nil, nil
 }
 
-func (f *FileSystemState) Delete(id string) error {
-	log.Infof("Delete state file:%s", f.Path)
-	err := os.Remove(f.Path)
+// GetStateHistory reads all States in the history directory. The State file applied before the history directory
+// is introduced is regarded as the only history
+func (f *FileSystemState) GetStateHistory(query *states.StateQuery) ([]*states.State, error) {
+	historyDir := f.historyDir(clusterOf(query))
+	entries, err := os.ReadDir(historyDir)
 	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		latest, err := f.GetLatestState(query)
+		if err != nil || latest == nil {
+			return nil, err
+		}
+		return []*states.State{latest}, nil
+	}
+
+	var history []*states.State
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if _, err = strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64); err != nil {
+			continue
+		}
+		state, err := readState(filepath.Join(historyDir, name))
+		if err != nil {
+			return nil, err
+		}
+		// the history directory of the default cluster may still keep legacy States of other clusters
+		if state.Cluster != clusterOf(query) {
+			continue
+		}
+		history = append(history, state)
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Serial > history[j].Serial
+	})
+	return history, nil
+}
+
+// GetStateBySerial reads the State with the specified serial in the history directory
+func (f *FileSystemState) GetStateBySerial(query *states.StateQuery, serial uint64) (*states.State, error) {
+	state, err := readState(f.historyPath(clusterOf(query), serial))
+	if err == nil {
+		if state.Cluster != clusterOf(query) {
+			return nil, nil
+		}
+		return state, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// the latest State may be applied before the history directory is introduced
+	latest, err := f.GetLatestState(query)
+	if err != nil || latest == nil || latest.Serial != serial {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// statePath returns the path of the state file of the cluster. The state file of the default cluster is Path, and
+// the cluster name is inserted before the extension of Path for other clusters, e.g. kusion_state.prod.json
+func (f *FileSystemState) statePath(cluster string) string {
+	if cluster == "" {
+		return f.Path
+	}
+	ext := filepath.Ext(f.Path)
+	return strings.TrimSuffix(f.Path, ext) + "." + url.PathEscape(cluster) + ext
+}
+
+func (f *FileSystemState) historyDir(cluster string) string {
+	return f.statePath(cluster) + historyDirSuffix
+}
+
+func (f *FileSystemState) historyPath(cluster string, serial uint64) string {
+	return filepath.Join(f.historyDir(cluster), strconv.FormatUint(serial, 10)+".json")
+}
+
+// clusterOf returns the cluster of the query, which is the default cluster if the query is nil
+func clusterOf(query *states.StateQuery) string {
+	if query == nil {
+		return ""
+	}
+	return query.Cluster
+}
+
+func readState(path string) (*states.State, error) {
+	jsonFile, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &states.State{}
+	// JSON is a subset of YAML. Please check FileSystemState.GetLatestState for detail explanation
+	if err = yaml.Unmarshal(jsonFile, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Lock creates a lock file next to the state file and fails if the lock file already exists
+func (f *FileSystemState) Lock(info *states.LockInfo) error {
+	path := f.statePath(info.Cluster)
+	file, err := os.OpenFile(f.lockPath(info.Cluster), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			held, readErr := f.readLock(info.Cluster)
+			if readErr != nil {
+				return &states.LockError{Err: fmt.Errorf("state file %s is locked and read lock info failed: %v", path, readErr)}
+			}
+			return &states.LockError{Info: held, Err: fmt.Errorf("state file %s is locked", path)}
+		}
 		return err
 	}
+	defer file.Close()
+
+	jsonByte, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(jsonByte); err != nil {
+		return err
+	}
+	log.Infof("lock state file:%s, lock id:%s", path, info.ID)
 	return nil
 }
+
+// Unlock removes the lock file if the ID recorded in it equals info.ID
+func (f *FileSystemState) Unlock(info *states.LockInfo) error {
+	path := f.statePath(info.Cluster)
+	held, err := f.readLock(info.Cluster)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("state file %s is not locked", path)
+		}
+		return err
+	}
+	if err = states.CheckLockID(held, info.ID); err != nil {
+		return err
+	}
+
+	log.Infof("unlock state file:%s, lock id:%s", path, info.ID)
+	return os.Remove(f.lockPath(info.Cluster))
+}
+
+func (f *FileSystemState) lockPath(cluster string) string {
+	return f.statePath(cluster) + lockFileSuffix
+}
+
+func (f *FileSystemState) readLock(cluster string) (*states.LockInfo, error) {
+	data, err := os.ReadFile(f.lockPath(cluster))
+	if err != nil {
+		return nil, err
+	}
+	info := &states.LockInfo{}
+	if err = json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -4,6 +4,7 @@
 package local
 
 import (
+	"encoding/json"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -95,6 +96,9 @@ func FileSystemStateSetUp(t *testing.T) *FileSystemState {
 	monkey.Patch(os.Remove, func(name string) error {
 		return nil
 	})
+	monkey.Patch(os.MkdirAll, func(path string, perm fs.FileMode) error {
+		return nil
+	})
 
 	return &FileSystemState{Path: "kusion_state_filesystem.json"}
 }
@@ -103,10 +107,171 @@ func TestFileSystemState(t *testing.T) {
 	defer monkey.UnpatchAll()
 	fileSystemState := FileSystemStateSetUp(t)
 
-	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 1}
 	err := fileSystemState.Apply(state)
 	assert.NoError(t, err)
 
-	err = fileSystemState.Delete("kusion_state_filesystem.json")
+	// the serial doesn't follow the latest one
+	state = &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 3}
+	err = fileSystemState.Apply(state)
+	var conflictErr *states.SerialConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+
+	err = fileSystemState.Delete(&states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}, 0)
+	assert.NoError(t, err)
+}
+
+func TestFileSystemState_Lock(t *testing.T) {
+	fileSystemState := &FileSystemState{Path: filepath.Join(t.TempDir(), KusionState)}
+	query := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+
+	info := states.NewLockInfo(query, "apply")
+	err := fileSystemState.Lock(info)
+	assert.NoError(t, err)
+
+	// lock a locked state
+	err = fileSystemState.Lock(states.NewLockInfo(query, "destroy"))
+	var lockErr *states.LockError
+	assert.ErrorAs(t, err, &lockErr)
+	assert.Equal(t, info.ID, lockErr.Info.ID)
+
+	// unlock with a mismatched lock ID
+	err = fileSystemState.Unlock(&states.LockInfo{ID: "mismatched"})
+	assert.ErrorAs(t, err, &lockErr)
+
+	err = fileSystemState.Unlock(info)
+	assert.NoError(t, err)
+
+	// unlock an unlocked state
+	err = fileSystemState.Unlock(info)
+	assert.Error(t, err)
+}
+
+func TestFileSystemState_History(t *testing.T) {
+	fileSystemState := &FileSystemState{Path: filepath.Join(t.TempDir(), KusionState)}
+	query := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+
+	for serial := uint64(1); serial <= 3; serial++ {
+		state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: serial}
+		assert.NoError(t, fileSystemState.Apply(state))
+	}
+
+	history, err := fileSystemState.GetStateHistory(query)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	for i, state := range history {
+		assert.Equal(t, uint64(3-i), state.Serial)
+	}
+
+	state, err := fileSystemState.GetStateBySerial(query, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), state.Serial)
+
+	state, err = fileSystemState.GetStateBySerial(query, 4)
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	// delete the latest State and the previous one becomes the latest
+	assert.NoError(t, fileSystemState.Delete(query, 3))
+	latest, err := fileSystemState.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	assert.Error(t, fileSystemState.Delete(query, 3))
+
+	// delete the State and all its history
+	assert.NoError(t, fileSystemState.Delete(query, 0))
+	history, err = fileSystemState.GetStateHistory(query)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestFileSystemState_Clusters(t *testing.T) {
+	fileSystemState := &FileSystemState{Path: filepath.Join(t.TempDir(), KusionState)}
+	query := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+	prodQuery := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod"}
+
+	// States of clusters are saved in separated files with their own serials and locks
+	assert.NoError(t, fileSystemState.Apply(&states.State{Serial: 1}))
+	assert.NoError(t, fileSystemState.Apply(&states.State{Serial: 1, Cluster: "prod"}))
+	assert.NoError(t, fileSystemState.Apply(&states.State{Serial: 2, Cluster: "prod"}))
+	assert.Equal(t, filepath.Join(filepath.Dir(fileSystemState.Path), "kusion_state.prod.json"), fileSystemState.statePath("prod"))
+
+	info := states.NewLockInfo(query, "apply")
+	assert.NoError(t, fileSystemState.Lock(info))
+	prodInfo := states.NewLockInfo(prodQuery, "apply")
+	assert.NoError(t, fileSystemState.Lock(prodInfo))
+	assert.NoError(t, fileSystemState.Unlock(prodInfo))
+	assert.NoError(t, fileSystemState.Unlock(info))
+
+	// deleting the State of the default cluster keeps the State of other clusters
+	assert.NoError(t, fileSystemState.Delete(query, 0))
+	history, err := fileSystemState.GetStateHistory(prodQuery)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	latest, err := fileSystemState.GetLatestState(prodQuery)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+}
+
+func TestFileSystemState_LegacyClusterState(t *testing.T) {
+	fileSystemState := &FileSystemState{Path: filepath.Join(t.TempDir(), KusionState)}
+	prodQuery := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod"}
+	testQuery := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "test"}
+
+	// the State of the prod cluster saved in the state file of the default cluster before files are separated
+	jsonByte, err := json.Marshal(&states.State{Serial: 3, Cluster: "prod"})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(fileSystemState.Path, jsonByte, fs.ModePerm))
+
+	latest, err := fileSystemState.GetLatestState(prodQuery)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), latest.Serial)
+	latest, err = fileSystemState.GetLatestState(testQuery)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	// the next apply follows the serial of the legacy State and saves the State in the file of the cluster
+	var conflictErr *states.SerialConflictError
+	assert.ErrorAs(t, fileSystemState.Apply(&states.State{Serial: 1, Cluster: "prod"}), &conflictErr)
+	assert.NoError(t, fileSystemState.Apply(&states.State{Serial: 4, Cluster: "prod"}))
+	latest, err = fileSystemState.GetLatestState(prodQuery)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), latest.Serial)
+
+	// purging the State of the cluster purges the legacy State too
+	assert.NoError(t, fileSystemState.Delete(prodQuery, 0))
+	latest, err = fileSystemState.GetLatestState(prodQuery)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+}
+
+func TestFileSystemState_LegacyStateOfDefaultCluster(t *testing.T) {
+	fileSystemState := &FileSystemState{Path: filepath.Join(t.TempDir(), KusionState)}
+	prodQuery := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod"}
+
+	// the stale State of the prod cluster in the state file of the default cluster
+	jsonByte, err := json.Marshal(&states.State{Serial: 3, Cluster: "prod"})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(fileSystemState.Path, jsonByte, fs.ModePerm))
+	assert.NoError(t, os.MkdirAll(fileSystemState.historyDir(""), fs.ModePerm))
+	assert.NoError(t, os.WriteFile(fileSystemState.historyPath("", 3), jsonByte, fs.ModePerm))
+
+	latest, err := fileSystemState.GetLatestState(&states.StateQuery{})
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+	history, err := fileSystemState.GetStateHistory(&states.StateQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+	target, err := fileSystemState.GetStateBySerial(&states.StateQuery{}, 3)
+	assert.NoError(t, err)
+	assert.Nil(t, target)
+
+	// the first apply of the default cluster moves the State of the prod cluster out before overwriting the file
+	assert.NoError(t, fileSystemState.Apply(&states.State{Serial: 1}))
+	latest, err = fileSystemState.GetLatestState(&states.StateQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), latest.Serial)
+	latest, err = fileSystemState.GetLatestState(prodQuery)
 	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), latest.Serial)
 }
@@ -0,0 +1,119 @@
+package states
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"kusionstack.io/kusion/pkg/version"
+)
+
+// LockInfo records who holds the lock of a State and why. It is persisted by every StateStorage
+// when the lock is acquired and returned in a LockError when someone else tries to acquire it.
+type LockInfo struct {
+	// ID is a unique identifier of this lock and is required to release it
+	ID string `json:"id" yaml:"id"`
+
+	// Tenant name
+	Tenant string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+
+	// Project name
+	Project string `json:"project" yaml:"project"`
+
+	// Stack name
+	Stack string `json:"stack" yaml:"stack"`
+
+	// Cluster name
+	Cluster string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+
+	// Operation is the operation that acquired this lock, e.g. apply or destroy
+	Operation string `json:"operation" yaml:"operation"`
+
+	// Who is the user@host that acquired this lock
+	Who string `json:"who" yaml:"who"`
+
+	// KusionVersion represents the Kusion's version that acquired this lock
+	KusionVersion string `json:"kusionVersion" yaml:"kusionVersion"`
+
+	// CreateTime is the time this lock is acquired
+	CreateTime time.Time `json:"createTime" yaml:"createTime"`
+}
+
+// NewLockInfo returns a LockInfo with a random ID for the State described by query
+func NewLockInfo(query *StateQuery, operation string) *LockInfo {
+	return &LockInfo{
+		ID:            newLockID(),
+		Tenant:        query.Tenant,
+		Project:       query.Project,
+		Stack:         query.Stack,
+		Cluster:       query.Cluster,
+		Operation:     operation,
+		Who:           lockOwner(),
+		KusionVersion: version.ReleaseVersion(),
+		CreateTime:    time.Now(),
+	}
+}
+
+// String returns a human-readable description of this lock
+func (l *LockInfo) String() string {
+	return fmt.Sprintf("ID: %s, Operation: %s, Who: %s, Version: %s, Created: %s",
+		l.ID, l.Operation, l.Who, l.KusionVersion, l.CreateTime.Format(time.RFC3339))
+}
+
+// LockError is returned by StateStorage.Lock and StateStorage.Unlock when the lock is held by someone else
+type LockError struct {
+	// Info is the lock currently held, it may be nil if the lock can't be read
+	Info *LockInfo
+
+	// Err is the underlying error
+	Err error
+}
+
+func (e *LockError) Error() string {
+	msg := "state is locked"
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.Info != nil {
+		msg = fmt.Sprintf("%s. Lock Info: [%s]", msg, e.Info)
+	}
+	return msg
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// CheckLockID returns a LockError if the held lock is not identified by id
+func CheckLockID(held *LockInfo, id string) error {
+	if held.ID != id {
+		return &LockError{
+			Info: held,
+			Err:  fmt.Errorf("lock ID %q does not match the existing lock", id),
+		}
+	}
+	return nil
+}
+
+func newLockID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+func lockOwner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+	return name + "@" + host
+}
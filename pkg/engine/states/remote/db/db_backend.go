@@ -1,11 +1,19 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"net"
 	"net/url"
+	"strconv"
 
 	"github.com/didi/gendry/manager"
+	_ "github.com/lib/pq"
 	"github.com/zclconf/go-cty/cty"
+	_ "modernc.org/sqlite"
+
+	"kusionstack.io/kusion/pkg/engine/dal/mapper"
 	"kusionstack.io/kusion/pkg/engine/states"
 )
 
@@ -21,50 +29,140 @@ func NewDBBackend() states.Backend {
 // structure for the receiving backend.
 func (b *DBBackend) ConfigSchema() cty.Type {
 	config := map[string]cty.Type{
+		"dbDialect":  cty.String,
 		"dbName":     cty.String,
 		"dbUser":     cty.String,
 		"dbPassword": cty.String,
 		"dbHost":     cty.String,
 		"dbPort":     cty.Number,
+		"dbSSLMode":  cty.String,
 	}
 	return cty.Object(config)
 }
 
 // Configure uses the provided configuration to set configuration fields
-// within the DBState backend.
+// within the DBState backend. Tables state and state_lock are created if they don't exist.
 func (b *DBBackend) Configure(obj cty.Value) error {
-	var dbName, dbUser, dbPassword, dbHost, dbPort cty.Value
-	if dbName = obj.GetAttr("dbName"); dbName.IsNull() {
-		return errors.New("dbName must be configure in backend config")
+	dialect := mapper.MySQL
+	if dbDialect := obj.GetAttr("dbDialect"); !dbDialect.IsNull() && dbDialect.AsString() != "" {
+		dialect = mapper.Dialect(dbDialect.AsString())
 	}
-	if dbUser = obj.GetAttr("dbUser"); dbUser.IsNull() {
-		return errors.New("dbUser must be configure in backend config")
+	if !dialect.IsValid() {
+		return fmt.Errorf("unsupported dbDialect %s, supported dialects are %v", dialect, mapper.Dialects)
 	}
-	if dbPassword = obj.GetAttr("dbPassword"); dbPassword.IsNull() {
-		return errors.New("dbPassword must be configure in backend config")
-	}
-	if dbHost = obj.GetAttr("dbHost"); dbHost.IsNull() {
-		return errors.New("dbHost must be configure in backend config")
+
+	var db *sql.DB
+	var err error
+	switch dialect {
+	case mapper.PostgreSQL:
+		db, err = openPostgreSQL(obj)
+	case mapper.SQLite:
+		db, err = openSQLite(obj)
+	default:
+		db, err = openMySQL(obj)
 	}
-	if dbPort = obj.GetAttr("dbPort"); dbPort.IsNull() {
-		return errors.New("dbPort must be configure in backend config")
+	if err != nil {
+		return err
 	}
-	port, _ := dbPort.AsBigFloat().Int64()
 
-	db, err := manager.New(dbName.AsString(), dbUser.AsString(), dbPassword.AsString(), dbHost.AsString()).Set(
-		manager.SetCharset("utf8"),
-		manager.SetParseTime(true),
-		manager.SetInterpolateParams(true),
-		manager.SetLoc(url.QueryEscape("Asia/Shanghai"))).Port(int(port)).Open(true)
-	if err != nil {
+	if err = dialect.Migrate(db); err != nil {
 		return err
 	}
 	b.DB = db
+	b.Dialect = dialect
 
 	return nil
 }
 
 // StateStorage return a StateStorage to manage State stored in db
 func (b *DBBackend) StateStorage() states.StateStorage {
-	return &DBState{b.DB}
+	return &DBState{DB: b.DB, Dialect: b.Dialect}
+}
+
+func openMySQL(obj cty.Value) (*sql.DB, error) {
+	dbName, dbUser, dbPassword, dbHost, port, err := serverConfig(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.New(dbName, dbUser, dbPassword, dbHost).Set(
+		manager.SetCharset("utf8"),
+		manager.SetParseTime(true),
+		manager.SetInterpolateParams(true),
+		manager.SetLoc(url.QueryEscape("Asia/Shanghai"))).Port(port).Open(true)
+}
+
+func openPostgreSQL(obj cty.Value) (*sql.DB, error) {
+	dsn, err := postgreSQLDSN(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// postgreSQLDSN returns the connection URL of the PostgreSQL server, lib/pq uses sslmode require if dbSSLMode
+// is not configured
+func postgreSQLDSN(obj cty.Value) (string, error) {
+	dbName, dbUser, dbPassword, dbHost, port, err := serverConfig(obj)
+	if err != nil {
+		return "", err
+	}
+
+	dsn := &url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(dbUser, dbPassword),
+		Host:   net.JoinHostPort(dbHost, strconv.Itoa(port)),
+		Path:   dbName,
+	}
+	if sslMode := obj.GetAttr("dbSSLMode"); !sslMode.IsNull() && sslMode.AsString() != "" {
+		dsn.RawQuery = url.Values{"sslmode": {sslMode.AsString()}}.Encode()
+	}
+	return dsn.String(), nil
+}
+
+// openSQLite opens the SQLite database file specified by dbName, the file is created if it doesn't exist
+func openSQLite(obj cty.Value) (*sql.DB, error) {
+	var dbName cty.Value
+	if dbName = obj.GetAttr("dbName"); dbName.IsNull() {
+		return nil, errors.New("dbName must be configure in backend config")
+	}
+
+	db, err := sql.Open("sqlite", dbName.AsString())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite doesn't support concurrent writes
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// serverConfig returns the configuration required to connect a database server
+func serverConfig(obj cty.Value) (dbName, dbUser, dbPassword, dbHost string, port int, err error) {
+	var name, user, password, host, dbPort cty.Value
+	if name = obj.GetAttr("dbName"); name.IsNull() {
+		return "", "", "", "", 0, errors.New("dbName must be configure in backend config")
+	}
+	if user = obj.GetAttr("dbUser"); user.IsNull() {
+		return "", "", "", "", 0, errors.New("dbUser must be configure in backend config")
+	}
+	if password = obj.GetAttr("dbPassword"); password.IsNull() {
+		return "", "", "", "", 0, errors.New("dbPassword must be configure in backend config")
+	}
+	if host = obj.GetAttr("dbHost"); host.IsNull() {
+		return "", "", "", "", 0, errors.New("dbHost must be configure in backend config")
+	}
+	if dbPort = obj.GetAttr("dbPort"); dbPort.IsNull() {
+		return "", "", "", "", 0, errors.New("dbPort must be configure in backend config")
+	}
+	p, _ := dbPort.AsBigFloat().Int64()
+
+	return name.AsString(), user.AsString(), password.AsString(), host.AsString(), int(p), nil
 }
@@ -2,13 +2,12 @@ package db
 
 import (
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/didi/gendry/scanner"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/copier"
 	"gopkg.in/yaml.v3"
 
@@ -28,31 +27,130 @@ func NewDBState() states.StateStorage {
 }
 
 type DBState struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Dialect mapper.Dialect
 }
 
-// Apply save state in DB by add-only strategy.
+// Apply save state in DB by add-only strategy. The table state has a unique key on
+// (tenant, project, stack, cluster, serial), so that concurrent insertions with the same serial are rejected
 func (s *DBState) Apply(state *states.State) error {
-	m := make(map[string]interface{})
+	query := &states.StateQuery{Tenant: state.Tenant, Project: state.Project, Stack: state.Stack, Cluster: state.Cluster}
+	latest, err := s.GetLatestState(query)
+	if err != nil {
+		return err
+	}
+	if err = states.CheckSerial(latest, state.Serial); err != nil {
+		return err
+	}
+
 	sort.Stable(state.Resources)
-	marshal, err := json.Marshal(state)
-	util.CheckNotError(err, fmt.Sprintf("marshal state failed:%+v", state))
-	err = json.Unmarshal(marshal, &m)
-	util.CheckNotError(err, fmt.Sprintf("unmarshal state failed:%+v", marshal))
-	m["resources"] = jsonutil.MustMarshal2String(m["resources"])
-	// timestamp is generated by DB, we ignore zero timestamp here
-	delete(m, "createTime")
-	delete(m, "modifiedTime")
-	id, err := mapper.Insert(s.DB, []map[string]interface{}{m})
+	resources := jsonutil.MustMarshal2String(state.Resources)
+	if state.IsEncrypted() {
+		// the ciphertext is stored in column resources, and it is distinguished by states.CiphertextPrefix
+		resources = state.Ciphertext
+	}
+	// id and timestamps are generated by DB, we ignore them here
+	m := map[string]interface{}{
+		"tenant":         state.Tenant,
+		"project":        state.Project,
+		"stack":          state.Stack,
+		"cluster":        state.Cluster,
+		"version":        state.Version,
+		"kusion_version": state.KusionVersion,
+		"serial":         state.Serial,
+		"operator":       state.Operator,
+		"resources":      resources,
+	}
+	id, err := mapper.Insert(s.DB, s.Dialect, []map[string]interface{}{m})
+	if s.Dialect.IsDuplicateKey(err) {
+		// another State with the same serial is inserted after the check above
+		return &states.SerialConflictError{LatestSerial: state.Serial, Serial: state.Serial}
+	}
 	state.ID = id
 	return err
 }
 
-func (s *DBState) Delete(id string) error {
-	panic("implement me")
+// Delete deletes rows of the stack in the cluster matched by query, and only the row with the specified serial if serial
+// is not 0
+func (s *DBState) Delete(q *states.StateQuery, serial uint64) error {
+	// deleting States of all stacks in a project by accident is dangerous
+	if len(q.Stack) == 0 {
+		return errors.New("no Stack in query")
+	}
+	where, err := queryWhere(q)
+	if err != nil {
+		return err
+	}
+	if serial != 0 {
+		where["serial"] = serial
+	}
+
+	affected, err := mapper.Delete(s.DB, s.Dialect, where)
+	if err != nil {
+		return err
+	}
+	if affected == 0 && serial != 0 {
+		return fmt.Errorf("can not find State with serial %d", serial)
+	}
+	log.Infof("delete %d State records with query:%v", affected, jsonutil.Marshal2PrettyString(q))
+	return nil
 }
 
 func (s *DBState) GetLatestState(q *states.StateQuery) (*states.State, error) {
+	where, err := queryWhere(q)
+	if err != nil {
+		return nil, err
+	}
+	where["_orderby"] = "serial desc"
+
+	stateDO, err := mapper.GetOne(s.DB, s.Dialect, where)
+	if errors.Is(err, scanner.ErrEmptyResult) {
+		return nil, nil
+	}
+	res := do2Bo(stateDO)
+	return res, err
+}
+
+// GetStateHistory returns all rows matched by query since DBState.Apply is add-only
+func (s *DBState) GetStateHistory(q *states.StateQuery) ([]*states.State, error) {
+	where, err := queryWhere(q)
+	if err != nil {
+		return nil, err
+	}
+	where["_orderby"] = "serial desc"
+
+	stateDOs, err := mapper.GetList(s.DB, s.Dialect, where)
+	if errors.Is(err, scanner.ErrEmptyResult) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*states.State, 0, len(stateDOs))
+	for _, stateDO := range stateDOs {
+		res = append(res, do2Bo(stateDO))
+	}
+	return res, nil
+}
+
+func (s *DBState) GetStateBySerial(q *states.StateQuery, serial uint64) (*states.State, error) {
+	where, err := queryWhere(q)
+	if err != nil {
+		return nil, err
+	}
+	where["serial"] = serial
+
+	stateDO, err := mapper.GetOne(s.DB, s.Dialect, where)
+	if errors.Is(err, scanner.ErrEmptyResult) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return do2Bo(stateDO), nil
+}
+
+func queryWhere(q *states.StateQuery) (map[string]interface{}, error) {
 	where := make(map[string]interface{})
 
 	if len(q.Tenant) == 0 {
@@ -73,29 +171,94 @@ func (s *DBState) GetLatestState(q *states.StateQuery) (*states.State, error) {
 		where["stack"] = q.Stack
 	}
 
-	if len(q.Cluster) != 0 {
-		where["cluster"] = q.Cluster
-	}
-	where["_orderby"] = "serial desc"
-
-	stateDO, err := mapper.GetOne(s.DB, where)
-	if errors.Is(err, scanner.ErrEmptyResult) {
-		return nil, nil
-	}
-	res := do2Bo(stateDO)
-	return res, err
+	// an empty cluster is the default one rather than any cluster, rows of other clusters are never matched
+	where["cluster"] = q.Cluster
+	return where, nil
 }
 
 func do2Bo(dbState *mapper.StateDO) *states.State {
 	var resStateList []models.Resource
-
-	// JSON is a subset of YAML. Please check FileSystemState.GetLatestState for detail explanation
-	parseErr := yaml.Unmarshal([]byte(dbState.Resources), &resStateList)
-	util.CheckNotError(parseErr, fmt.Sprintf("marshall stateDO.resources failed:%v", dbState.Resources))
+	var ciphertext string
+	if strings.HasPrefix(dbState.Resources, states.CiphertextPrefix) {
+		// the Resources are encrypted, please check DBState.Apply
+		ciphertext = dbState.Resources
+	} else {
+		// JSON is a subset of YAML. Please check FileSystemState.GetLatestState for detail explanation
+		parseErr := yaml.Unmarshal([]byte(dbState.Resources), &resStateList)
+		util.CheckNotError(parseErr, fmt.Sprintf("marshall stateDO.resources failed:%v", dbState.Resources))
+	}
 	res := states.NewState()
 	e := copier.Copy(res, dbState)
 	util.CheckNotError(e,
 		fmt.Sprintf("copy db_state to State failed. db_state:%v", jsonutil.MustMarshal2String(dbState)))
 	res.Resources = resStateList
+	res.Ciphertext = ciphertext
 	return res
 }
+
+// Lock inserts a lock row into table state_lock, the unique key of this table guarantees that only one lock can be inserted
+func (s *DBState) Lock(info *states.LockInfo) error {
+	lock := map[string]interface{}{
+		"id":             info.ID,
+		"tenant":         info.Tenant,
+		"project":        info.Project,
+		"stack":          info.Stack,
+		"cluster":        info.Cluster,
+		"operation":      info.Operation,
+		"who":            info.Who,
+		"kusion_version": info.KusionVersion,
+		"create_time":    info.CreateTime,
+	}
+	insertErr := mapper.InsertLock(s.DB, s.Dialect, []map[string]interface{}{lock})
+	if insertErr == nil {
+		return nil
+	}
+
+	// the insertion may fail because the lock is held by others, check it and return the held lock
+	held, err := s.getLock(info)
+	if err != nil {
+		return insertErr
+	}
+	return &states.LockError{Info: held, Err: fmt.Errorf("state is locked: %v", insertErr)}
+}
+
+// Unlock deletes the lock row in table state_lock if its ID equals info.ID
+func (s *DBState) Unlock(info *states.LockInfo) error {
+	held, err := s.getLock(info)
+	if err != nil {
+		return err
+	}
+	if err = states.CheckLockID(held, info.ID); err != nil {
+		return err
+	}
+
+	where := lockWhere(info)
+	where["id"] = info.ID
+	_, err = mapper.DeleteLock(s.DB, s.Dialect, where)
+	return err
+}
+
+func (s *DBState) getLock(info *states.LockInfo) (*states.LockInfo, error) {
+	lockDO, err := mapper.GetLock(s.DB, s.Dialect, lockWhere(info))
+	if errors.Is(err, scanner.ErrEmptyResult) {
+		return nil, errors.New("state is not locked")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	held := &states.LockInfo{}
+	if err = copier.Copy(held, lockDO); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+func lockWhere(info *states.LockInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"tenant":  info.Tenant,
+		"project": info.Project,
+		"stack":   info.Stack,
+		"cluster": info.Cluster,
+	}
+}
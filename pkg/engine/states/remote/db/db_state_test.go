@@ -12,7 +12,8 @@ import (
 
 	"bou.ke/monkey"
 	"github.com/didi/gendry/manager"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/didi/gendry/scanner"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 
 	"kusionstack.io/kusion/pkg/engine/dal/mapper"
@@ -44,11 +45,11 @@ func DBStateSetUp(t *testing.T) *DBState {
 
 	stateDo := &mapper.StateDO{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
 
-	monkey.Patch(mapper.GetOne, func(db *sql.DB, where map[string]interface{}) (*mapper.StateDO, error) {
+	monkey.Patch(mapper.GetOne, func(db *sql.DB, dialect mapper.Dialect, where map[string]interface{}) (*mapper.StateDO, error) {
 		return stateDo, nil
 	})
 
-	monkey.Patch(mapper.Insert, func(db *sql.DB, data []map[string]interface{}) (int64, error) {
+	monkey.Patch(mapper.Insert, func(db *sql.DB, dialect mapper.Dialect, data []map[string]interface{}) (int64, error) {
 		return 1, nil
 	})
 
@@ -62,16 +63,159 @@ func TestDBState(t *testing.T) {
 	_, err := dbState.GetLatestState(&states.StateQuery{Tenant: "test_global_tenant", Stack: "test_env", Project: "test_project"})
 	assert.NoError(t, err)
 
-	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", KusionVersion: "1.0.3"}
+	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", KusionVersion: "1.0.3", Serial: 1}
 	err = dbState.Apply(state)
 	assert.NoError(t, err)
 
-	defer func() {
-		if r := recover(); r != "implement me" {
-			t.Errorf("Delete() got: %v, want: 'implement me'", r)
+	// the serial doesn't follow the latest one
+	state.Serial = 2
+	err = dbState.Apply(state)
+	var conflictErr *states.SerialConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+
+	// another State with the same serial is inserted concurrently
+	monkey.Patch(mapper.Insert, func(db *sql.DB, dialect mapper.Dialect, data []map[string]interface{}) (int64, error) {
+		return 0, &mysql.MySQLError{Number: 1062}
+	})
+	state.Serial = 1
+	err = dbState.Apply(state)
+	assert.ErrorAs(t, err, &conflictErr)
+
+	monkey.Patch(mapper.Delete, func(db *sql.DB, dialect mapper.Dialect, where map[string]interface{}) (int64, error) {
+		if where["serial"] == uint64(2) {
+			return 0, nil
+		}
+		return 1, nil
+	})
+	query := &states.StateQuery{Tenant: "test_global_tenant", Stack: "test_env", Project: "test_project"}
+	assert.NoError(t, dbState.Delete(query, 0))
+	assert.NoError(t, dbState.Delete(query, 1))
+	assert.Error(t, dbState.Delete(query, 2))
+	assert.Error(t, dbState.Delete(&states.StateQuery{Tenant: "test_global_tenant", Project: "test_project"}, 0))
+}
+
+func TestDBState_Delete(t *testing.T) {
+	defer monkey.UnpatchAll()
+	dbState := DBStateSetUp(t)
+
+	rows := []map[string]interface{}{
+		{"cluster": "", "serial": uint64(1)},
+		{"cluster": "", "serial": uint64(2)},
+		{"cluster": "prod", "serial": uint64(1)},
+		{"cluster": "prod", "serial": uint64(2)},
+	}
+	monkey.Patch(mapper.Delete, func(db *sql.DB, dialect mapper.Dialect, where map[string]interface{}) (int64, error) {
+		var kept []map[string]interface{}
+		for _, row := range rows {
+			cluster, ok := where["cluster"]
+			if !ok || row["cluster"] != cluster {
+				kept = append(kept, row)
+				continue
+			}
+			if serial, ok := where["serial"]; ok && row["serial"] != serial {
+				kept = append(kept, row)
+			}
 		}
-	}()
-	dbState.Delete("test")
+		affected := len(rows) - len(kept)
+		rows = kept
+		return int64(affected), nil
+	})
+
+	// purging the default cluster keeps rows of other clusters
+	query := &states.StateQuery{Tenant: "test_global_tenant", Stack: "test_env", Project: "test_project"}
+	assert.NoError(t, dbState.Delete(query, 0))
+	assert.Equal(t, []map[string]interface{}{
+		{"cluster": "prod", "serial": uint64(1)},
+		{"cluster": "prod", "serial": uint64(2)},
+	}, rows)
+
+	query.Cluster = "prod"
+	assert.NoError(t, dbState.Delete(query, 2))
+	assert.Equal(t, []map[string]interface{}{{"cluster": "prod", "serial": uint64(1)}}, rows)
+}
+
+func TestDBState_Clusters(t *testing.T) {
+	defer monkey.UnpatchAll()
+	dbState := DBStateSetUp(t)
+
+	// rows are sorted by serial desc
+	rows := []*mapper.StateDO{
+		{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod", Serial: 5},
+		{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod", Serial: 4},
+		{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "", Serial: 2},
+		{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "", Serial: 1},
+	}
+	match := func(row *mapper.StateDO, where map[string]interface{}) bool {
+		cluster, ok := where["cluster"]
+		if !ok || row.Cluster != cluster {
+			return false
+		}
+		if serial, ok := where["serial"]; ok && row.Serial != serial {
+			return false
+		}
+		return true
+	}
+	monkey.Patch(mapper.GetList, func(db *sql.DB, dialect mapper.Dialect, where map[string]interface{}) ([]*mapper.StateDO, error) {
+		var matched []*mapper.StateDO
+		for _, row := range rows {
+			if match(row, where) {
+				matched = append(matched, row)
+			}
+		}
+		return matched, nil
+	})
+	monkey.Patch(mapper.GetOne, func(db *sql.DB, dialect mapper.Dialect, where map[string]interface{}) (*mapper.StateDO, error) {
+		for _, row := range rows {
+			if match(row, where) {
+				return row, nil
+			}
+		}
+		return nil, scanner.ErrEmptyResult
+	})
+	monkey.Patch(mapper.Delete, func(db *sql.DB, dialect mapper.Dialect, where map[string]interface{}) (int64, error) {
+		var kept []*mapper.StateDO
+		for _, row := range rows {
+			if !match(row, where) {
+				kept = append(kept, row)
+			}
+		}
+		affected := len(rows) - len(kept)
+		rows = kept
+		return int64(affected), nil
+	})
+
+	defaultQuery := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+	prodQuery := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod"}
+
+	// the default cluster doesn't read States of the prod cluster
+	latest, err := dbState.GetLatestState(defaultQuery)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	history, err := dbState.GetStateHistory(defaultQuery)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	target, err := dbState.GetStateBySerial(defaultQuery, 5)
+	assert.NoError(t, err)
+	assert.Nil(t, target)
+
+	latest, err = dbState.GetLatestState(prodQuery)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), latest.Serial)
+	target, err = dbState.GetStateBySerial(prodQuery, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", target.Cluster)
+
+	// the serial of the default cluster follows its own latest State
+	assert.NoError(t, dbState.Apply(&states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 3}))
+
+	// purging the default cluster keeps rows of the prod cluster
+	assert.NoError(t, dbState.Delete(defaultQuery, 0))
+	latest, err = dbState.GetLatestState(defaultQuery)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+	history, err = dbState.GetStateHistory(prodQuery)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
 }
 
 func TestDBState_do2Bo(t *testing.T) {
@@ -128,3 +272,27 @@ func TestDBState_do2Bo(t *testing.T) {
 		})
 	}
 }
+
+func TestDBState_GetStateHistory(t *testing.T) {
+	defer monkey.UnpatchAll()
+	dbState := DBStateSetUp(t)
+	monkey.Patch(mapper.GetList, func(db *sql.DB, dialect mapper.Dialect, where map[string]interface{}) ([]*mapper.StateDO, error) {
+		assert.Equal(t, "serial desc", where["_orderby"])
+		return []*mapper.StateDO{
+			{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 2},
+			{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 1},
+		}, nil
+	})
+
+	query := &states.StateQuery{Tenant: "test_global_tenant", Stack: "test_env", Project: "test_project"}
+	history, err := dbState.GetStateHistory(query)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, uint64(2), history[0].Serial)
+
+	_, err = dbState.GetStateBySerial(query, 1)
+	assert.NoError(t, err)
+
+	_, err = dbState.GetStateHistory(&states.StateQuery{Stack: "test_env"})
+	assert.Error(t, err)
+}
@@ -22,6 +22,9 @@ func (b *HTTPBackend) ConfigSchema() cty.Type {
 		"urlPrefix":          cty.String,
 		"applyURLFormat":     cty.String,
 		"getLatestURLFormat": cty.String,
+		"lockURLFormat":      cty.String,
+		"historyURLFormat":   cty.String,
+		"deleteURLFormat":    cty.String,
 	}
 	return cty.Object(config)
 }
@@ -60,6 +63,41 @@ func (b *HTTPBackend) Configure(obj cty.Value) error {
 		b.getLatestURLFormat = asString
 	}
 
+	// lockURLFormat is required, otherwise concurrent operations may overwrite State of each other
+	if lock := obj.GetAttr("lockURLFormat"); lock.IsNull() || lock.AsString() == "" {
+		return errors.New("lockURLFormat can not be empty")
+	} else {
+		asString := lock.AsString()
+		count := strings.Count(asString, "%s")
+		if count != ParamsCounts {
+			return errors.New("lockURLFormat must contains 4 \"%s\" placeholders for tenant, project, " +
+				"stack and cluster. Current format:" + asString)
+		}
+		b.lockURLFormat = asString
+	}
+
+	// historyURLFormat is optional, State history is not supported if it is not set
+	if history := obj.GetAttr("historyURLFormat"); !history.IsNull() && history.AsString() != "" {
+		asString := history.AsString()
+		count := strings.Count(asString, "%s")
+		if count != ParamsCounts {
+			return errors.New("historyURLFormat must contains 4 \"%s\" placeholders for tenant, project, " +
+				"stack and cluster. Current format:" + asString)
+		}
+		b.historyURLFormat = asString
+	}
+
+	// deleteURLFormat is optional, State can not be deleted if it is not set
+	if deleteFormat := obj.GetAttr("deleteURLFormat"); !deleteFormat.IsNull() && deleteFormat.AsString() != "" {
+		asString := deleteFormat.AsString()
+		count := strings.Count(asString, "%s")
+		if count != ParamsCounts {
+			return errors.New("deleteURLFormat must contains 4 \"%s\" placeholders for tenant, project, " +
+				"stack and cluster. Current format:" + asString)
+		}
+		b.deleteURLFormat = asString
+	}
+
 	return nil
 }
 
@@ -69,5 +107,8 @@ func (b *HTTPBackend) StateStorage() states.StateStorage {
 		urlPrefix:          b.urlPrefix,
 		applyURLFormat:     b.applyURLFormat,
 		getLatestURLFormat: b.getLatestURLFormat,
+		lockURLFormat:      b.lockURLFormat,
+		historyURLFormat:   b.historyURLFormat,
+		deleteURLFormat:    b.deleteURLFormat,
 	}
 }
@@ -19,6 +19,9 @@ func TestHttpBackend_ConfigSchema(t *testing.T) {
 				"urlPrefix":          cty.String,
 				"applyURLFormat":     cty.String,
 				"getLatestURLFormat": cty.String,
+				"lockURLFormat":      cty.String,
+				"historyURLFormat":   cty.String,
+				"deleteURLFormat":    cty.String,
 			}),
 		},
 	}
@@ -48,10 +51,22 @@ func TestHttpBackend_Configure(t *testing.T) {
 					"urlPrefix":          "kusion-url",
 					"applyURLFormat":     "/apis/v1/tenants/%s/projects/%s/stacks/%s/clusters/%s/states/",
 					"getLatestURLFormat": "/apis/v1/tenants/%s/projects/%s/stacks/%s/clusters/%s/states/",
+					"lockURLFormat":      "/apis/v1/tenants/%s/projects/%s/stacks/%s/clusters/%s/lock/",
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "without_lock",
+			args: args{
+				config: map[string]interface{}{
+					"urlPrefix":          "kusion-url",
+					"applyURLFormat":     "/apis/v1/tenants/%s/projects/%s/stacks/%s/clusters/%s/states/",
+					"getLatestURLFormat": "/apis/v1/tenants/%s/projects/%s/stacks/%s/clusters/%s/states/",
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
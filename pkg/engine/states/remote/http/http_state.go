@@ -37,6 +37,20 @@ type HTTPState struct {
 
 	// getLatestURLFormat is the suffix url format to get the latest state
 	getLatestURLFormat string
+
+	// lockURLFormat is the suffix url format to lock(POST) and unlock(DELETE) a state. A locked state is responded with StatusConflict or StatusLocked
+	// and the LockInfo of the held lock in the body
+	lockURLFormat string
+
+	// historyURLFormat is the suffix url format to get the State history. It is optional and the history is not
+	// supported if it is empty. All States are responded in a JSON array, and the State with a specified serial
+	// is requested with the query parameter "serial"
+	historyURLFormat string
+
+	// deleteURLFormat is the suffix url format to delete States with the DELETE method. It is optional and State
+	// can not be deleted if it is empty. Only the State with a specified serial is deleted if the query parameter
+	// "serial" is given, otherwise the State and all its history are deleted
+	deleteURLFormat string
 }
 
 const ParamsCounts = 4
@@ -71,8 +85,13 @@ func (s *HTTPState) GetLatestState(query *states.StateQuery) (*states.State, err
 	return state, nil
 }
 
-// Apply is an implementation of StateStorage.Apply
+// Apply is an implementation of StateStorage.Apply. The Serial of the latest State this State is based on is sent in
+// the header "If-Match" as an ETag, and the service SHOULD respond StatusPreconditionFailed if it is not the Serial of
+// the latest State stored
 func (s *HTTPState) Apply(state *states.State) error {
+	if state.Serial == 0 {
+		return &states.SerialConflictError{Serial: state.Serial}
+	}
 	jsonState, err := json.Marshal(state)
 	if err != nil {
 		return err
@@ -84,10 +103,19 @@ func (s *HTTPState) Apply(state *states.State) error {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf("\"%d\"", state.Serial-1))
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
+	if res.StatusCode == http.StatusPreconditionFailed {
+		conflictErr := &states.SerialConflictError{Serial: state.Serial}
+		query := &states.StateQuery{Tenant: state.Tenant, Project: state.Project, Stack: state.Stack, Cluster: state.Cluster}
+		if latest, err := s.GetLatestState(query); err == nil && latest != nil {
+			conflictErr.LatestSerial = latest.Serial
+		}
+		return conflictErr
+	}
 	if res.StatusCode != 200 {
 		return fmt.Errorf("apply state failed. StatusCode:%v, Status:%s", res.StatusCode, res.Status)
 	}
@@ -96,7 +124,150 @@ func (s *HTTPState) Apply(state *states.State) error {
 	return nil
 }
 
-// Delete is not support now
-func (s *HTTPState) Delete(id string) error {
-	return errors.New("not supported")
+// Delete is an implementation of StateStorage.Delete
+func (s *HTTPState) Delete(query *states.StateQuery, serial uint64) error {
+	if s.deleteURLFormat == "" {
+		return errors.New("deleteURLFormat is not configured")
+	}
+	url := fmt.Sprintf("%s"+s.deleteURLFormat, s.urlPrefix, query.Tenant, query.Project, query.Stack, query.Cluster)
+	if serial != 0 {
+		url = fmt.Sprintf("%s?serial=%d", url, serial)
+	}
+
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("delete state failed. StatusCode:%v, Status:%s", res.StatusCode, res.Status)
+	}
+	return nil
+}
+
+// GetStateHistory is an implementation of StateStorage.GetStateHistory
+func (s *HTTPState) GetStateHistory(query *states.StateQuery) ([]*states.State, error) {
+	if s.historyURLFormat == "" {
+		return nil, errors.New("historyURLFormat is not configured")
+	}
+	url := fmt.Sprintf("%s"+s.historyURLFormat, s.urlPrefix, query.Tenant, query.Project, query.Stack, query.Cluster)
+	res, err := http.DefaultClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		log.Infof("Can't find the state history by request:%s", url)
+		return nil, nil
+	}
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("get the state history failed. StatusCode:%v, Status:%s", res.StatusCode, res.Status)
+	}
+
+	var history []*states.State
+	resBody, _ := io.ReadAll(res.Body)
+	if err = json.Unmarshal(resBody, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetStateBySerial is an implementation of StateStorage.GetStateBySerial
+func (s *HTTPState) GetStateBySerial(query *states.StateQuery, serial uint64) (*states.State, error) {
+	if s.historyURLFormat == "" {
+		return nil, errors.New("historyURLFormat is not configured")
+	}
+	url := fmt.Sprintf("%s"+s.historyURLFormat+"?serial=%d", s.urlPrefix, query.Tenant, query.Project, query.Stack, query.Cluster, serial)
+	res, err := http.DefaultClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		log.Infof("Can't find the state by request:%s", url)
+		return nil, nil
+	}
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("get the state by serial failed. StatusCode:%v, Status:%s", res.StatusCode, res.Status)
+	}
+
+	state := &states.State{}
+	resBody, _ := io.ReadAll(res.Body)
+	if err = json.Unmarshal(resBody, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Lock is an implementation of StateStorage.Lock
+func (s *HTTPState) Lock(info *states.LockInfo) error {
+	if s.lockURLFormat == "" {
+		return errors.New("lockURLFormat is not configured, can not lock state")
+	}
+	jsonInfo, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s"+s.lockURLFormat, s.urlPrefix, info.Tenant, info.Project, info.Stack, info.Cluster)
+
+	req, err := http.NewRequest("POST", url, strings.NewReader(string(jsonInfo)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusConflict, http.StatusLocked:
+		held := &states.LockInfo{}
+		resBody, _ := io.ReadAll(res.Body)
+		if err = json.Unmarshal(resBody, held); err != nil {
+			return &states.LockError{Err: fmt.Errorf("state is locked and parse lock info failed: %v", err)}
+		}
+		return &states.LockError{Info: held, Err: errors.New("state is locked")}
+	default:
+		return fmt.Errorf("lock state failed. StatusCode:%v, Status:%s", res.StatusCode, res.Status)
+	}
+}
+
+// Unlock is an implementation of StateStorage.Unlock. The ID of the lock is sent in the body and the service
+// is responsible for checking it
+func (s *HTTPState) Unlock(info *states.LockInfo) error {
+	if s.lockURLFormat == "" {
+		return errors.New("lockURLFormat is not configured, can not unlock state")
+	}
+	jsonInfo, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s"+s.lockURLFormat, s.urlPrefix, info.Tenant, info.Project, info.Stack, info.Cluster)
+
+	req, err := http.NewRequest("DELETE", url, strings.NewReader(string(jsonInfo)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unlock state failed. StatusCode:%v, Status:%s", res.StatusCode, res.Status)
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -35,6 +36,7 @@ func TestHTTPState_Apply(t *testing.T) {
 	state.Project = "p"
 	state.Stack = "s"
 	state.Cluster = "c"
+	state.Serial = 1
 
 	tests := []struct {
 		name     string
@@ -80,6 +82,29 @@ func TestHTTPState_Apply(t *testing.T) {
 				}, nil
 			},
 		},
+		{
+			name: "apply_conflict",
+			fields: fields{
+				urlPrefix:          prefix,
+				applyURLFormat:     format,
+				getLatestURLFormat: format,
+			},
+			args: args{state: state},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				var conflictErr *states.SerialConflictError
+				return errors.As(err, &conflictErr)
+			},
+			mockFunc: func(c *http.Client, req *http.Request) (*http.Response, error) {
+				if req.Method == "POST" && req.Header.Get("If-Match") != `"0"` {
+					return nil, fmt.Errorf("unexpected If-Match: %s", req.Header.Get("If-Match"))
+				}
+				return &http.Response{
+					Status:     "PreconditionFailed",
+					StatusCode: 412,
+					Body:       http.NoBody,
+				}, nil
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -187,3 +212,122 @@ func TestHTTPState_GetLatestState(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPState_Lock(t *testing.T) {
+	info := &states.LockInfo{ID: "id", Tenant: "t", Project: "p", Stack: "s", Cluster: "c", Operation: "apply"}
+
+	tests := []struct {
+		name          string
+		lockURLFormat string
+		wantErr       assert.ErrorAssertionFunc
+		mockFunc      interface{}
+	}{
+		{
+			name:          "lock",
+			lockURLFormat: format,
+			wantErr:       assert.NoError,
+			mockFunc: func(c *http.Client, req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					Status:     "Success",
+					StatusCode: 200,
+					Body:       http.NoBody,
+				}, nil
+			},
+		},
+		{
+			name:          "locked",
+			lockURLFormat: format,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				var lockErr *states.LockError
+				return assert.ErrorAs(t, err, &lockErr) && assert.Equal(t, "held", lockErr.Info.ID)
+			},
+			mockFunc: func(c *http.Client, req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					Status:     "Conflict",
+					StatusCode: 409,
+					Body:       io.NopCloser(strings.NewReader(json_util.Marshal2String(&states.LockInfo{ID: "held"}))),
+				}, nil
+			},
+		},
+		{
+			name:          "lock_not_configured",
+			lockURLFormat: "",
+			wantErr:       assert.Error,
+			mockFunc: func(c *http.Client, req *http.Request) (*http.Response, error) {
+				return nil, fmt.Errorf("should not send request")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &HTTPState{
+				urlPrefix:     prefix,
+				lockURLFormat: tt.lockURLFormat,
+			}
+			monkey.Patch((*http.Client).Do, tt.mockFunc)
+			err := s.Lock(info)
+			tt.wantErr(t, err, fmt.Sprintf("Lock(%v)", info))
+		})
+	}
+}
+
+func TestHTTPState_GetStateHistory(t *testing.T) {
+	defer monkey.UnpatchAll()
+	query := &states.StateQuery{Tenant: "t", Project: "p", Stack: "s", Cluster: "c"}
+	history := []*states.State{{Tenant: "t", Project: "p", Stack: "s", Cluster: "c", Serial: 2}, {Tenant: "t", Project: "p", Stack: "s", Cluster: "c", Serial: 1}}
+
+	s := &HTTPState{urlPrefix: prefix, historyURLFormat: format}
+	monkey.Patch((*http.Client).Do, func(c *http.Client, req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("serial") == "1" {
+			return &http.Response{
+				Status:     "Success",
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(json_util.Marshal2String(history[1]))),
+			}, nil
+		}
+		return &http.Response{
+			Status:     "Success",
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(json_util.Marshal2String(history))),
+		}, nil
+	})
+
+	got, err := s.GetStateHistory(query)
+	assert.NoError(t, err)
+	assert.Equal(t, history, got)
+
+	state, err := s.GetStateBySerial(query, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, history[1], state)
+
+	s.historyURLFormat = ""
+	_, err = s.GetStateHistory(query)
+	assert.Error(t, err)
+}
+
+func TestHTTPState_Delete(t *testing.T) {
+	defer monkey.UnpatchAll()
+	query := &states.StateQuery{Tenant: "t", Project: "p", Stack: "s", Cluster: "c"}
+
+	var requested []string
+	s := &HTTPState{urlPrefix: prefix, deleteURLFormat: format}
+	monkey.Patch((*http.Client).Do, func(c *http.Client, req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.Method+" "+req.URL.String())
+		return &http.Response{
+			Status:     "Success",
+			StatusCode: 200,
+			Body:       http.NoBody,
+		}, nil
+	})
+
+	assert.NoError(t, s.Delete(query, 0))
+	assert.NoError(t, s.Delete(query, 2))
+	assert.Equal(t, []string{
+		"DELETE " + prefix + fmt.Sprintf(format, "t", "p", "s", "c"),
+		"DELETE " + prefix + fmt.Sprintf(format, "t", "p", "s", "c") + "?serial=2",
+	}, requested)
+
+	s.deleteURLFormat = ""
+	assert.Error(t, s.Delete(query, 0))
+}
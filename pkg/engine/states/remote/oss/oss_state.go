@@ -4,7 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"gopkg.in/yaml.v3"
@@ -14,7 +20,16 @@ import (
 
 var ErrOSSNoExist = errors.New("oss: key not exist")
 
-const OSSStateName = "kusion_state.json"
+const (
+	OSSStateName = "kusion_state.json"
+	OSSLockName  = "kusion_state.lock"
+
+	// OSSHistoryDir is the directory that keeps every applied State, named by its serial
+	OSSHistoryDir = "history/"
+
+	// OSSClusterDir is the directory that keeps objects of clusters other than the default one
+	OSSClusterDir = "clusters/"
+)
 
 var _ states.StateStorage = &OssState{}
 
@@ -45,27 +60,134 @@ func (s *OssState) Apply(state *states.State) error {
 	if err != nil {
 		return err
 	}
-	prefix := state.Tenant + "/" + state.Project + "/" + state.Stack + "/" + OSSStateName
-	err = s.bucket.PutObject(prefix, bytes.NewReader(jsonByte))
+	prefix := stateDir(state.Tenant, state.Project, state.Stack, state.Cluster) + OSSStateName
+	if state.Cluster == "" {
+		if err = s.moveLegacyState(state.Tenant, state.Project, state.Stack); err != nil {
+			return err
+		}
+	}
+
+	// check the serial with the latest State and put the State only if the latest one is not changed since read
+	latest, etag, err := s.getStateAndETag(prefix)
+	if err != nil {
+		return err
+	}
+	if latest != nil && latest.Cluster != state.Cluster {
+		// the legacy State of another cluster, which is moved out above
+		latest = nil
+	}
+	if latest == nil {
+		// the State of the cluster may be still saved in the legacy object
+		query := &states.StateQuery{Tenant: state.Tenant, Project: state.Project, Stack: state.Stack, Cluster: state.Cluster}
+		if latest, err = s.legacyState(query); err != nil {
+			return err
+		}
+	}
+	if err = states.CheckSerial(latest, state.Serial); err != nil {
+		return err
+	}
+	precondition := oss.ForbidOverWrite(true)
+	if etag != "" {
+		precondition = oss.IfMatch(etag)
+	}
+	err = s.bucket.PutObject(prefix, bytes.NewReader(jsonByte), precondition)
+	if err != nil {
+		var svcErr oss.ServiceError
+		if errors.As(err, &svcErr) && (svcErr.StatusCode == http.StatusPreconditionFailed || svcErr.StatusCode == http.StatusConflict) {
+			conflictErr := &states.SerialConflictError{Serial: state.Serial}
+			if latest, _, readErr := s.getStateAndETag(prefix); readErr == nil && latest != nil {
+				conflictErr.LatestSerial = latest.Serial
+			}
+			return conflictErr
+		}
+		return err
+	}
+
+	// keep a copy of this State in the history directory
+	err = s.bucket.PutObject(historyKey(stateDir(state.Tenant, state.Project, state.Stack, state.Cluster), state.Serial), bytes.NewReader(jsonByte))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (s *OssState) Delete(id string) error {
-	panic("implement me")
+// Delete deletes the State object and all history objects of the stack in the cluster, or only the history object with
+// the specified serial if serial is not 0
+func (s *OssState) Delete(query *states.StateQuery, serial uint64) error {
+	prefix := stateDir(query.Tenant, query.Project, query.Stack, query.Cluster)
+	if serial == 0 {
+		keys, err := s.listKeys(prefix)
+		if err != nil {
+			return err
+		}
+		// the lock is held by the caller and released by itself, and objects of other clusters are kept
+		var toDelete []string
+		for _, key := range keys {
+			if key == prefix+OSSStateName || strings.HasPrefix(key, prefix+OSSHistoryDir) {
+				toDelete = append(toDelete, key)
+			}
+		}
+		// at most 1000 objects can be deleted in one request
+		for start := 0; start < len(toDelete); start += 1000 {
+			end := start + 1000
+			if end > len(toDelete) {
+				end = len(toDelete)
+			}
+			if _, err = s.bucket.DeleteObjects(toDelete[start:end], oss.DeleteObjectsQuiet(true)); err != nil {
+				return err
+			}
+		}
+
+		// the legacy State of the cluster is deleted too, or it would be read as the latest State again
+		legacy, err := s.legacyState(query)
+		if err != nil || legacy == nil {
+			return err
+		}
+		return s.bucket.DeleteObject(stateDir(query.Tenant, query.Project, query.Stack, "") + OSSStateName)
+	}
+
+	target, err := s.GetStateBySerial(query, serial)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("can not find State with serial %d", serial)
+	}
+	if err = s.bucket.DeleteObject(historyKey(stateDir(query.Tenant, query.Project, query.Stack, query.Cluster), serial)); err != nil {
+		return err
+	}
+
+	// the previous State becomes the latest one if the latest State is deleted
+	latest, err := s.GetLatestState(query)
+	if err != nil || latest == nil || latest.Serial != serial {
+		return err
+	}
+	history, err := s.GetStateHistory(query)
+	if err != nil {
+		return err
+	}
+	for _, previous := range history {
+		if previous.Serial == serial {
+			continue
+		}
+		jsonByte, err := json.MarshalIndent(previous, "", "  ")
+		if err != nil {
+			return err
+		}
+		return s.bucket.PutObject(prefix+OSSStateName, bytes.NewReader(jsonByte))
+	}
+	return s.bucket.DeleteObject(prefix + OSSStateName)
 }
 
 func (s *OssState) GetLatestState(query *states.StateQuery) (*states.State, error) {
-	prefix := query.Tenant + "/" + query.Project + "/" + query.Stack + "/" + OSSStateName
+	prefix := stateDir(query.Tenant, query.Project, query.Stack, query.Cluster) + OSSStateName
 	objects, err := s.bucket.ListObjects(oss.Delimiter("/"), oss.Prefix(prefix))
 	if err != nil {
 		return nil, err
 	}
 
 	if len(objects.Objects) == 0 {
-		return nil, nil
+		return s.legacyState(query)
 	}
 
 	body, err := s.bucket.GetObject(prefix)
@@ -84,5 +206,231 @@ func (s *OssState) GetLatestState(query *states.StateQuery) (*states.State, erro
 	if err != nil {
 		return nil, err
 	}
+	if state.Cluster != query.Cluster {
+		// the State object of the default cluster may still keep the legacy State of another cluster
+		return nil, nil
+	}
+	return state, nil
+}
+
+// GetStateHistory reads all States in the history directory
+func (s *OssState) GetStateHistory(query *states.StateQuery) ([]*states.State, error) {
+	prefix := stateDir(query.Tenant, query.Project, query.Stack, query.Cluster) + OSSHistoryDir
+
+	keys, err := s.listKeys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []*states.State
+	for _, key := range keys {
+		if _, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(key, prefix), ".json"), 10, 64); err != nil {
+			continue
+		}
+		state, err := s.getState(key)
+		if err != nil {
+			return nil, err
+		}
+		// the history directory of the default cluster may still keep legacy States of other clusters
+		if state.Cluster != query.Cluster {
+			continue
+		}
+		history = append(history, state)
+	}
+	if len(history) == 0 {
+		// the latest State may be applied before the history directory is introduced
+		latest, err := s.GetLatestState(query)
+		if err != nil || latest == nil {
+			return nil, err
+		}
+		return []*states.State{latest}, nil
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Serial > history[j].Serial
+	})
+	return history, nil
+}
+
+// GetStateBySerial reads the State with the specified serial in the history directory
+func (s *OssState) GetStateBySerial(query *states.StateQuery, serial uint64) (*states.State, error) {
+	state, err := s.getState(historyKey(stateDir(query.Tenant, query.Project, query.Stack, query.Cluster), serial))
+	if err == nil {
+		if state.Cluster != query.Cluster {
+			return nil, nil
+		}
+		return state, nil
+	}
+	var svcErr oss.ServiceError
+	if !errors.As(err, &svcErr) || svcErr.StatusCode != http.StatusNotFound {
+		return nil, err
+	}
+
+	// the latest State may be applied before the history directory is introduced
+	latest, err := s.GetLatestState(query)
+	if err != nil || latest == nil || latest.Serial != serial {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// legacyState returns the State of the cluster in the State object of the default cluster, where States of all clusters
+// were saved before objects of clusters are separated. It returns nil for the default cluster, or if the legacy State
+// belongs to another cluster. The next apply of the cluster saves its State in the directory of the cluster
+func (s *OssState) legacyState(query *states.StateQuery) (*states.State, error) {
+	if query.Cluster == "" {
+		return nil, nil
+	}
+	state, _, err := s.getStateAndETag(stateDir(query.Tenant, query.Project, query.Stack, "") + OSSStateName)
+	if err != nil || state == nil || state.Cluster != query.Cluster {
+		return nil, err
+	}
 	return state, nil
 }
+
+// moveLegacyState moves the legacy State of another cluster in the State object of the default cluster to the directory
+// of that cluster if it has no State object, so that the State is not lost when the default cluster overwrites the object
+func (s *OssState) moveLegacyState(tenant, project, stack string) error {
+	legacy, _, err := s.getStateAndETag(stateDir(tenant, project, stack, "") + OSSStateName)
+	if err != nil || legacy == nil || legacy.Cluster == "" {
+		return err
+	}
+	jsonByte, err := json.MarshalIndent(legacy, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the State object of the cluster is kept if it exists
+	err = s.bucket.PutObject(stateDir(tenant, project, stack, legacy.Cluster)+OSSStateName, bytes.NewReader(jsonByte), oss.ForbidOverWrite(true))
+	var svcErr oss.ServiceError
+	if errors.As(err, &svcErr) && (svcErr.StatusCode == http.StatusPreconditionFailed || svcErr.StatusCode == http.StatusConflict) {
+		return nil
+	}
+	return err
+}
+
+func (s *OssState) listKeys(prefix string) ([]string, error) {
+	var keys []string
+	marker := ""
+	for {
+		objects, err := s.bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker))
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects.Objects {
+			keys = append(keys, object.Key)
+		}
+		if !objects.IsTruncated {
+			break
+		}
+		marker = objects.NextMarker
+	}
+	return keys, nil
+}
+
+// getStateAndETag returns the State object and its ETag, a nil State is returned if the object doesn't exist
+func (s *OssState) getStateAndETag(key string) (*states.State, string, error) {
+	meta, err := s.bucket.GetObjectDetailedMeta(key)
+	if err != nil {
+		var svcErr oss.ServiceError
+		if errors.As(err, &svcErr) && svcErr.StatusCode == http.StatusNotFound {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	etag := meta.Get(oss.HTTPHeaderEtag)
+	// make sure the content read is the same version as the ETag
+	state, err := s.getState(key, oss.IfMatch(etag))
+	if err != nil {
+		return nil, "", err
+	}
+	return state, etag, nil
+}
+
+func (s *OssState) getState(key string, options ...oss.Option) (*states.State, error) {
+	body, err := s.bucket.GetObject(key, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	state := &states.State{}
+	// JSON is a subset of YAML. Please check FileSystemState.GetLatestState for detail explanation
+	if err = yaml.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// stateDir returns the directory of objects of the stack in the cluster. Objects of the default cluster are placed in
+// the directory of the stack, and objects of other clusters are placed in sub directories named by clusters
+func stateDir(tenant, project, stack, cluster string) string {
+	dir := tenant + "/" + project + "/" + stack + "/"
+	if cluster != "" {
+		dir += OSSClusterDir + url.PathEscape(cluster) + "/"
+	}
+	return dir
+}
+
+func historyKey(dir string, serial uint64) string {
+	return dir + OSSHistoryDir + strconv.FormatUint(serial, 10) + ".json"
+}
+
+// Lock puts a lock object with the header "x-oss-forbid-overwrite: true", so the write only succeeds if the lock object doesn't exist
+func (s *OssState) Lock(info *states.LockInfo) error {
+	jsonByte, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	key := lockKey(info)
+	err = s.bucket.PutObject(key, bytes.NewReader(jsonByte), oss.ForbidOverWrite(true))
+	if err != nil {
+		var svcErr oss.ServiceError
+		if errors.As(err, &svcErr) && svcErr.StatusCode == http.StatusConflict {
+			held, readErr := s.getLock(key)
+			if readErr != nil {
+				return &states.LockError{Err: fmt.Errorf("state is locked and read lock info failed: %v", readErr)}
+			}
+			return &states.LockError{Info: held, Err: errors.New("state is locked")}
+		}
+		return err
+	}
+	return nil
+}
+
+// Unlock deletes the lock object if the ID recorded in it equals info.ID
+func (s *OssState) Unlock(info *states.LockInfo) error {
+	key := lockKey(info)
+	held, err := s.getLock(key)
+	if err != nil {
+		return err
+	}
+	if err = states.CheckLockID(held, info.ID); err != nil {
+		return err
+	}
+	return s.bucket.DeleteObject(key)
+}
+
+func (s *OssState) getLock(key string) (*states.LockInfo, error) {
+	body, err := s.bucket.GetObject(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	info := &states.LockInfo{}
+	if err = json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func lockKey(info *states.LockInfo) string {
+	return stateDir(info.Tenant, info.Project, info.Stack, info.Cluster) + OSSLockName
+}
@@ -6,6 +6,7 @@ package oss
 import (
 	"encoding/json"
 	"io"
+	"net/http"
 	"testing"
 	"time"
 
@@ -28,6 +29,9 @@ func SetUp(t *testing.T) *OssState {
 	monkey.Patch(oss.Bucket.PutObject, func(b oss.Bucket, objectKey string, reader io.Reader, options ...oss.Option) error {
 		return nil
 	})
+	monkey.Patch(oss.Bucket.GetObjectDetailedMeta, func(b oss.Bucket, objectKey string, options ...oss.Option) (http.Header, error) {
+		return http.Header{oss.HTTPHeaderEtag: []string{"etag"}}, nil
+	})
 	monkey.Patch(oss.Bucket.ListObjects, func(b oss.Bucket, options ...oss.Option) (oss.ListObjectsResult, error) {
 		return oss.ListObjectsResult{Objects: []oss.ObjectProperties{{LastModified: time.Now()}}}, nil
 	})
@@ -45,18 +49,79 @@ func TestOssState(t *testing.T) {
 	ossState := SetUp(t)
 	_, err := NewOSSState("test_endpoint", "test_access_id", "test_access_secret", "testbucket")
 	assert.NoError(t, err)
-	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 1}
 	err = ossState.Apply(state)
 	assert.NoError(t, err)
 	query := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
 	latestState, err := ossState.GetLatestState(query)
 	assert.NoError(t, err)
-	assert.Equal(t, state, latestState)
-
-	defer func() {
-		if r := recover(); r != "implement me" {
-			t.Errorf("Delete() got: %v, want: 'implement me'", r)
-		}
-	}()
-	ossState.Delete("test")
+	assert.Equal(t, &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}, latestState)
+
+	// the serial doesn't follow the latest one
+	err = ossState.Apply(&states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 2})
+	var conflictErr *states.SerialConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+
+	// the latest State is changed since read
+	monkey.Patch(oss.Bucket.PutObject, func(b oss.Bucket, objectKey string, reader io.Reader, options ...oss.Option) error {
+		return oss.ServiceError{StatusCode: http.StatusPreconditionFailed}
+	})
+	err = ossState.Apply(state)
+	assert.ErrorAs(t, err, &conflictErr)
+
+	var deleted []string
+	monkey.Patch(oss.Bucket.DeleteObjects, func(b oss.Bucket, objectKeys []string, options ...oss.Option) (oss.DeleteObjectsResult, error) {
+		deleted = append(deleted, objectKeys...)
+		return oss.DeleteObjectsResult{}, nil
+	})
+	monkey.Patch(oss.Bucket.ListObjects, func(b oss.Bucket, options ...oss.Option) (oss.ListObjectsResult, error) {
+		return oss.ListObjectsResult{Objects: []oss.ObjectProperties{
+			{Key: "test_global_tenant/test_project/test_env/" + OSSStateName},
+			{Key: "test_global_tenant/test_project/test_env/" + OSSLockName},
+			{Key: "test_global_tenant/test_project/test_env/" + OSSHistoryDir + "1.json"},
+			{Key: "test_global_tenant/test_project/test_env/" + OSSClusterDir + "prod/" + OSSStateName},
+			{Key: "test_global_tenant/test_project/test_env/" + OSSClusterDir + "prod/" + OSSHistoryDir + "1.json"},
+		}}, nil
+	})
+	err = ossState.Delete(query, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"test_global_tenant/test_project/test_env/" + OSSStateName,
+		"test_global_tenant/test_project/test_env/" + OSSHistoryDir + "1.json",
+	}, deleted)
+}
+
+func TestStateDir(t *testing.T) {
+	assert.Equal(t, "tenant/project/stack/", stateDir("tenant", "project", "stack", ""))
+	assert.Equal(t, "tenant/project/stack/clusters/prod/", stateDir("tenant", "project", "stack", "prod"))
+	assert.Equal(t, "tenant/project/stack/clusters/prod/"+OSSLockName,
+		lockKey(&states.LockInfo{Tenant: "tenant", Project: "project", Stack: "stack", Cluster: "prod"}))
+}
+
+func TestOssState_LegacyStateOfDefaultCluster(t *testing.T) {
+	defer monkey.UnpatchAll()
+	ossState := SetUp(t)
+
+	// the stale State of the prod cluster in the State object of the default cluster
+	legacy := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod", Serial: 3}
+	jsonByte, _ := json.MarshalIndent(legacy, "", "  ")
+	monkey.Patch(oss.Bucket.GetObject, func(b oss.Bucket, objectKey string, options ...oss.Option) (io.ReadCloser, error) {
+		return mocks.NewBody(string(jsonByte)), nil
+	})
+	var put []string
+	monkey.Patch(oss.Bucket.PutObject, func(b oss.Bucket, objectKey string, reader io.Reader, options ...oss.Option) error {
+		put = append(put, objectKey)
+		return nil
+	})
+
+	query := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+	latest, err := ossState.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	// the first apply of the default cluster moves the State of the prod cluster out before overwriting the object
+	err = ossState.Apply(&states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, stateDir("test_global_tenant", "test_project", "test_env", "prod")+OSSStateName, put[0])
+	assert.Equal(t, stateDir("test_global_tenant", "test_project", "test_env", "")+OSSStateName, put[1])
 }
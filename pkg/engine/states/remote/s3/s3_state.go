@@ -4,9 +4,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -16,7 +23,16 @@ import (
 
 var ErrS3NoExist = errors.New("s3: key not exist")
 
-const S3StateName = "kusion_state.json"
+const (
+	S3StateName = "kusion_state.json"
+	S3LockName  = "kusion_state.lock"
+
+	// S3HistoryDir is the directory that keeps every applied State, named by its serial
+	S3HistoryDir = "history/"
+
+	// S3ClusterDir is the directory that keeps objects of clusters other than the default one
+	S3ClusterDir = "clusters/"
+)
 
 var _ states.StateStorage = &S3State{}
 
@@ -48,13 +64,60 @@ func (s *S3State) Apply(state *states.State) error {
 	if err != nil {
 		return err
 	}
-	prefix := state.Tenant + "/" + state.Project + "/" + state.Stack + "/" + S3StateName
+	prefix := stateDir(state.Tenant, state.Project, state.Stack, state.Cluster) + S3StateName
 	s3Client := s3.New(s.sess)
-	_, err = s3Client.PutObject(&s3.PutObjectInput{
+	if state.Cluster == "" {
+		if err = s.moveLegacyState(state.Tenant, state.Project, state.Stack); err != nil {
+			return err
+		}
+	}
+
+	// check the serial with the latest State and put the State only if the latest one is not changed since read
+	latest, etag, err := s.getStateAndETag(prefix)
+	if err != nil && !isNoSuchKey(err) {
+		return err
+	}
+	if latest != nil && latest.Cluster != state.Cluster {
+		// the legacy State of another cluster, which is moved out above
+		latest = nil
+	}
+	if latest == nil {
+		// the State of the cluster may be still saved in the legacy object
+		query := &states.StateQuery{Tenant: state.Tenant, Project: state.Project, Stack: state.Stack, Cluster: state.Cluster}
+		if latest, err = s.legacyState(query); err != nil {
+			return err
+		}
+	}
+	if err = states.CheckSerial(latest, state.Serial); err != nil {
+		return err
+	}
+	req, _ := s3Client.PutObjectRequest(&s3.PutObjectInput{
 		Bucket: aws.String(s.bucketName),
 		Key:    aws.String(prefix),
 		Body:   bytes.NewReader(jsonByte),
 	})
+	if etag == "" {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	} else {
+		req.HTTPRequest.Header.Set("If-Match", etag)
+	}
+	if err = req.Send(); err != nil {
+		if isPreconditionFailed(err) {
+			conflictErr := &states.SerialConflictError{Serial: state.Serial}
+			if latest, _, readErr := s.getStateAndETag(prefix); readErr == nil {
+				conflictErr.LatestSerial = latest.Serial
+			}
+			return conflictErr
+		}
+		return err
+	}
+
+	// keep a copy of this State in the history directory
+	_, err = s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(historyKey(stateDir(state.Tenant, state.Project, state.Stack, state.Cluster), state.Serial)),
+		Body:   bytes.NewReader(jsonByte),
+	})
 	if err != nil {
 		return err
 	}
@@ -62,12 +125,98 @@ func (s *S3State) Apply(state *states.State) error {
 	return nil
 }
 
-func (s *S3State) Delete(id string) error {
-	panic("implement me")
+// Delete deletes the State object and all history objects of the stack in the cluster, or only the history object with
+// the specified serial if serial is not 0
+func (s *S3State) Delete(query *states.StateQuery, serial uint64) error {
+	prefix := stateDir(query.Tenant, query.Project, query.Stack, query.Cluster)
+	s3Client := s3.New(s.sess)
+	if serial == 0 {
+		keys, err := s.listKeys(prefix)
+		if err != nil {
+			return err
+		}
+		// the lock is held by the caller and released by itself, and objects of other clusters are kept
+		var toDelete []*s3.ObjectIdentifier
+		for _, key := range keys {
+			if key == prefix+S3StateName || strings.HasPrefix(key, prefix+S3HistoryDir) {
+				toDelete = append(toDelete, &s3.ObjectIdentifier{Key: aws.String(key)})
+			}
+		}
+		// at most 1000 objects can be deleted in one request
+		for start := 0; start < len(toDelete); start += 1000 {
+			end := start + 1000
+			if end > len(toDelete) {
+				end = len(toDelete)
+			}
+			_, err = s3Client.DeleteObjects(&s3.DeleteObjectsInput{
+				Bucket: aws.String(s.bucketName),
+				Delete: &s3.Delete{Objects: toDelete[start:end], Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// the legacy State of the cluster is deleted too, or it would be read as the latest State again
+		legacy, err := s.legacyState(query)
+		if err != nil || legacy == nil {
+			return err
+		}
+		_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(stateDir(query.Tenant, query.Project, query.Stack, "") + S3StateName),
+		})
+		return err
+	}
+
+	target, err := s.GetStateBySerial(query, serial)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("can not find State with serial %d", serial)
+	}
+	_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(historyKey(stateDir(query.Tenant, query.Project, query.Stack, query.Cluster), serial)),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the previous State becomes the latest one if the latest State is deleted
+	latest, err := s.GetLatestState(query)
+	if err != nil || latest == nil || latest.Serial != serial {
+		return err
+	}
+	history, err := s.GetStateHistory(query)
+	if err != nil {
+		return err
+	}
+	for _, previous := range history {
+		if previous.Serial == serial {
+			continue
+		}
+		jsonByte, err := json.MarshalIndent(previous, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(prefix + S3StateName),
+			Body:   bytes.NewReader(jsonByte),
+		})
+		return err
+	}
+	_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(prefix + S3StateName),
+	})
+	return err
 }
 
 func (s *S3State) GetLatestState(query *states.StateQuery) (*states.State, error) {
-	prefix := query.Tenant + "/" + query.Project + "/" + query.Stack + "/" + S3StateName
+	prefix := stateDir(query.Tenant, query.Project, query.Stack, query.Cluster) + S3StateName
 	s3Client := s3.New(s.sess)
 
 	params := &s3.ListObjectsInput{
@@ -82,7 +231,7 @@ func (s *S3State) GetLatestState(query *states.StateQuery) (*states.State, error
 	}
 
 	if len(objects.Contents) == 0 {
-		return nil, nil
+		return s.legacyState(query)
 	}
 
 	out, err := s3Client.GetObject(&s3.GetObjectInput{
@@ -103,5 +252,260 @@ func (s *S3State) GetLatestState(query *states.StateQuery) (*states.State, error
 	if err != nil {
 		return nil, err
 	}
+	if state.Cluster != query.Cluster {
+		// the State object of the default cluster may still keep the legacy State of another cluster
+		return nil, nil
+	}
+	return state, nil
+}
+
+// GetStateHistory reads all States in the history directory
+func (s *S3State) GetStateHistory(query *states.StateQuery) ([]*states.State, error) {
+	prefix := stateDir(query.Tenant, query.Project, query.Stack, query.Cluster) + S3HistoryDir
+	keys, err := s.listKeys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []*states.State
+	for _, key := range keys {
+		if _, err = strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(key, prefix), ".json"), 10, 64); err != nil {
+			continue
+		}
+		state, err := s.getState(key)
+		if err != nil {
+			return nil, err
+		}
+		// the history directory of the default cluster may still keep legacy States of other clusters
+		if state.Cluster != query.Cluster {
+			continue
+		}
+		history = append(history, state)
+	}
+	if len(history) == 0 {
+		// the latest State may be applied before the history directory is introduced
+		latest, err := s.GetLatestState(query)
+		if err != nil || latest == nil {
+			return nil, err
+		}
+		return []*states.State{latest}, nil
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Serial > history[j].Serial
+	})
+	return history, nil
+}
+
+// GetStateBySerial reads the State with the specified serial in the history directory
+func (s *S3State) GetStateBySerial(query *states.StateQuery, serial uint64) (*states.State, error) {
+	state, err := s.getState(historyKey(stateDir(query.Tenant, query.Project, query.Stack, query.Cluster), serial))
+	if err == nil {
+		if state.Cluster != query.Cluster {
+			return nil, nil
+		}
+		return state, nil
+	}
+	if !isNoSuchKey(err) {
+		return nil, err
+	}
+
+	// the latest State may be applied before the history directory is introduced
+	latest, err := s.GetLatestState(query)
+	if err != nil || latest == nil || latest.Serial != serial {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// legacyState returns the State of the cluster in the State object of the default cluster, where States of all clusters
+// were saved before objects of clusters are separated. It returns nil for the default cluster, or if the legacy State
+// belongs to another cluster. The next apply of the cluster saves its State in the directory of the cluster
+func (s *S3State) legacyState(query *states.StateQuery) (*states.State, error) {
+	if query.Cluster == "" {
+		return nil, nil
+	}
+	state, err := s.getState(stateDir(query.Tenant, query.Project, query.Stack, "") + S3StateName)
+	if err != nil {
+		if isNoSuchKey(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if state.Cluster != query.Cluster {
+		return nil, nil
+	}
 	return state, nil
 }
+
+// moveLegacyState moves the legacy State of another cluster in the State object of the default cluster to the directory
+// of that cluster if it has no State object, so that the State is not lost when the default cluster overwrites the object
+func (s *S3State) moveLegacyState(tenant, project, stack string) error {
+	legacy, err := s.getState(stateDir(tenant, project, stack, "") + S3StateName)
+	if err != nil {
+		if isNoSuchKey(err) {
+			return nil
+		}
+		return err
+	}
+	if legacy.Cluster == "" {
+		return nil
+	}
+	jsonByte, err := json.MarshalIndent(legacy, "", "  ")
+	if err != nil {
+		return err
+	}
+	s3Client := s3.New(s.sess)
+	req, _ := s3Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(stateDir(tenant, project, stack, legacy.Cluster) + S3StateName),
+		Body:   bytes.NewReader(jsonByte),
+	})
+	// the State object of the cluster is kept if it exists
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
+	if err = req.Send(); err != nil && !isPreconditionFailed(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *S3State) listKeys(prefix string) ([]string, error) {
+	s3Client := s3.New(s.sess)
+
+	var keys []string
+	err := s3Client.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket: aws.String(s.bucketName),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *S3State) getState(key string) (*states.State, error) {
+	state, _, err := s.getStateAndETag(key)
+	return state, err
+}
+
+func (s *S3State) getStateAndETag(key string) (*states.State, string, error) {
+	s3Client := s3.New(s.sess)
+	out, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	state := &states.State{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, "", err
+	}
+	return state, aws.StringValue(out.ETag), nil
+}
+
+func isNoSuchKey(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey
+}
+
+func isPreconditionFailed(err error) bool {
+	var reqErr awserr.RequestFailure
+	return errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusPreconditionFailed
+}
+
+// stateDir returns the directory of objects of the stack in the cluster. Objects of the default cluster are placed in
+// the directory of the stack, and objects of other clusters are placed in sub directories named by clusters
+func stateDir(tenant, project, stack, cluster string) string {
+	dir := tenant + "/" + project + "/" + stack + "/"
+	if cluster != "" {
+		dir += S3ClusterDir + url.PathEscape(cluster) + "/"
+	}
+	return dir
+}
+
+func historyKey(dir string, serial uint64) string {
+	return dir + S3HistoryDir + strconv.FormatUint(serial, 10) + ".json"
+}
+
+// Lock puts a lock object with the header "If-None-Match: *", so the write only succeeds if the lock object doesn't exist
+func (s *S3State) Lock(info *states.LockInfo) error {
+	jsonByte, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	key := lockKey(info)
+	s3Client := s3.New(s.sess)
+	req, _ := s3Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(jsonByte),
+	})
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
+	if err = req.Send(); err != nil {
+		if isPreconditionFailed(err) {
+			held, readErr := s.getLock(key)
+			if readErr != nil {
+				return &states.LockError{Err: fmt.Errorf("state is locked and read lock info failed: %v", readErr)}
+			}
+			return &states.LockError{Info: held, Err: errors.New("state is locked")}
+		}
+		return err
+	}
+	return nil
+}
+
+// Unlock deletes the lock object if the ID recorded in it equals info.ID
+func (s *S3State) Unlock(info *states.LockInfo) error {
+	key := lockKey(info)
+	held, err := s.getLock(key)
+	if err != nil {
+		return err
+	}
+	if err = states.CheckLockID(held, info.ID); err != nil {
+		return err
+	}
+
+	s3Client := s3.New(s.sess)
+	_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *S3State) getLock(key string) (*states.LockInfo, error) {
+	s3Client := s3.New(s.sess)
+	out, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	info := &states.LockInfo{}
+	if err = json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func lockKey(info *states.LockInfo) string {
+	return stateDir(info.Tenant, info.Project, info.Stack, info.Cluster) + S3LockName
+}
@@ -5,6 +5,7 @@ package s3
 
 import (
 	"encoding/json"
+	"net/http"
 	"testing"
 	"time"
 
@@ -14,7 +15,9 @@ import (
 
 	"github.com/Azure/go-autorest/autorest/mocks"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/stretchr/testify/assert"
@@ -30,6 +33,12 @@ func S3StateSetUp(t *testing.T) *S3State {
 	monkey.Patch((*s3.S3).PutObject, func(c *s3.S3, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
 		return nil, nil
 	})
+	monkey.Patch((*s3.S3).PutObjectRequest, func(c *s3.S3, input *s3.PutObjectInput) (*request.Request, *s3.PutObjectOutput) {
+		return &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}, &s3.PutObjectOutput{}
+	})
+	monkey.Patch((*request.Request).Send, func(r *request.Request) error {
+		return nil
+	})
 
 	monkey.Patch((*s3.S3).ListObjects, func(c *s3.S3, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
 		return &s3.ListObjectsOutput{Contents: []*s3.Object{{LastModified: aws.Time(time.Now())}}}, nil
@@ -52,18 +61,108 @@ func TestS3State(t *testing.T) {
 
 	_, err := NewS3State("test_endpoint", "test_access_key", "test_access_secret", "test_bucket", "test_region")
 	assert.NoError(t, err)
-	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+	state := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 1}
 	err = s3State.Apply(state)
 	assert.NoError(t, err)
 	query := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
 	latestState, err := s3State.GetLatestState(query)
 	assert.NoError(t, err)
-	assert.Equal(t, state, latestState)
+	assert.Equal(t, &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}, latestState)
+
+	// the serial doesn't follow the latest one
+	err = s3State.Apply(&states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 2})
+	var conflictErr *states.SerialConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+
+	// the latest State is changed since read
+	monkey.Patch((*request.Request).Send, func(r *request.Request) error {
+		assert.Equal(t, "*", r.HTTPRequest.Header.Get("If-None-Match"))
+		return awserr.NewRequestFailure(awserr.New("PreconditionFailed", "", nil), http.StatusPreconditionFailed, "")
+	})
+	err = s3State.Apply(state)
+	assert.ErrorAs(t, err, &conflictErr)
 
-	defer func() {
-		if r := recover(); r != "implement me" {
-			t.Errorf("Delete() got: %v, want: 'implement me'", r)
+	var deleted []string
+	monkey.Patch((*s3.S3).ListObjectsPages, func(c *s3.S3, input *s3.ListObjectsInput, fn func(*s3.ListObjectsOutput, bool) bool) error {
+		fn(&s3.ListObjectsOutput{Contents: []*s3.Object{
+			{Key: aws.String("test_global_tenant/test_project/test_env/" + S3StateName)},
+			{Key: aws.String("test_global_tenant/test_project/test_env/" + S3LockName)},
+			{Key: aws.String("test_global_tenant/test_project/test_env/" + S3HistoryDir + "1.json")},
+			{Key: aws.String("test_global_tenant/test_project/test_env/" + S3ClusterDir + "prod/" + S3StateName)},
+			{Key: aws.String("test_global_tenant/test_project/test_env/" + S3ClusterDir + "prod/" + S3HistoryDir + "1.json")},
+		}}, true)
+		return nil
+	})
+	monkey.Patch((*s3.S3).DeleteObjects, func(c *s3.S3, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+		for _, object := range input.Delete.Objects {
+			deleted = append(deleted, aws.StringValue(object.Key))
 		}
-	}()
-	s3State.Delete("test")
+		return &s3.DeleteObjectsOutput{}, nil
+	})
+	err = s3State.Delete(query, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"test_global_tenant/test_project/test_env/" + S3StateName,
+		"test_global_tenant/test_project/test_env/" + S3HistoryDir + "1.json",
+	}, deleted)
+}
+
+func TestS3State_LegacyStateOfDefaultCluster(t *testing.T) {
+	defer monkey.UnpatchAll()
+	s3State := S3StateSetUp(t)
+
+	// the stale State of the prod cluster in the State object of the default cluster
+	legacy := &states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Cluster: "prod", Serial: 3}
+	jsonByte, _ := json.MarshalIndent(legacy, "", "  ")
+	monkey.Patch((*s3.S3).GetObject, func(c *s3.S3, input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+		return &s3.GetObjectOutput{Body: mocks.NewBody(string(jsonByte))}, nil
+	})
+	var put []string
+	monkey.Patch((*s3.S3).PutObjectRequest, func(c *s3.S3, input *s3.PutObjectInput) (*request.Request, *s3.PutObjectOutput) {
+		put = append(put, aws.StringValue(input.Key))
+		return &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}, &s3.PutObjectOutput{}
+	})
+
+	query := &states.StateQuery{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env"}
+	latest, err := s3State.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	// the first apply of the default cluster moves the State of the prod cluster out before overwriting the object
+	err = s3State.Apply(&states.State{Tenant: "test_global_tenant", Project: "test_project", Stack: "test_env", Serial: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"test_global_tenant/test_project/test_env/" + S3ClusterDir + "prod/" + S3StateName,
+		"test_global_tenant/test_project/test_env/" + S3StateName,
+	}, put)
+}
+
+func TestStateDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster string
+		want    string
+	}{
+		{
+			name: "default cluster",
+			want: "tenant/project/stack/",
+		},
+		{
+			name:    "cluster",
+			cluster: "prod",
+			want:    "tenant/project/stack/clusters/prod/",
+		},
+		{
+			name:    "escaped cluster",
+			cluster: "arn:aws:eks:us-east-1:123456789012:cluster/prod",
+			want:    "tenant/project/stack/clusters/arn:aws:eks:us-east-1:123456789012:cluster%2Fprod/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, stateDir("tenant", "project", "stack", tt.cluster))
+		})
+	}
+	assert.Equal(t, "tenant/project/stack/clusters/prod/"+S3LockName,
+		lockKey(&states.LockInfo{Tenant: "tenant", Project: "project", Stack: "stack", Cluster: "prod"}))
 }
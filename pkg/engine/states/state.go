@@ -13,11 +13,26 @@ type StateStorage interface {
 	// GetLatestState return nil if state not exists
 	GetLatestState(query *StateQuery) (*State, error)
 
-	// Apply means update this state if it already exists or create a new one
+	// Apply means update this state if it already exists or create a new one.
+	// The Serial of state MUST be the next Serial of the latest State, otherwise a SerialConflictError is returned
 	Apply(state *State) error
 
-	// Delete State by id
-	Delete(id string) error
+	// Delete deletes the State matched by query. If serial is 0, the State and all its history are deleted,
+	// otherwise only the State with the specified serial is deleted and the previous one becomes the latest
+	Delete(query *StateQuery, serial uint64) error
+
+	// GetStateHistory returns all States matched by query, ordered by Serial from the newest to the oldest
+	GetStateHistory(query *StateQuery) ([]*State, error)
+
+	// GetStateBySerial returns the State matched by query with the specified Serial, nil if it does not exist
+	GetStateBySerial(query *StateQuery, serial uint64) (*State, error)
+
+	// Lock acquires the lock of the State described in info and returns a LockError if the lock is held by others.
+	// Operations that modify the State should hold this lock during the whole operation
+	Lock(info *LockInfo) error
+
+	// Unlock releases the lock of the State described in info. The lock is only released when the ID of the held lock equals info.ID
+	Unlock(info *LockInfo) error
 }
 
 type StateQuery struct {
@@ -67,6 +82,10 @@ type State struct {
 	// Resources records all resources in this operation
 	Resources models.Resources `json:"resources" yaml:"resources"`
 
+	// Ciphertext is the encrypted Resources if this State is encrypted at rest, and Resources is empty in this case.
+	// It always starts with CiphertextPrefix
+	Ciphertext string `json:"ciphertext,omitempty" yaml:"ciphertext,omitempty"`
+
 	// CreateTime is the time State is created
 	CreateTime time.Time `json:"createTime" yaml:"createTime"`
 
@@ -74,6 +93,14 @@ type State struct {
 	ModifiedTime time.Time `json:"modifiedTime,omitempty" yaml:"modifiedTime"`
 }
 
+// CiphertextPrefix is the prefix of State.Ciphertext, which is used to tell encrypted Resources from plaintext ones
+const CiphertextPrefix = "kusion+encrypted:"
+
+// IsEncrypted returns true if the Resources of this State are encrypted
+func (s *State) IsEncrypted() bool {
+	return s.Ciphertext != ""
+}
+
 func NewState() *State {
 	s := &State{
 		KusionVersion: version.ReleaseVersion(),
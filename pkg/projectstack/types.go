@@ -117,6 +117,18 @@ func (p *Project) TableReport() string {
 // StackConfiguration is the stack configuration
 type StackConfiguration struct {
 	Name string `json:"name" yaml:"name"` // Stack name
+
+	// Clusters maps cluster names to Kubernetes clusters, which can be referred by the cluster extension of resources
+	Clusters map[string]*KubernetesCluster `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+}
+
+// KubernetesCluster locates a Kubernetes cluster by a kubeconfig file and a context in it
+type KubernetesCluster struct {
+	// KubeConfig is the path of the kubeconfig file, relative to the stack directory. The default kubeconfig is used if empty
+	KubeConfig string `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+
+	// Context is the context name in the kubeconfig file. The current context is used if empty
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
 }
 
 type Stack struct {
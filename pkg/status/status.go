@@ -27,6 +27,8 @@ const (
 	Internal         Code = "INTERNAL"
 	Unauthenticated  Code = "UNAUTHENTICATED"
 	IllegalManifest  Code = "ILLEGAL_MANIFEST"
+	Conflict         Code = "CONFLICT"
+	DeadlineExceeded Code = "DEADLINE_EXCEEDED"
 )
 
 type Status interface {
@@ -1,8 +1,11 @@
 package signals
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"kusionstack.io/kusion/pkg/log"
@@ -10,13 +13,42 @@ import (
 
 var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
 
-// listen for interrupts or the SIGTERM signal and
-// executing clean job
-func HandleInterrupt() {
-	stopCh := make(chan os.Signal, 1)
+// exit is replaced in tests
+var exit = os.Exit
+
+// NotifyContext returns a copy of the parent context which is cancelled at the first interrupt or SIGTERM signal, so
+// that the running operation stops starting new resources, waits for resources in progress and saves its State. The
+// process exits at the second signal. Calling the returned stop function stops listening for signals
+func NotifyContext(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	stopCh := make(chan os.Signal, 2)
+	doneCh := make(chan struct{})
 	signal.Notify(stopCh, shutdownSignals...)
+
 	go func() {
-		<-stopCh
-		log.Info("Received termination, signaling shutdown, executing clean job")
+		select {
+		case <-stopCh:
+		case <-doneCh:
+			return
+		}
+		log.Info("Received termination, cancelling the running operation")
+		fmt.Fprintln(os.Stderr, "\nInterrupted, waiting for running resources to finish and saving the State. Interrupt again to exit immediately")
+		cancel()
+
+		select {
+		case <-stopCh:
+			log.Info("Received termination again, exit immediately")
+			exit(1)
+		case <-doneCh:
+		}
 	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(stopCh)
+			close(doneCh)
+			cancel()
+		})
+	}
 }
@@ -0,0 +1,46 @@
+//go:build !windows
+// +build !windows
+
+package signals
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyContext(t *testing.T) {
+	exited := make(chan int, 1)
+	defer func(e func(int)) { exit = e }(exit)
+	exit = func(code int) { exited <- code }
+
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+
+	// the first signal cancels the context
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context is not cancelled")
+	}
+
+	// the second signal exits the process
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	select {
+	case code := <-exited:
+		assert.Equal(t, 1, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("process doesn't exit")
+	}
+}
+
+func TestNotifyContext_Stop(t *testing.T) {
+	ctx, stop := NotifyContext(context.Background())
+	stop()
+	stop()
+	assert.Error(t, ctx.Err())
+}
@@ -0,0 +1,76 @@
+package vals
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// NewClient returns a Vault client configured and authenticated in the same way as the vault secret store of vals,
+// so that other Vault secrets engines like transit can be used with the same configuration.
+// Only auth methods token and approle are supported.
+func (v *Vault) NewClient() (*vaultapi.Client, error) {
+	cfg := vaultapi.DefaultConfig()
+	if v.Address != "" {
+		cfg.Address = v.Address
+	} else if v.Host != "" {
+		proto := v.Proto
+		if proto == "" {
+			proto = "https"
+		}
+		cfg.Address = fmt.Sprintf("%s://%s", proto, v.Host)
+	}
+	client, err := vaultapi.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create Vault client failed: %v", err)
+	}
+	if v.Namespace != "" {
+		client.SetNamespace(v.Namespace)
+	}
+
+	authMethod := v.AuthMethod
+	if authMethod == "" {
+		authMethod = os.Getenv("VAULT_AUTH_METHOD")
+	}
+	switch authMethod {
+	case "", "token":
+		if v.TokenEnv != "" {
+			token := os.Getenv(v.TokenEnv)
+			if token == "" {
+				return nil, fmt.Errorf("token_env configured to read Vault token from env %s, but it isn't set", v.TokenEnv)
+			}
+			client.SetToken(token)
+		}
+		if v.TokenFile != "" {
+			token, err := os.ReadFile(v.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("read Vault token file failed: %v", err)
+			}
+			client.SetToken(strings.TrimSpace(string(token)))
+		}
+	case "approle":
+		roleID, secretID := v.RoleID, v.SecretID
+		if roleID == "" {
+			roleID = os.Getenv("VAULT_ROLE_ID")
+		}
+		if secretID == "" {
+			secretID = os.Getenv("VAULT_SECRET_ID")
+		}
+		secret, err := client.Logical().Write("auth/approle/login", map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("login Vault with approle failed: %v", err)
+		}
+		if secret == nil || secret.Auth == nil {
+			return nil, fmt.Errorf("login Vault with approle failed: no auth info returned")
+		}
+		client.SetToken(secret.Auth.ClientToken)
+	default:
+		return nil, fmt.Errorf("unsupported Vault auth method %s", authMethod)
+	}
+	return client, nil
+}